@@ -0,0 +1,124 @@
+package mqc
+
+import (
+	"context"
+	"errors"
+)
+
+// Metadata is a set of key-value pairs sent with a call, e.g. credentials.
+type Metadata map[string]string
+
+// Credentials attach credentials to every call made by a client,
+// e.g. a bearer token or a signature.
+type Credentials interface {
+	// Metadata returns the metadata to send with a call of the method.
+	Metadata(ctx context.Context, method *Method) (Metadata, error)
+}
+
+// Identity is the authenticated identity of a caller.
+type Identity struct {
+	// Subject identifies the caller, e.g. a user or service name.
+	Subject string
+
+	// Attributes are further claims about the caller, e.g. roles.
+	Attributes map[string]string
+}
+
+// Authenticator validates the credentials of incoming calls
+// before the method handler runs.
+type Authenticator interface {
+	// Authenticate returns the identity of the caller of a method,
+	// or an error if the call must be rejected.
+	Authenticate(ctx context.Context, method *Method, md Metadata) (*Identity, error)
+}
+
+// Authorizer decides whether the caller of a method may call it,
+// after the call was authenticated.
+type Authorizer interface {
+	// Authorize returns an error if the caller identity, which is nil for
+	// unauthenticated calls, must not call the method.
+	Authorize(ctx context.Context, method *Method, identity *Identity) error
+}
+
+type metadataKey struct{}
+type identityKey struct{}
+
+// MetadataFromContext returns the metadata received with an incoming call.
+func MetadataFromContext(ctx context.Context) Metadata {
+	md, _ := ctx.Value(metadataKey{}).(Metadata)
+	return md
+}
+
+// NewContextWithMetadata returns a context carrying the metadata of an incoming call.
+func NewContextWithMetadata(ctx context.Context, md Metadata) context.Context {
+	return context.WithValue(ctx, metadataKey{}, md)
+}
+
+// IdentityFromContext returns the authenticated identity of the caller of
+// an incoming call, or nil if the server does not authenticate calls.
+func IdentityFromContext(ctx context.Context) *Identity {
+	identity, _ := ctx.Value(identityKey{}).(*Identity)
+	return identity
+}
+
+// NewContextWithIdentity returns a context carrying the identity of a caller.
+func NewContextWithIdentity(ctx context.Context, identity *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// OutgoingMetadata returns the metadata to send with a call,
+// or nil if no credentials are configured.
+func OutgoingMetadata(ctx context.Context, credentials Credentials, method *Method) (Metadata, error) {
+	if credentials == nil {
+		return nil, nil
+	}
+	return credentials.Metadata(ctx, method)
+}
+
+// Authenticate authenticates an incoming call and returns the context for
+// its handler, carrying the metadata and the identity of the caller.
+// Without an authenticator, the identity is that of the verified client
+// certificate of the peer, if any.
+// Rejected calls return ErrUnauthenticated, unless the authenticator
+// returned an mqc.Error.
+func Authenticate(ctx context.Context, authenticator Authenticator, method *Method, md Metadata) (context.Context, error) {
+	ctx = NewContextWithMetadata(ctx, md)
+	if authenticator == nil {
+		if peer := PeerFromContext(ctx); peer != nil && peer.Identity != nil {
+			ctx = NewContextWithIdentity(ctx, peer.Identity)
+		}
+		return ctx, nil
+	}
+
+	identity, err := authenticator.Authenticate(ctx, method, md)
+	if err != nil {
+		var mqcErr *Error
+		if errors.As(err, &mqcErr) {
+			return nil, mqcErr
+		}
+		return nil, ErrUnauthenticated
+	}
+	if identity == nil {
+		return nil, ErrUnauthenticated
+	}
+
+	return NewContextWithIdentity(ctx, identity), nil
+}
+
+// Authorize authorizes an incoming call with the identity of its context.
+// Denied calls return ErrPermissionDenied, unless the authorizer
+// returned an mqc.Error.
+func Authorize(ctx context.Context, authorizer Authorizer, method *Method) error {
+	if authorizer == nil {
+		return nil
+	}
+
+	if err := authorizer.Authorize(ctx, method, IdentityFromContext(ctx)); err != nil {
+		var mqcErr *Error
+		if errors.As(err, &mqcErr) {
+			return mqcErr
+		}
+		return ErrPermissionDenied
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package authz
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/srand/mqc"
+	"github.com/srand/mqc/transport/mqtt"
+)
+
+// MosquittoACL returns an ACL file for the Mosquitto broker, granting the
+// MQTT topics of the methods of the services to the users allowed by the policy.
+//
+// MQTT usernames are taken as identity subjects without attributes, so only
+// rules matching subjects can grant access to a user: rules listing literal
+// subjects grant access to those users, and rules matching any subject with
+// "*" grant access to all users. The server user is granted the topics of
+// all calls.
+//
+// A broker ACL cannot express a rule denying a user what a later rule grants
+// to all users; such conflicts are reported as comments in the ACL.
+func (p *Policy) MosquittoACL(services []*mqc.ServiceDesc, serverUser string) string {
+	var methods []*mqc.Method
+	for _, service := range services {
+		for _, desc := range service.Methods {
+			if desc.Type == mqc.MethodTypePublisher {
+				// Pub-sub methods are described by their publisher
+				methods = append(methods,
+					&mqc.Method{Service: service.Name, Name: desc.Name, Type: mqc.MethodTypePublisher},
+					&mqc.Method{Service: service.Name, Name: desc.Name, Type: mqc.MethodTypeConsumer})
+			} else {
+				methods = append(methods, &mqc.Method{Service: service.Name, Name: desc.Name, Type: desc.Type})
+			}
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("# Generated from an mqc authorization policy\n")
+
+	// Any user, matching rules without literal subjects
+	everyone := &mqc.Identity{}
+	b.WriteString("\n# All users\n")
+	for _, method := range methods {
+		if p.Decide(method, everyone).Allowed() {
+			writeGrants(&b, "pattern", method, false)
+		}
+	}
+
+	for _, user := range p.users() {
+		fmt.Fprintf(&b, "\nuser %s\n", user)
+
+		identity := &mqc.Identity{Subject: user}
+		for _, method := range methods {
+			allowed := p.Decide(method, identity).Allowed()
+			allowedEveryone := p.Decide(method, everyone).Allowed()
+
+			if allowed && !allowedEveryone {
+				writeGrants(&b, "topic", method, false)
+			} else if !allowed && allowedEveryone {
+				fmt.Fprintf(&b, "# %s is denied %s, but granted to all users\n", user, method.FullName())
+			}
+		}
+	}
+
+	if serverUser != "" {
+		fmt.Fprintf(&b, "\nuser %s\n", serverUser)
+		for _, method := range methods {
+			if !method.IsPubSub() {
+				writeGrants(&b, "topic", method, true)
+			}
+		}
+	}
+
+	return b.String()
+}
+
+// writeGrants writes the ACL lines granting the topics of a method
+// to a client, or to the server handling it.
+func writeGrants(b *strings.Builder, keyword string, method *mqc.Method, server bool) {
+	publish, subscribe := mqtt.Topics(method)
+	if server {
+		publish, subscribe = subscribe, publish
+	}
+
+	fmt.Fprintf(b, "# %s\n", method.FullName())
+	for _, topic := range publish {
+		fmt.Fprintf(b, "%s write %s\n", keyword, topic)
+	}
+	for _, topic := range subscribe {
+		fmt.Fprintf(b, "%s read %s\n", keyword, topic)
+	}
+}
+
+// users returns the literal subjects of the rules, sorted.
+func (p *Policy) users() []string {
+	seen := map[string]bool{}
+	for _, rule := range p.Rules {
+		for _, subject := range rule.Subjects {
+			if subject != "" && !strings.ContainsAny(subject, `*?[\`) {
+				seen[subject] = true
+			}
+		}
+	}
+
+	users := make([]string, 0, len(seen))
+	for user := range seen {
+		users = append(users, user)
+	}
+	sort.Strings(users)
+	return users
+}
@@ -0,0 +1,64 @@
+package authz
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/srand/mqc"
+)
+
+// Authorizer authorizes calls with a policy and logs every decision.
+type Authorizer struct {
+	policy *Policy
+	logger *slog.Logger
+}
+
+var _ mqc.Authorizer = (*Authorizer)(nil)
+
+// NewAuthorizer returns an authorizer for the policy, for use with
+// transport.WithAuthorizer. Decisions are logged to the logger,
+// or to the default logger if nil: allowed calls at info level
+// and denied calls at warning level.
+func NewAuthorizer(policy *Policy, logger *slog.Logger) *Authorizer {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Authorizer{policy: policy, logger: logger}
+}
+
+// LoadAuthorizer returns an authorizer for the policy in a YAML or JSON file,
+// logging decisions to the default logger.
+func LoadAuthorizer(file string) (*Authorizer, error) {
+	policy, err := LoadPolicy(file)
+	if err != nil {
+		return nil, err
+	}
+	return NewAuthorizer(policy, nil), nil
+}
+
+// Authorize returns mqc.ErrPermissionDenied if the policy denies the call.
+func (a *Authorizer) Authorize(ctx context.Context, method *mqc.Method, identity *mqc.Identity) error {
+	decision := a.policy.Decide(method, identity)
+
+	var subject string
+	if identity != nil {
+		subject = identity.Subject
+	}
+
+	level := slog.LevelInfo
+	if !decision.Allowed() {
+		level = slog.LevelWarn
+	}
+
+	a.logger.LogAttrs(ctx, level, "authorization decision",
+		slog.String("decision", string(decision.Effect)),
+		slog.String("rule", decision.Rule),
+		slog.String("method", method.FullName()),
+		slog.String("subject", subject),
+	)
+
+	if !decision.Allowed() {
+		return mqc.ErrPermissionDenied
+	}
+	return nil
+}
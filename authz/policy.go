@@ -0,0 +1,239 @@
+// Package authz authorizes calls with a policy of rules matching the called
+// method and the identity of the caller.
+//
+// Policies are written in YAML or JSON:
+//
+//	default: deny
+//	rules:
+//	  - name: health checks
+//	    effect: allow
+//	    services: ["Health"]
+//	  - name: operators
+//	    effect: allow
+//	    services: ["Greeter"]
+//	    methods: ["Say*"]
+//	    types: ["unary"]
+//	    attributes:
+//	      role: operator
+//
+// Rules are evaluated in order and the first matching rule decides.
+// Calls matching no rule get the default effect, which is deny unless set.
+package authz
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/srand/mqc"
+	"gopkg.in/yaml.v3"
+)
+
+// Effect is the decision of a rule.
+type Effect string
+
+const (
+	Allow Effect = "allow"
+	Deny  Effect = "deny"
+)
+
+// Method type names matched by the types of a rule.
+var methodTypes = map[string]int{
+	"unary":         mqc.MethodTypeUnary,
+	"server-stream": mqc.MethodTypeServerStream,
+	"client-stream": mqc.MethodTypeClientStream,
+	"bidi-stream":   mqc.MethodTypeBidiStream,
+	"publisher":     mqc.MethodTypePublisher,
+	"consumer":      mqc.MethodTypeConsumer,
+}
+
+// Policy is an ordered list of authorization rules.
+type Policy struct {
+	// Default is the effect for calls matching no rule, deny if empty.
+	Default Effect `json:"default" yaml:"default"`
+
+	// Rules are evaluated in order.
+	Rules []Rule `json:"rules" yaml:"rules"`
+}
+
+// Rule matches calls by method and caller. Empty fields match any call,
+// and patterns use the syntax of path.Match, e.g. "Greeter*".
+type Rule struct {
+	// Name identifies the rule in logged decisions.
+	Name string `json:"name" yaml:"name"`
+
+	// Effect of the rule, allow or deny.
+	Effect Effect `json:"effect" yaml:"effect"`
+
+	// Services are patterns matching the service name, as in the generated
+	// code, e.g. "Health" for the health checking service.
+	Services []string `json:"services" yaml:"services"`
+
+	// Methods are patterns matching the method name within the service.
+	Methods []string `json:"methods" yaml:"methods"`
+
+	// Types are the method types: unary, server-stream, client-stream,
+	// bidi-stream, publisher or consumer.
+	Types []string `json:"types" yaml:"types"`
+
+	// Subjects are patterns matching the subject of the caller identity.
+	// Unauthenticated callers never match subjects.
+	Subjects []string `json:"subjects" yaml:"subjects"`
+
+	// Attributes are patterns matching the attributes of the caller identity.
+	// All attributes must match.
+	Attributes map[string]string `json:"attributes" yaml:"attributes"`
+}
+
+// Decision is the result of evaluating a policy for a call.
+type Decision struct {
+	// Effect of the matching rule, or the default effect.
+	Effect Effect
+
+	// Rule is the name of the matching rule, its index if it has no name,
+	// or "default" if no rule matched.
+	Rule string
+}
+
+// Allowed reports whether the call is allowed.
+func (d Decision) Allowed() bool {
+	return d.Effect == Allow
+}
+
+// LoadPolicy reads a policy from a YAML or JSON file.
+func LoadPolicy(file string) (*Policy, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy: %v", err)
+	}
+	return ParsePolicy(data)
+}
+
+// ParsePolicy parses and validates a policy in YAML or JSON.
+func ParsePolicy(data []byte) (*Policy, error) {
+	var policy Policy
+
+	// JSON documents are valid YAML
+	if err := yaml.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("failed to parse policy: %v", err)
+	}
+
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// Validate checks the effects, method types and patterns of the policy.
+func (p *Policy) Validate() error {
+	if p.Default != "" && p.Default != Allow && p.Default != Deny {
+		return fmt.Errorf("invalid default effect %q", p.Default)
+	}
+
+	for i, rule := range p.Rules {
+		name := ruleName(i, &rule)
+
+		if rule.Effect != Allow && rule.Effect != Deny {
+			return fmt.Errorf("rule %s: invalid effect %q", name, rule.Effect)
+		}
+
+		for _, t := range rule.Types {
+			if _, ok := methodTypes[t]; !ok {
+				return fmt.Errorf("rule %s: invalid method type %q", name, t)
+			}
+		}
+
+		patterns := append(append(append([]string{}, rule.Services...), rule.Methods...), rule.Subjects...)
+		for _, value := range rule.Attributes {
+			patterns = append(patterns, value)
+		}
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("rule %s: invalid pattern %q", name, pattern)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Decide evaluates the policy for a call of the method by the caller,
+// which is nil for unauthenticated calls.
+func (p *Policy) Decide(method *mqc.Method, identity *mqc.Identity) Decision {
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		if rule.matches(method, identity) {
+			return Decision{Effect: rule.Effect, Rule: ruleName(i, rule)}
+		}
+	}
+
+	effect := p.Default
+	if effect == "" {
+		effect = Deny
+	}
+	return Decision{Effect: effect, Rule: "default"}
+}
+
+func (r *Rule) matches(method *mqc.Method, identity *mqc.Identity) bool {
+	if !matchAny(r.Services, method.Service) || !matchAny(r.Methods, method.Name) {
+		return false
+	}
+
+	if len(r.Types) > 0 {
+		matched := false
+		for _, t := range r.Types {
+			if methodTypes[t] == method.Type {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	if len(r.Subjects) == 0 && len(r.Attributes) == 0 {
+		return true
+	}
+	if identity == nil {
+		return false
+	}
+
+	if !matchAny(r.Subjects, identity.Subject) {
+		return false
+	}
+	for name, pattern := range r.Attributes {
+		value, ok := identity.Attributes[name]
+		if !ok || !match(pattern, value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchAny reports whether the value matches any of the patterns,
+// or true if there are no patterns.
+func matchAny(patterns []string, value string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if match(pattern, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func match(pattern, value string) bool {
+	matched, _ := path.Match(pattern, value)
+	return matched
+}
+
+func ruleName(i int, rule *Rule) string {
+	if rule.Name != "" {
+		return rule.Name
+	}
+	return fmt.Sprintf("#%d", i)
+}
@@ -0,0 +1,154 @@
+// Package balancer balances the calls of clients over several client
+// connections, e.g. to replicas of a server reachable on different addresses:
+//
+//	conn, err := balancer.New(replica1, replica2)
+//	client := NewGreeterClient(conn)
+//
+// Calls are distributed round-robin over the connections. Connections whose
+// last connection attempt failed are tried last, and a call that cannot be
+// invoked on one connection is invoked on the next one.
+package balancer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+	"time"
+
+	"github.com/srand/mqc"
+	"github.com/srand/mqc/serialization"
+)
+
+// Balancer is an mqc.ClientConn balancing calls over client connections.
+type Balancer struct {
+	conns  []mqc.ClientConn
+	next   atomic.Uint64
+	closed atomic.Bool
+}
+
+var _ mqc.ClientConn = (*Balancer)(nil)
+
+// New creates a balancer of client connections. The connections must use the
+// same serializer, because messages are marshaled before the connection of
+// a call is picked. The balancer closes the connections when it is closed.
+func New(conns ...mqc.ClientConn) (*Balancer, error) {
+	if len(conns) == 0 {
+		return nil, fmt.Errorf("balancer needs at least one connection")
+	}
+
+	serializer := reflect.TypeOf(conns[0].Serializer())
+	for _, conn := range conns[1:] {
+		if reflect.TypeOf(conn.Serializer()) != serializer {
+			return nil, fmt.Errorf("balanced connections must use the same serializer")
+		}
+	}
+
+	return &Balancer{conns: conns}, nil
+}
+
+// Invoke invokes a method on the next connection, failing over to the other
+// connections if the call cannot be invoked. It returns the error of the last
+// connection tried.
+func (b *Balancer) Invoke(ctx context.Context, method *mqc.Method) (mqc.Conn, error) {
+	if b.closed.Load() {
+		return nil, mqc.ErrUnavailable
+	}
+
+	var err error
+	for _, conn := range b.pick() {
+		var call mqc.Conn
+		if call, err = conn.Invoke(ctx, method); err == nil {
+			return call, nil
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, err
+}
+
+// pick returns the connections in the order they are tried for the next call.
+func (b *Balancer) pick() []mqc.ClientConn {
+	start := int(b.next.Add(1)-1) % len(b.conns)
+
+	healthy := make([]mqc.ClientConn, 0, len(b.conns))
+	var failing []mqc.ClientConn
+	for i := range b.conns {
+		conn := b.conns[(start+i)%len(b.conns)]
+		switch conn.State() {
+		case mqc.ConnStateTransientFailure, mqc.ConnStateShutdown:
+			failing = append(failing, conn)
+		default:
+			healthy = append(healthy, conn)
+		}
+	}
+	return append(healthy, failing...)
+}
+
+// Serializer returns the serializer of the connections.
+func (b *Balancer) Serializer() serialization.Serializer {
+	return b.conns[0].Serializer()
+}
+
+// State returns the best state of the connections: ready if any connection
+// is ready, and failing only if all connections are failing.
+func (b *Balancer) State() mqc.ConnState {
+	if b.closed.Load() {
+		return mqc.ConnStateShutdown
+	}
+
+	best := mqc.ConnStateShutdown
+	for _, conn := range b.conns {
+		best = better(best, conn.State())
+	}
+	return best
+}
+
+// better returns the better of two connection states.
+func better(a, b mqc.ConnState) mqc.ConnState {
+	rank := func(state mqc.ConnState) int {
+		switch state {
+		case mqc.ConnStateReady:
+			return 4
+		case mqc.ConnStateConnecting:
+			return 3
+		case mqc.ConnStateIdle:
+			return 2
+		case mqc.ConnStateTransientFailure:
+			return 1
+		default:
+			return 0
+		}
+	}
+	if rank(b) > rank(a) {
+		return b
+	}
+	return a
+}
+
+// CallTimeout returns the call timeout of the first connection having one.
+func (b *Balancer) CallTimeout() time.Duration {
+	for _, conn := range b.conns {
+		if c, ok := conn.(interface{ CallTimeout() time.Duration }); ok {
+			return c.CallTimeout()
+		}
+	}
+	return 0
+}
+
+// Close closes the balancer and its connections.
+func (b *Balancer) Close() error {
+	if b.closed.Swap(true) {
+		return nil
+	}
+
+	var errs []error
+	for _, conn := range b.conns {
+		if err := conn.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,482 @@
+// Package channelz provides live introspection of mqc transports: their
+// multiplexed sessions, in-flight calls, MQTT subscriptions and pub-sub
+// consumers, e.g. to find out which streams of a server are hanging.
+//
+//	registry := channelz.NewRegistry()
+//	t, err := tcp.NewTransport(transport.WithChannelz(registry), ...)
+//	http.Handle("/debug/channelz", registry)
+//
+// The registry is served as an HTML page, or as JSON with ?format=json.
+// Snapshot returns the same information to programs.
+package channelz
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/srand/mqc"
+)
+
+// Roles of calls.
+const (
+	RoleClient = "client"
+	RoleServer = "server"
+)
+
+// TransportInfo describes a transport and everything it has open.
+type TransportInfo struct {
+	ID            int64              `json:"id"`
+	Kind          string             `json:"kind"`
+	Addrs         []string           `json:"addrs"`
+	Created       time.Time          `json:"created"`
+	Sessions      []SessionInfo      `json:"sessions,omitempty"`
+	Calls         []CallInfo         `json:"calls,omitempty"`
+	Subscriptions []SubscriptionInfo `json:"subscriptions,omitempty"`
+	Consumers     []ConsumerInfo     `json:"consumers,omitempty"`
+}
+
+// SessionInfo describes a multiplexed session and its calls.
+type SessionInfo struct {
+	ID      int64      `json:"id"`
+	Remote  string     `json:"remote"`
+	Created time.Time  `json:"created"`
+	Streams int        `json:"streams"`
+	Calls   []CallInfo `json:"calls,omitempty"`
+}
+
+// CallInfo describes an in-flight call. Bytes are the sizes of the
+// messages of the call, without the framing of the transport.
+type CallInfo struct {
+	ID               int64         `json:"id"`
+	Role             string        `json:"role"`
+	Method           string        `json:"method"`
+	Type             string        `json:"type"`
+	Started          time.Time     `json:"started"`
+	Duration         time.Duration `json:"duration"`
+	MessagesSent     int64         `json:"messages_sent"`
+	MessagesReceived int64         `json:"messages_received"`
+	BytesSent        int64         `json:"bytes_sent"`
+	BytesReceived    int64         `json:"bytes_received"`
+	LastMessage      time.Time     `json:"last_message,omitzero"`
+}
+
+// SubscriptionInfo describes an MQTT subscription of a server to the calls of a method.
+type SubscriptionInfo struct {
+	ID      int64     `json:"id"`
+	Topic   string    `json:"topic"`
+	Method  string    `json:"method"`
+	Created time.Time `json:"created"`
+}
+
+// ConsumerInfo describes a pub-sub consumer.
+type ConsumerInfo struct {
+	ID          int64     `json:"id"`
+	Topic       string    `json:"topic"`
+	Method      string    `json:"method"`
+	Created     time.Time `json:"created"`
+	Received    int64     `json:"received"`
+	LastMessage time.Time `json:"last_message,omitzero"`
+}
+
+// Registry keeps track of the transports created with it.
+// A nil *Registry tracks nothing.
+type Registry struct {
+	mu         sync.Mutex
+	nextID     int64
+	transports map[int64]*Transport
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{transports: make(map[int64]*Transport)}
+}
+
+// id returns a new ID, unique within the registry.
+// It must be called with the registry mutex held.
+func (r *Registry) id() int64 {
+	r.nextID++
+	return r.nextID
+}
+
+// AddTransport tracks a transport of the kind, e.g. "tcp" or "mqtt",
+// until it is removed.
+func (r *Registry) AddTransport(kind string, addrs []string) *Transport {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t := &Transport{
+		registry: r,
+		info: TransportInfo{
+			ID:      r.id(),
+			Kind:    kind,
+			Addrs:   slices.Clone(addrs),
+			Created: time.Now(),
+		},
+		sessions:      make(map[any]*Session),
+		calls:         make(map[int64]*Call),
+		subscriptions: make(map[int64]*SubscriptionInfo),
+		consumers:     make(map[int64]*Consumer),
+	}
+	r.transports[t.info.ID] = t
+	return t
+}
+
+// Snapshot returns the transports of the registry and everything they have
+// open, ordered by creation.
+func (r *Registry) Snapshot() []TransportInfo {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	transports := make([]TransportInfo, 0, len(r.transports))
+	for _, t := range r.transports {
+		transports = append(transports, t.snapshot(now))
+	}
+	slices.SortFunc(transports, func(a, b TransportInfo) int { return int(a.ID - b.ID) })
+	return transports
+}
+
+// Transport is a tracked transport. A nil *Transport tracks nothing.
+type Transport struct {
+	registry      *Registry
+	info          TransportInfo
+	sessions      map[any]*Session
+	calls         map[int64]*Call
+	subscriptions map[int64]*SubscriptionInfo
+	consumers     map[int64]*Consumer
+}
+
+// snapshot must be called with the registry mutex held.
+func (t *Transport) snapshot(now time.Time) TransportInfo {
+	info := t.info
+	info.Addrs = slices.Clone(t.info.Addrs)
+
+	for _, s := range t.sessions {
+		info.Sessions = append(info.Sessions, s.snapshot(now))
+	}
+	slices.SortFunc(info.Sessions, func(a, b SessionInfo) int { return int(a.ID - b.ID) })
+
+	info.Calls = snapshotCalls(t.calls, now)
+
+	for _, s := range t.subscriptions {
+		info.Subscriptions = append(info.Subscriptions, *s)
+	}
+	slices.SortFunc(info.Subscriptions, func(a, b SubscriptionInfo) int { return int(a.ID - b.ID) })
+
+	for _, c := range t.consumers {
+		consumer := c.info
+		consumer.Received = c.received.Load()
+		consumer.LastMessage = loadTime(&c.last)
+		info.Consumers = append(info.Consumers, consumer)
+	}
+	slices.SortFunc(info.Consumers, func(a, b ConsumerInfo) int { return int(a.ID - b.ID) })
+
+	return info
+}
+
+// Remove stops tracking the transport and everything it has open.
+func (t *Transport) Remove() {
+	if t == nil {
+		return
+	}
+
+	t.registry.mu.Lock()
+	defer t.registry.mu.Unlock()
+	delete(t.registry.transports, t.info.ID)
+}
+
+// AddSession tracks a session of the transport with the remote address until
+// it is removed. The key identifies the session for Session, e.g. a
+// *yamux.Session, whose number of open streams is reported if it has a
+// NumStreams method.
+func (t *Transport) AddSession(key any, remote string) *Session {
+	if t == nil {
+		return nil
+	}
+
+	t.registry.mu.Lock()
+	defer t.registry.mu.Unlock()
+
+	s := &Session{
+		transport: t,
+		key:       key,
+		info: SessionInfo{
+			ID:      t.registry.id(),
+			Remote:  remote,
+			Created: time.Now(),
+		},
+		calls: make(map[int64]*Call),
+	}
+	t.sessions[key] = s
+	return s
+}
+
+// Session returns the tracked session of the key, or nil if unknown.
+func (t *Transport) Session(key any) *Session {
+	if t == nil {
+		return nil
+	}
+
+	t.registry.mu.Lock()
+	defer t.registry.mu.Unlock()
+	return t.sessions[key]
+}
+
+// StartCall tracks a call of a transport without sessions, e.g. MQTT,
+// until it ends.
+func (t *Transport) StartCall(role string, method *mqc.Method) *Call {
+	if t == nil {
+		return nil
+	}
+	return t.startCall(t.calls, role, method)
+}
+
+func (t *Transport) startCall(calls map[int64]*Call, role string, method *mqc.Method) *Call {
+	t.registry.mu.Lock()
+	defer t.registry.mu.Unlock()
+
+	c := &Call{
+		registry: t.registry,
+		calls:    calls,
+		info: CallInfo{
+			ID:      t.registry.id(),
+			Role:    role,
+			Method:  method.FullName(),
+			Type:    method.TypeName(),
+			Started: time.Now(),
+		},
+		method: method,
+		client: role == RoleClient,
+	}
+	calls[c.info.ID] = c
+	return c
+}
+
+// Subscribe tracks a subscription of the transport to the topic for the calls
+// of a method, and returns a function removing it.
+func (t *Transport) Subscribe(topic string, method *mqc.Method) (remove func()) {
+	if t == nil {
+		return func() {}
+	}
+
+	t.registry.mu.Lock()
+	defer t.registry.mu.Unlock()
+
+	s := &SubscriptionInfo{
+		ID:      t.registry.id(),
+		Topic:   topic,
+		Method:  method.FullName(),
+		Created: time.Now(),
+	}
+	t.subscriptions[s.ID] = s
+
+	return func() {
+		t.registry.mu.Lock()
+		defer t.registry.mu.Unlock()
+		delete(t.subscriptions, s.ID)
+	}
+}
+
+// AddConsumer tracks a pub-sub consumer of the method subscribed to the topic
+// until it is removed.
+func (t *Transport) AddConsumer(topic string, method *mqc.Method) *Consumer {
+	if t == nil {
+		return nil
+	}
+
+	t.registry.mu.Lock()
+	defer t.registry.mu.Unlock()
+
+	c := &Consumer{
+		transport: t,
+		info: ConsumerInfo{
+			ID:      t.registry.id(),
+			Topic:   topic,
+			Method:  method.FullName(),
+			Created: time.Now(),
+		},
+	}
+	t.consumers[c.info.ID] = c
+	return c
+}
+
+// Session is a tracked multiplexed session. A nil *Session tracks nothing.
+type Session struct {
+	transport *Transport
+	key       any
+	info      SessionInfo
+	calls     map[int64]*Call
+}
+
+// snapshot must be called with the registry mutex held.
+func (s *Session) snapshot(now time.Time) SessionInfo {
+	info := s.info
+	if mux, ok := s.key.(interface{ NumStreams() int }); ok {
+		info.Streams = mux.NumStreams()
+	}
+	info.Calls = snapshotCalls(s.calls, now)
+	return info
+}
+
+// StartCall tracks a call of the session until it ends.
+func (s *Session) StartCall(role string, method *mqc.Method) *Call {
+	if s == nil {
+		return nil
+	}
+	return s.transport.startCall(s.calls, role, method)
+}
+
+// Remove stops tracking the session and its calls.
+func (s *Session) Remove() {
+	if s == nil {
+		return
+	}
+
+	s.transport.registry.mu.Lock()
+	defer s.transport.registry.mu.Unlock()
+	delete(s.transport.sessions, s.key)
+}
+
+// Call is a tracked call. A nil *Call tracks nothing.
+type Call struct {
+	registry *Registry
+	calls    map[int64]*Call
+	info     CallInfo
+	method   *mqc.Method
+	client   bool
+
+	sent          atomic.Int64
+	received      atomic.Int64
+	bytesSent     atomic.Int64
+	bytesReceived atomic.Int64
+	last          atomic.Int64
+}
+
+func snapshotCalls(calls map[int64]*Call, now time.Time) []CallInfo {
+	var infos []CallInfo
+	for _, c := range calls {
+		info := c.info
+		info.Duration = now.Sub(info.Started)
+		info.MessagesSent = c.sent.Load()
+		info.MessagesReceived = c.received.Load()
+		info.BytesSent = c.bytesSent.Load()
+		info.BytesReceived = c.bytesReceived.Load()
+		info.LastMessage = loadTime(&c.last)
+		infos = append(infos, info)
+	}
+	slices.SortFunc(infos, func(a, b CallInfo) int { return int(a.ID - b.ID) })
+	return infos
+}
+
+// End stops tracking the call.
+func (c *Call) End() {
+	if c == nil {
+		return
+	}
+
+	c.registry.mu.Lock()
+	defer c.registry.mu.Unlock()
+	delete(c.calls, c.info.ID)
+}
+
+// Conn returns a connection counting the messages and bytes of the call.
+// The call of a client connection ends when the connection is closed or
+// receiving fails, e.g. at the end of a stream, while server calls end
+// with their handler.
+func (c *Call) Conn(conn mqc.Conn) mqc.Conn {
+	if c == nil {
+		return conn
+	}
+	return &callConn{Conn: conn, call: c}
+}
+
+type callConn struct {
+	mqc.Conn
+	call *Call
+}
+
+// Method returns the method the connection was invoked for.
+func (c *callConn) Method() *mqc.Method {
+	return c.call.method
+}
+
+func (c *callConn) Send(ctx context.Context, data []byte) error {
+	err := c.Conn.Send(ctx, data)
+	if err == nil {
+		c.call.sent.Add(1)
+		c.call.bytesSent.Add(int64(len(data)))
+		storeTime(&c.call.last, time.Now())
+	} else if c.call.client {
+		c.call.End()
+	}
+	return err
+}
+
+func (c *callConn) Recv(ctx context.Context) ([]byte, error) {
+	data, err := c.Conn.Recv(ctx)
+	if err == nil {
+		c.call.received.Add(1)
+		c.call.bytesReceived.Add(int64(len(data)))
+		storeTime(&c.call.last, time.Now())
+	} else if c.call.client {
+		c.call.End()
+	}
+	return data, err
+}
+
+func (c *callConn) Close() error {
+	if c.call.client {
+		c.call.End()
+	}
+	return c.Conn.Close()
+}
+
+// Consumer is a tracked pub-sub consumer. A nil *Consumer tracks nothing.
+type Consumer struct {
+	transport *Transport
+	info      ConsumerInfo
+	received  atomic.Int64
+	last      atomic.Int64
+}
+
+// Received records a message received by the consumer.
+func (c *Consumer) Received() {
+	if c == nil {
+		return
+	}
+	c.received.Add(1)
+	storeTime(&c.last, time.Now())
+}
+
+// Remove stops tracking the consumer.
+func (c *Consumer) Remove() {
+	if c == nil {
+		return
+	}
+
+	c.transport.registry.mu.Lock()
+	defer c.transport.registry.mu.Unlock()
+	delete(c.transport.consumers, c.info.ID)
+}
+
+func storeTime(v *atomic.Int64, t time.Time) {
+	v.Store(t.UnixNano())
+}
+
+func loadTime(v *atomic.Int64) time.Time {
+	if n := v.Load(); n != 0 {
+		return time.Unix(0, n)
+	}
+	return time.Time{}
+}
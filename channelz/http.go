@@ -0,0 +1,94 @@
+package channelz
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"time"
+)
+
+var _ http.Handler = (*Registry)(nil)
+
+// ServeHTTP serves a snapshot of the registry as an HTML page,
+// or as JSON if requested with ?format=json.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	transports := r.Snapshot()
+
+	if req.URL.Query().Get("format") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(transports)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	page.Execute(w, transports)
+}
+
+var page = template.Must(template.New("channelz").Funcs(template.FuncMap{
+	"since": func(t time.Time) string {
+		if t.IsZero() {
+			return "-"
+		}
+		return time.Since(t).Round(time.Millisecond).String()
+	},
+	"duration": func(d time.Duration) string {
+		return d.Round(time.Millisecond).String()
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<title>channelz</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; margin: 0.5em 0 1em 0; }
+th, td { border: 1px solid #ccc; padding: 2px 8px; text-align: left; }
+</style>
+</head>
+<body>
+<h1>channelz</h1>
+{{- define "calls"}}
+<table>
+<tr><th>ID</th><th>Role</th><th>Method</th><th>Type</th><th>Duration</th><th>Messages sent</th><th>Messages received</th><th>Bytes sent</th><th>Bytes received</th><th>Last message</th></tr>
+{{- range .}}
+<tr><td>{{.ID}}</td><td>{{.Role}}</td><td>{{.Method}}</td><td>{{.Type}}</td><td>{{duration .Duration}}</td><td>{{.MessagesSent}}</td><td>{{.MessagesReceived}}</td><td>{{.BytesSent}}</td><td>{{.BytesReceived}}</td><td>{{since .LastMessage}} ago</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- range .}}
+<h2>Transport {{.ID}}: {{.Kind}} {{range .Addrs}}{{.}} {{end}}</h2>
+<p>Created {{since .Created}} ago</p>
+{{- range .Sessions}}
+<h3>Session {{.ID}}: {{.Remote}}</h3>
+<p>Created {{since .Created}} ago, {{.Streams}} open streams</p>
+{{- if .Calls}}{{template "calls" .Calls}}{{end}}
+{{- end}}
+{{- if .Calls}}
+<h3>Calls</h3>
+{{- template "calls" .Calls}}
+{{- end}}
+{{- if .Subscriptions}}
+<h3>Subscriptions</h3>
+<table>
+<tr><th>ID</th><th>Topic</th><th>Method</th><th>Age</th></tr>
+{{- range .Subscriptions}}
+<tr><td>{{.ID}}</td><td>{{.Topic}}</td><td>{{.Method}}</td><td>{{since .Created}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- if .Consumers}}
+<h3>Consumers</h3>
+<table>
+<tr><th>ID</th><th>Topic</th><th>Method</th><th>Age</th><th>Received</th><th>Last message</th></tr>
+{{- range .Consumers}}
+<tr><td>{{.ID}}</td><td>{{.Topic}}</td><td>{{.Method}}</td><td>{{since .Created}}</td><td>{{.Received}}</td><td>{{since .LastMessage}} ago</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- else}}
+<p>No transports.</p>
+{{- end}}
+</body>
+</html>
+`))
@@ -0,0 +1,63 @@
+package mqc
+
+import (
+	"context"
+
+	"github.com/srand/mqc/serialization"
+)
+
+// ConnState is the state of the client connection of a ClientConn.
+type ConnState int
+
+const (
+	// ConnStateIdle means there is no connection. The next call connects.
+	ConnStateIdle ConnState = iota
+
+	// ConnStateConnecting means a connection is being established.
+	ConnStateConnecting
+
+	// ConnStateReady means the connection is established.
+	ConnStateReady
+
+	// ConnStateTransientFailure means the last connection attempt failed.
+	// The next call connects again.
+	ConnStateTransientFailure
+
+	// ConnStateShutdown means the connection was closed.
+	ConnStateShutdown
+)
+
+func (s ConnState) String() string {
+	switch s {
+	case ConnStateIdle:
+		return "IDLE"
+	case ConnStateConnecting:
+		return "CONNECTING"
+	case ConnStateReady:
+		return "READY"
+	case ConnStateTransientFailure:
+		return "TRANSIENT_FAILURE"
+	case ConnStateShutdown:
+		return "SHUTDOWN"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// ClientConn is the client side of a transport, used by generated clients to
+// invoke methods. All transports are client connections, and client
+// connections can be wrapped, e.g. to balance or route calls, without
+// implementing the server side of a transport.
+type ClientConn interface {
+	// Invoke creates a new connection object for the given method.
+	Invoke(ctx context.Context, method *Method) (Conn, error)
+
+	// Serializer returns the serializer used for marshaling and unmarshaling messages.
+	Serializer() serialization.Serializer
+
+	// State returns the state of the client connection.
+	State() ConnState
+
+	// Close closes the client connection.
+	Close() error
+}
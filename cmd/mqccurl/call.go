@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/srand/mqc"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// client invokes methods with JSON input and prints JSON lines.
+type client struct {
+	transport mqc.Transport
+	codec     codec
+	input     *json.Decoder
+	output    io.Writer
+}
+
+// next reads the next input message, returning io.EOF when there are no more.
+func (c *client) next(desc protoreflect.MessageDescriptor) (proto.Message, error) {
+	var raw json.RawMessage
+	if err := c.input.Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	msg := dynamicpb.NewMessage(desc)
+	if err := protojson.Unmarshal(raw, msg); err != nil {
+		return nil, fmt.Errorf("%s: %w", desc.FullName(), err)
+	}
+	return msg, nil
+}
+
+// single reads exactly one input message. Without input, the message is empty.
+func (c *client) single(desc protoreflect.MessageDescriptor) (proto.Message, error) {
+	msg, err := c.next(desc)
+	if errors.Is(err, io.EOF) {
+		return dynamicpb.NewMessage(desc), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := c.next(desc); !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("method takes a single request")
+	}
+	return msg, nil
+}
+
+func (c *client) send(ctx context.Context, conn mqc.Conn, msg proto.Message) error {
+	data, err := c.codec.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return conn.Send(ctx, data)
+}
+
+func (c *client) sendAll(ctx context.Context, conn mqc.Conn, desc protoreflect.MessageDescriptor) error {
+	for {
+		msg, err := c.next(desc)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := c.send(ctx, conn, msg); err != nil {
+			return err
+		}
+	}
+}
+
+// print receives one message and prints it as a JSON line.
+func (c *client) print(ctx context.Context, conn mqc.Conn, desc protoreflect.MessageDescriptor) error {
+	data, err := conn.Recv(ctx)
+	if err != nil {
+		return err
+	}
+
+	msg := dynamicpb.NewMessage(desc)
+	if err := c.codec.Unmarshal(data, msg); err != nil {
+		return err
+	}
+
+	line, err := protojson.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(c.output, "%s\n", line)
+	return err
+}
+
+// printAll prints received messages until the stream ends.
+func (c *client) printAll(ctx context.Context, conn mqc.Conn, desc protoreflect.MessageDescriptor) error {
+	for {
+		err := c.print(ctx, conn, desc)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// call invokes an RPC method.
+func (c *client) call(ctx context.Context, info *methodInfo) error {
+	if info.method == nil {
+		return fmt.Errorf("%s is a pub-sub method, use publish or subscribe", info.name())
+	}
+	if info.desc == nil {
+		return fmt.Errorf("%s: no schema, use -proto", info.name())
+	}
+
+	input, output := info.desc.Input(), info.desc.Output()
+
+	conn, err := c.transport.Invoke(ctx, info.method)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	switch info.method.Type {
+	case mqc.MethodTypeUnary, mqc.MethodTypeServerStream:
+		req, err := c.single(input)
+		if err != nil {
+			return err
+		}
+		if err := c.send(ctx, conn, req); err != nil {
+			return err
+		}
+		if info.method.IsUnary() {
+			return c.print(ctx, conn, output)
+		}
+		return c.printAll(ctx, conn, output)
+
+	case mqc.MethodTypeClientStream:
+		if err := c.sendAll(ctx, conn, input); err != nil {
+			return err
+		}
+		if err := conn.SendClose(ctx); err != nil {
+			return err
+		}
+		return c.print(ctx, conn, output)
+
+	case mqc.MethodTypeBidiStream:
+		done := make(chan error, 1)
+		go func() {
+			err := c.sendAll(ctx, conn, input)
+			if err == nil {
+				err = conn.SendClose(ctx)
+			}
+			done <- err
+		}()
+
+		if err := c.printAll(ctx, conn, output); err != nil {
+			return err
+		}
+		return <-done
+	}
+
+	return fmt.Errorf("%s: unsupported method type %d", info.name(), info.method.Type)
+}
+
+func (c *client) pubsubMethod(info *methodInfo, methodType int) (*mqc.Method, error) {
+	if !info.pubsub {
+		return nil, fmt.Errorf("%s is not a pub-sub method, use call", info.name())
+	}
+	if info.desc == nil {
+		return nil, fmt.Errorf("%s: no schema, use -proto", info.name())
+	}
+	return mqc.NewMethod(info.name(), methodType), nil
+}
+
+// publish publishes every input message on a pub-sub method.
+func (c *client) publish(ctx context.Context, info *methodInfo) error {
+	method, err := c.pubsubMethod(info, mqc.MethodTypePublisher)
+	if err != nil {
+		return err
+	}
+
+	conn, err := c.transport.Invoke(ctx, method)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return c.sendAll(ctx, conn, info.desc.Input())
+}
+
+// subscribe prints the messages published on a pub-sub method.
+func (c *client) subscribe(ctx context.Context, info *methodInfo) error {
+	method, err := c.pubsubMethod(info, mqc.MethodTypeConsumer)
+	if err != nil {
+		return err
+	}
+
+	conn, err := c.transport.Invoke(ctx, method)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return c.printAll(ctx, conn, info.desc.Input())
+}
+
+// list prints the known methods, one per line.
+func (c *client) list(s *schema) error {
+	for _, info := range s.methods {
+		line := info.name()
+		if info.method != nil {
+			line += " " + methodTypeName(info.method.Type)
+		}
+		if info.pubsub {
+			if info.method != nil {
+				line += ","
+			} else {
+				line += " "
+			}
+			line += methodTypeName(mqc.MethodTypePublisher)
+		}
+		if info.desc != nil {
+			line += fmt.Sprintf(" %s -> %s", info.desc.Input().FullName(), info.desc.Output().FullName())
+		}
+		if _, err := fmt.Fprintln(c.output, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
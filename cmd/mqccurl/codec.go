@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/srand/mqc/serialization"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// codec encodes dynamic messages for the serializer of the target transport.
+type codec interface {
+	Marshal(msg proto.Message) ([]byte, error)
+	Unmarshal(data []byte, msg proto.Message) error
+}
+
+func newCodec(name string) (codec, error) {
+	switch name {
+	case "proto":
+		return protoCodec{}, nil
+	case "json":
+		return structCodec{}, nil
+	}
+	return nil, fmt.Errorf("unknown serializer %q", name)
+}
+
+// serializerName returns the codec name matching a transport serializer.
+func serializerName(serializer serialization.Serializer) string {
+	if _, ok := serializer.(*serialization.JSONSerializer); ok {
+		return "json"
+	}
+	return "proto"
+}
+
+// protoCodec encodes messages in the protobuf wire format.
+type protoCodec struct{}
+
+func (protoCodec) Marshal(msg proto.Message) ([]byte, error) {
+	return proto.Marshal(msg)
+}
+
+func (protoCodec) Unmarshal(data []byte, msg proto.Message) error {
+	return proto.Unmarshal(data, msg)
+}
+
+// structCodec encodes messages the way encoding/json encodes the structs
+// generated by protoc-gen-go, which is what the JSON serializer does:
+// fields are keyed by their proto name, enums are numbers and oneofs are
+// objects keyed by the Go name of the oneof.
+type structCodec struct{}
+
+func (structCodec) Marshal(msg proto.Message) ([]byte, error) {
+	return json.Marshal(structValue(msg.ProtoReflect()))
+}
+
+func (structCodec) Unmarshal(data []byte, msg proto.Message) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value map[string]any
+	if err := decoder.Decode(&value); err != nil {
+		return err
+	}
+	return setStructValue(msg.ProtoReflect(), value)
+}
+
+func structValue(msg protoreflect.Message) map[string]any {
+	result := map[string]any{}
+
+	msg.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		value := structFieldValue(fd, v)
+		if oneof := fd.ContainingOneof(); oneof != nil && !oneof.IsSynthetic() {
+			result[goCamelCase(string(oneof.Name()))] = map[string]any{string(fd.Name()): value}
+		} else {
+			result[string(fd.Name())] = value
+		}
+		return true
+	})
+
+	return result
+}
+
+func structFieldValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) any {
+	switch {
+	case fd.IsList():
+		list := v.List()
+		values := make([]any, list.Len())
+		for i := range values {
+			values[i] = structScalarValue(fd, list.Get(i))
+		}
+		return values
+	case fd.IsMap():
+		values := map[string]any{}
+		v.Map().Range(func(key protoreflect.MapKey, value protoreflect.Value) bool {
+			values[key.String()] = structScalarValue(fd.MapValue(), value)
+			return true
+		})
+		return values
+	}
+	return structScalarValue(fd, v)
+}
+
+func structScalarValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) any {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return structValue(v.Message())
+	case protoreflect.EnumKind:
+		return int32(v.Enum())
+	case protoreflect.BytesKind:
+		return base64.StdEncoding.EncodeToString(v.Bytes())
+	}
+	return v.Interface()
+}
+
+func setStructValue(msg protoreflect.Message, value map[string]any) error {
+	fields := msg.Descriptor().Fields()
+	oneofs := msg.Descriptor().Oneofs()
+
+	for key, v := range value {
+		if v == nil {
+			continue
+		}
+
+		if fd := fields.ByName(protoreflect.Name(key)); fd != nil {
+			if err := setStructField(msg, fd, v); err != nil {
+				return err
+			}
+			continue
+		}
+
+		found := false
+		for i := 0; i < oneofs.Len(); i++ {
+			oneof := oneofs.Get(i)
+			if goCamelCase(string(oneof.Name())) != key {
+				continue
+			}
+			found = true
+
+			wrapper, ok := v.(map[string]any)
+			if !ok {
+				return fmt.Errorf("%s: expected object", oneof.FullName())
+			}
+			for name, fv := range wrapper {
+				fd := oneof.Fields().ByName(protoreflect.Name(name))
+				if fd == nil {
+					return fmt.Errorf("%s: unknown field %q", oneof.FullName(), name)
+				}
+				if err := setStructField(msg, fd, fv); err != nil {
+					return err
+				}
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: unknown field %q", msg.Descriptor().FullName(), key)
+		}
+	}
+
+	return nil
+}
+
+func setStructField(msg protoreflect.Message, fd protoreflect.FieldDescriptor, v any) error {
+	switch {
+	case fd.IsList():
+		values, ok := v.([]any)
+		if !ok {
+			return fmt.Errorf("%s: expected array", fd.FullName())
+		}
+		list := msg.Mutable(fd).List()
+		for _, value := range values {
+			if fd.Message() != nil {
+				elem := list.NewElement()
+				if err := setStructMessage(elem.Message(), fd, value); err != nil {
+					return err
+				}
+				list.Append(elem)
+				continue
+			}
+			scalar, err := structScalar(fd, value)
+			if err != nil {
+				return err
+			}
+			list.Append(scalar)
+		}
+		return nil
+	case fd.IsMap():
+		values, ok := v.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: expected object", fd.FullName())
+		}
+		m := msg.Mutable(fd).Map()
+		for k, value := range values {
+			key, err := structScalar(fd.MapKey(), k)
+			if err != nil {
+				return err
+			}
+			if fd.MapValue().Message() != nil {
+				elem := m.NewValue()
+				if err := setStructMessage(elem.Message(), fd.MapValue(), value); err != nil {
+					return err
+				}
+				m.Set(key.MapKey(), elem)
+				continue
+			}
+			scalar, err := structScalar(fd.MapValue(), value)
+			if err != nil {
+				return err
+			}
+			m.Set(key.MapKey(), scalar)
+		}
+		return nil
+	case fd.Message() != nil:
+		return setStructMessage(msg.Mutable(fd).Message(), fd, v)
+	}
+
+	scalar, err := structScalar(fd, v)
+	if err != nil {
+		return err
+	}
+	msg.Set(fd, scalar)
+	return nil
+}
+
+func setStructMessage(msg protoreflect.Message, fd protoreflect.FieldDescriptor, v any) error {
+	value, ok := v.(map[string]any)
+	if !ok {
+		return fmt.Errorf("%s: expected object", fd.FullName())
+	}
+	return setStructValue(msg, value)
+}
+
+// structScalar converts a decoded JSON value to a scalar field value.
+// Map keys are always strings, so numbers and booleans are also parsed from strings.
+func structScalar(fd protoreflect.FieldDescriptor, v any) (protoreflect.Value, error) {
+	text := fmt.Sprint(v)
+
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		b, err := strconv.ParseBool(text)
+		return protoreflect.ValueOfBool(b), err
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(text), nil
+	case protoreflect.BytesKind:
+		b, err := base64.StdEncoding.DecodeString(text)
+		return protoreflect.ValueOfBytes(b), err
+	case protoreflect.EnumKind:
+		n, err := strconv.ParseInt(text, 10, 32)
+		return protoreflect.ValueOfEnum(protoreflect.EnumNumber(n)), err
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		n, err := strconv.ParseInt(text, 10, 32)
+		return protoreflect.ValueOfInt32(int32(n)), err
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		n, err := strconv.ParseInt(text, 10, 64)
+		return protoreflect.ValueOfInt64(n), err
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		n, err := strconv.ParseUint(text, 10, 32)
+		return protoreflect.ValueOfUint32(uint32(n)), err
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		n, err := strconv.ParseUint(text, 10, 64)
+		return protoreflect.ValueOfUint64(n), err
+	case protoreflect.FloatKind:
+		f, err := strconv.ParseFloat(text, 32)
+		return protoreflect.ValueOfFloat32(float32(f)), err
+	case protoreflect.DoubleKind:
+		f, err := strconv.ParseFloat(text, 64)
+		return protoreflect.ValueOfFloat64(f), err
+	}
+	return protoreflect.Value{}, fmt.Errorf("%s: unsupported field kind %s", fd.FullName(), fd.Kind())
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/srand/mqc/reflection"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// The struct codec must produce the same JSON as the JSON serializer, which
+// encodes generated messages with encoding/json.
+func TestStructCodecMatchesGeneratedStructs(t *testing.T) {
+	messages := []proto.Message{
+		&reflection.ListServicesResponse{
+			Services: []*reflection.ServiceInfo{{
+				Name:     "test.Greeter",
+				FullName: "test.Greeter",
+				File:     "test.proto",
+				Methods: []*reflection.MethodInfo{
+					{Name: "Hello", Type: 3, InputType: "test.Request", OutputType: "test.Reply"},
+				},
+			}},
+		},
+		&reflection.FileDescriptorResponse{
+			FileDescriptorProto: [][]byte{{0, 1, 2}, {0xff}},
+		},
+	}
+
+	for _, msg := range messages {
+		expected, err := json.Marshal(msg)
+		require.NoError(t, err)
+
+		dynamic := dynamicpb.NewMessage(msg.ProtoReflect().Descriptor())
+		require.NoError(t, structCodec{}.Unmarshal(expected, dynamic))
+		assert.True(t, proto.Equal(msg, dynamic))
+
+		data, err := structCodec{}.Marshal(dynamic)
+		require.NoError(t, err)
+		assert.JSONEq(t, string(expected), string(data))
+	}
+}
+
+func TestSchemaFind(t *testing.T) {
+	s := &schema{}
+	s.addService(reflection.File_mqc_reflection_proto.Services().Get(0))
+
+	info, err := s.find("mqc.reflection.ServerReflection/ListServices")
+	require.NoError(t, err)
+	assert.Equal(t, "ListServices", string(info.desc.Name()))
+	assert.False(t, info.pubsub)
+
+	_, err = s.find("mqc.reflection.ServerReflection/Unknown")
+	assert.Error(t, err)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/srand/mqc"
+	"github.com/srand/mqc/transport"
+	"github.com/srand/mqc/transport/http"
+	"github.com/srand/mqc/transport/mqtt"
+	"github.com/srand/mqc/transport/tcp"
+	"github.com/srand/mqc/transport/unix"
+)
+
+// newTransport creates a client transport for an address URL.
+// The scheme selects the transport:
+//
+//	tcp://host:port
+//	unix:///path/to/socket
+//	ws://host:port/path, wss://host:port/path
+//	mqtt://host:port, mqtts://host:port
+func newTransport(address string, tlsConfig *tls.Config, timeout time.Duration) (mqc.Transport, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, err
+	}
+
+	options := []transport.TransportOption{
+		transport.WithConnectTimeout(timeout),
+	}
+
+	switch u.Scheme {
+	case "tcp":
+		if tlsConfig != nil {
+			options = append(options, transport.WithTLSConfig(tlsConfig))
+		}
+		return tcp.NewTransport(append(options, transport.WithAddress(u.Host))...)
+
+	case "unix":
+		return unix.NewTransport(append(options, transport.WithAddress(u.Host+u.Path))...)
+
+	case "ws", "wss":
+		origin := "http://" + u.Host
+		if u.Scheme == "wss" {
+			origin = "https://" + u.Host
+		}
+		return http.NewWebSocketTransport(append(options, transport.WithAddress(address), transport.WithOrigin(origin))...)
+
+	case "mqtt", "mqtts":
+		broker := "tcp://" + u.Host
+		if u.Scheme == "mqtts" {
+			broker = "ssl://" + u.Host
+			if tlsConfig == nil {
+				tlsConfig = &tls.Config{}
+			}
+		}
+		if tlsConfig != nil {
+			options = append(options, transport.WithTLSConfig(tlsConfig))
+		}
+		return mqtt.NewTransport(append(options, transport.WithAddress(broker))...)
+	}
+
+	return nil, fmt.Errorf("unsupported address scheme %q", u.Scheme)
+}
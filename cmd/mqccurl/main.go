@@ -0,0 +1,158 @@
+// Command mqccurl invokes methods of mqc servers from the command line.
+//
+// Usage:
+//
+//	mqccurl [flags] ADDRESS list
+//	mqccurl [flags] ADDRESS call SERVICE/METHOD
+//	mqccurl [flags] ADDRESS publish SERVICE/METHOD
+//	mqccurl [flags] ADDRESS subscribe SERVICE/METHOD
+//
+// Methods are described by the reflection service of the server, or by
+// local .proto files given with -proto. Requests are read as JSON from -d,
+// or from stdin with -d @, and converted to the serializer of the transport.
+// Client and bidirectional streaming methods and publish accept a sequence
+// of JSON objects. Responses and received messages are printed as JSON lines.
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+)
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), `Usage: mqccurl [flags] ADDRESS COMMAND [SERVICE/METHOD]
+
+Commands:
+  list                        list services and methods
+  call SERVICE/METHOD         invoke an RPC method
+  publish SERVICE/METHOD      publish messages on a pub-sub method
+  subscribe SERVICE/METHOD    print messages published on a pub-sub method
+
+Addresses:
+  tcp://host:port, unix:///path, ws://host:port/path, wss://host:port/path,
+  mqtt://host:port, mqtts://host:port
+
+Flags:
+`)
+	flag.PrintDefaults()
+}
+
+func main() {
+	data := flag.String("d", "", "JSON request data, @file to read a file or @ to read stdin")
+	protoFiles := flag.String("proto", "", "comma-separated .proto files describing the methods, instead of reflection")
+	importPaths := flag.String("I", ".", "comma-separated import paths for -proto")
+	serializer := flag.String("serializer", "", "serializer of the server, proto or json (default: transport default)")
+	timeout := flag.Duration("timeout", 0, "call timeout, 0 for none")
+	connectTimeout := flag.Duration("connect-timeout", 5*time.Second, "connect timeout")
+	useTLS := flag.Bool("tls", false, "connect with TLS")
+	insecure := flag.Bool("insecure", false, "skip TLS certificate verification")
+	flag.Usage = usage
+	flag.Parse()
+
+	if err := run(flag.Args(), *data, *protoFiles, *importPaths, *serializer, *timeout, *connectTimeout, *useTLS, *insecure); err != nil {
+		fmt.Fprintln(os.Stderr, "mqccurl:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, data, protoFiles, importPaths, serializer string, timeout, connectTimeout time.Duration, useTLS, insecure bool) error {
+	if len(args) < 2 || (args[1] != "list" && len(args) != 3) {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	var tlsConfig *tls.Config
+	if useTLS || insecure {
+		tlsConfig = &tls.Config{InsecureSkipVerify: insecure}
+	}
+
+	transport, err := newTransport(args[0], tlsConfig, connectTimeout)
+	if err != nil {
+		return err
+	}
+	defer transport.Close()
+
+	if serializer == "" {
+		serializer = serializerName(transport.Serializer())
+	}
+	codec, err := newCodec(serializer)
+	if err != nil {
+		return err
+	}
+
+	input, err := openInput(data)
+	if err != nil {
+		return err
+	}
+
+	var s *schema
+	if protoFiles != "" {
+		s, err = loadFiles(ctx, strings.Split(protoFiles, ","), strings.Split(importPaths, ","))
+	} else {
+		s, err = loadReflection(ctx, transport)
+	}
+	if err != nil {
+		return err
+	}
+
+	c := &client{
+		transport: transport,
+		codec:     codec,
+		input:     json.NewDecoder(input),
+		output:    os.Stdout,
+	}
+
+	if args[1] == "list" {
+		return c.list(s)
+	}
+
+	info, err := s.find(args[2])
+	if err != nil {
+		return err
+	}
+
+	switch args[1] {
+	case "call":
+		return c.call(ctx, info)
+	case "publish":
+		return c.publish(ctx, info)
+	case "subscribe":
+		err := c.subscribe(ctx, info)
+		if ctx.Err() != nil {
+			// Interrupted or timed out, which is how subscriptions end
+			return nil
+		}
+		return err
+	}
+
+	return fmt.Errorf("unknown command %q", args[1])
+}
+
+// openInput returns the request data given with -d.
+func openInput(data string) (io.Reader, error) {
+	switch {
+	case data == "@":
+		return os.Stdin, nil
+	case strings.HasPrefix(data, "@"):
+		return os.Open(strings.TrimPrefix(data, "@"))
+	}
+	return bytes.NewReader([]byte(data)), nil
+}
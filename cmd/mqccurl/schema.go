@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/bufbuild/protocompile"
+	"github.com/srand/mqc"
+	mqcoptions "github.com/srand/mqc/options"
+	"github.com/srand/mqc/reflection"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// methodInfo describes a method that can be invoked.
+type methodInfo struct {
+	// method identifies the RPC method, nil if the method is pub-sub only.
+	method *mqc.Method
+	// pubsub reports whether the method can be published and subscribed to.
+	pubsub bool
+	// desc is the schema of the method, nil if the server did not describe it.
+	desc protoreflect.MethodDescriptor
+}
+
+func (m *methodInfo) name() string {
+	if m.method != nil {
+		return m.method.FullName()
+	}
+	return goCamelCase(string(m.desc.Parent().Name())) + "/" + goCamelCase(string(m.desc.Name()))
+}
+
+// schema holds the methods known from reflection or local schema files.
+type schema struct {
+	methods []*methodInfo
+}
+
+// find returns a method by its mqc name, <service>/<method>, where the
+// service may also be given by its fully qualified schema name.
+func (s *schema) find(name string) (*methodInfo, error) {
+	for _, m := range s.methods {
+		if m.name() == name {
+			return m, nil
+		}
+		if m.desc != nil && string(m.desc.Parent().FullName())+"/"+goCamelCase(string(m.desc.Name())) == name {
+			return m, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown method %q", name)
+}
+
+// addService adds the methods declared by a service schema.
+func (s *schema) addService(desc protoreflect.ServiceDescriptor) {
+	service := goCamelCase(string(desc.Name()))
+
+	for i := 0; i < desc.Methods().Len(); i++ {
+		md := desc.Methods().Get(i)
+		info := &methodInfo{desc: md}
+
+		kind := methodKind(md)
+		if kind != mqcoptions.Kind_KIND_PUBSUB {
+			info.method = mqc.NewMethod(service+"/"+goCamelCase(string(md.Name())), rpcMethodType(md))
+		}
+		switch kind {
+		case mqcoptions.Kind_KIND_PUBSUB:
+			info.pubsub = true
+		case mqcoptions.Kind_KIND_UNSPECIFIED:
+			info.pubsub = md.IsStreamingClient() && md.IsStreamingServer() && md.Input() == md.Output()
+		}
+
+		s.methods = append(s.methods, info)
+	}
+}
+
+// loadFiles builds a schema from local .proto files.
+func loadFiles(ctx context.Context, files, importPaths []string) (*schema, error) {
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{ImportPaths: importPaths}),
+	}
+
+	compiled, err := compiler.Compile(ctx, files...)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &schema{}
+	for _, file := range compiled {
+		for i := 0; i < file.Services().Len(); i++ {
+			s.addService(file.Services().Get(i))
+		}
+	}
+	return s, nil
+}
+
+// loadReflection builds a schema from the reflection service of a server.
+// Methods of services the server does not describe are listed without schema.
+func loadReflection(ctx context.Context, transport mqc.Transport) (*schema, error) {
+	client := reflection.NewServerReflectionClient(transport)
+
+	res, err := client.ListServices(ctx, &reflection.ListServicesRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("reflection: %w", err)
+	}
+
+	set := &descriptorpb.FileDescriptorSet{}
+	seen := map[string]bool{}
+	for _, service := range res.Services {
+		if service.FullName == "" {
+			continue
+		}
+
+		files, err := client.FileContainingSymbol(ctx, &reflection.FileContainingSymbolRequest{Symbol: service.FullName})
+		if err != nil {
+			return nil, fmt.Errorf("reflection: %s: %w", service.FullName, err)
+		}
+
+		for _, data := range files.FileDescriptorProto {
+			file := &descriptorpb.FileDescriptorProto{}
+			if err := proto.Unmarshal(data, file); err != nil {
+				return nil, err
+			}
+			if !seen[file.GetName()] {
+				seen[file.GetName()] = true
+				set.File = append(set.File, file)
+			}
+		}
+	}
+
+	registry, err := protodesc.NewFiles(set)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &schema{}
+	for _, service := range res.Services {
+		if service.FullName != "" {
+			desc, err := registry.FindDescriptorByName(protoreflect.FullName(service.FullName))
+			if err != nil {
+				return nil, err
+			}
+			if sd, ok := desc.(protoreflect.ServiceDescriptor); ok {
+				s.addService(sd)
+				continue
+			}
+		}
+
+		for _, method := range service.Methods {
+			s.methods = append(s.methods, &methodInfo{
+				method: mqc.NewMethod(service.Name+"/"+method.Name, int(method.Type)),
+			})
+		}
+	}
+	return s, nil
+}
+
+func rpcMethodType(md protoreflect.MethodDescriptor) int {
+	if md.IsStreamingClient() && md.IsStreamingServer() {
+		return mqc.MethodTypeBidiStream
+	} else if md.IsStreamingClient() {
+		return mqc.MethodTypeClientStream
+	} else if md.IsStreamingServer() {
+		return mqc.MethodTypeServerStream
+	}
+	return mqc.MethodTypeUnary
+}
+
+// methodKind returns the declared kind of a method, or of its service.
+// Options are re-parsed, since schemas compiled from source or received
+// through reflection do not carry the generated extension types.
+func methodKind(md protoreflect.MethodDescriptor) mqcoptions.Kind {
+	methodOptions := &descriptorpb.MethodOptions{}
+	if data, err := proto.Marshal(md.Options()); err == nil && proto.Unmarshal(data, methodOptions) == nil {
+		if opts, ok := proto.GetExtension(methodOptions, mqcoptions.E_Method).(*mqcoptions.MethodOptions); ok && opts.GetKind() != mqcoptions.Kind_KIND_UNSPECIFIED {
+			return opts.GetKind()
+		}
+	}
+
+	serviceOptions := &descriptorpb.ServiceOptions{}
+	if data, err := proto.Marshal(md.Parent().Options()); err == nil && proto.Unmarshal(data, serviceOptions) == nil {
+		if opts, ok := proto.GetExtension(serviceOptions, mqcoptions.E_Service).(*mqcoptions.ServiceOptions); ok {
+			return opts.GetKind()
+		}
+	}
+
+	return mqcoptions.Kind_KIND_UNSPECIFIED
+}
+
+func methodTypeName(methodType int) string {
+	switch methodType {
+	case mqc.MethodTypeUnary:
+		return "unary"
+	case mqc.MethodTypeServerStream:
+		return "server-stream"
+	case mqc.MethodTypeClientStream:
+		return "client-stream"
+	case mqc.MethodTypeBidiStream:
+		return "bidi-stream"
+	case mqc.MethodTypePublisher, mqc.MethodTypeConsumer:
+		return "pubsub"
+	}
+	return "unknown"
+}
+
+// goCamelCase converts a schema name to the Go name used by the generators,
+// and thus in mqc method names.
+func goCamelCase(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '.' && i+1 < len(s) && isASCIILower(s[i+1]):
+			// Skip over '.' in ".{{lowercase}}"
+		case c == '.':
+			b.WriteByte('_')
+		case c == '_' && (i == 0 || s[i-1] == '.'):
+			b.WriteByte('X')
+		case c == '_' && i+1 < len(s) && isASCIILower(s[i+1]):
+			// Skip over '_' in "_{{lowercase}}"
+		case isASCIIDigit(c):
+			b.WriteByte(c)
+		default:
+			if isASCIILower(c) {
+				c -= 'a' - 'A'
+			}
+			b.WriteByte(c)
+			for ; i+1 < len(s) && isASCIILower(s[i+1]); i++ {
+				b.WriteByte(s[i+1])
+			}
+		}
+	}
+	return b.String()
+}
+
+func isASCIILower(c byte) bool {
+	return 'a' <= c && c <= 'z'
+}
+
+func isASCIIDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
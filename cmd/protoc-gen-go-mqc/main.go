@@ -3,16 +3,30 @@ package main
 import (
 	"flag"
 	"fmt"
+	"strconv"
+	"strings"
 	"unicode"
 
 	"github.com/srand/mqc"
+	mqcoptions "github.com/srand/mqc/options"
 	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
 )
 
 const version = "1.0.0"
 
+const (
+	contextPackage       = protogen.GoImportPath("context")
+	fmtPackage           = protogen.GoImportPath("fmt")
+	timePackage          = protogen.GoImportPath("time")
+	mqcPackage           = protogen.GoImportPath("github.com/srand/mqc")
+	mqctestPackage       = protogen.GoImportPath("github.com/srand/mqc/mqctest")
+	serializationPackage = protogen.GoImportPath("github.com/srand/mqc/serialization")
+)
+
 func main() {
 	showVersion := flag.Bool("version", false, "print the version and exit")
+	mocks := flag.Bool("mocks", false, "generate mock clients and fake streams")
 	flag.Parse()
 	if *showVersion {
 		fmt.Printf("protoc-gen-go-mqc %v\n", version)
@@ -22,16 +36,27 @@ func main() {
 	protogen.Options{
 		ParamFunc: flag.Set,
 	}.Run(func(gen *protogen.Plugin) error {
-		for _, f := range gen.Files {
-			if !f.Generate {
-				continue
-			}
-			generateFile(gen, f)
-		}
-		return nil
+		return generate(gen, *mocks)
 	})
 }
 
+// generate generates the files requested by the plugin.
+func generate(gen *protogen.Plugin, mocks bool) error {
+	for _, f := range gen.Files {
+		if !f.Generate || len(f.Services) == 0 {
+			continue
+		}
+		if err := validateFile(f); err != nil {
+			return err
+		}
+		generateFile(gen, f)
+		if mocks && hasRpcMethods(f) {
+			generateMockFile(gen, f)
+		}
+	}
+	return nil
+}
+
 // Makes leading character lowercase
 func unexport(name string) string {
 	if len(name) == 0 {
@@ -40,21 +65,27 @@ func unexport(name string) string {
 	return string(unicode.ToLower(rune(name[0]))) + name[1:]
 }
 
-func methodCtor(svc *protogen.Service, m *protogen.Method, methodType int) string {
+// methodTypeIdent returns the MethodType constant of a method.
+// Unless a pub-sub method type is requested, the RPC method type is returned.
+func methodTypeIdent(m *protogen.Method, methodType int) string {
 	if methodType == mqc.MethodTypePublisher {
-		return fmt.Sprintf("mqc.NewMethod(\"%s/%s\", mqc.MethodTypePublisher)", svc.GoName, m.GoName)
+		return "mqc.MethodTypePublisher"
 	}
 	if methodType == mqc.MethodTypeConsumer {
-		return fmt.Sprintf("mqc.NewMethod(\"%s/%s\", mqc.MethodTypeConsumer)", svc.GoName, m.GoName)
+		return "mqc.MethodTypeConsumer"
 	}
 	if m.Desc.IsStreamingClient() && m.Desc.IsStreamingServer() {
-		return fmt.Sprintf("mqc.NewMethod(\"%s/%s\", mqc.MethodTypeBidiStream)", svc.GoName, m.GoName)
+		return "mqc.MethodTypeBidiStream"
 	} else if m.Desc.IsStreamingClient() {
-		return fmt.Sprintf("mqc.NewMethod(\"%s/%s\", mqc.MethodTypeClientStream)", svc.GoName, m.GoName)
+		return "mqc.MethodTypeClientStream"
 	} else if m.Desc.IsStreamingServer() {
-		return fmt.Sprintf("mqc.NewMethod(\"%s/%s\", mqc.MethodTypeServerStream)", svc.GoName, m.GoName)
+		return "mqc.MethodTypeServerStream"
 	}
-	return fmt.Sprintf("mqc.NewMethod(\"%s/%s\", mqc.MethodTypeUnary)", svc.GoName, m.GoName)
+	return "mqc.MethodTypeUnary"
+}
+
+func methodCtor(svc *protogen.Service, m *protogen.Method, methodType int) string {
+	return fmt.Sprintf("mqc.NewMethod(\"%s/%s\", %s)", svc.GoName, m.GoName, methodTypeIdent(m, methodType))
 }
 
 func clientStreamInterface(g *protogen.GeneratedFile, method *protogen.Method) string {
@@ -64,7 +95,7 @@ func clientStreamInterface(g *protogen.GeneratedFile, method *protogen.Method) s
 	} else if method.Desc.IsStreamingClient() {
 		return "mqc.ClientStreamClient[" + typeParam + "]"
 	} else { // i.e. if method.Desc.IsStreamingServer()
-		return "mqc.ServerStreamClient[" + method.Output.GoIdent.GoName + "]"
+		return "mqc.ServerStreamClient[" + g.QualifiedGoIdent(method.Output.GoIdent) + "]"
 	}
 }
 
@@ -74,9 +105,9 @@ func clientSignature(g *protogen.GeneratedFile, m *protogen.Method) string {
 	} else if m.Desc.IsStreamingClient() {
 		return fmt.Sprintf("%s(ctx context.Context) (%s, error)", m.GoName, clientStreamInterface(g, m))
 	} else if m.Desc.IsStreamingServer() {
-		return fmt.Sprintf("%s(ctx context.Context, req *%s) (%s, error)", m.GoName, m.Input.GoIdent.GoName, clientStreamInterface(g, m))
+		return fmt.Sprintf("%s(ctx context.Context, req *%s) (%s, error)", m.GoName, g.QualifiedGoIdent(m.Input.GoIdent), clientStreamInterface(g, m))
 	}
-	return fmt.Sprintf("%s(ctx context.Context, req *%s) (*%s, error)", m.GoName, m.Input.GoIdent.GoName, m.Output.GoIdent.GoName)
+	return fmt.Sprintf("%s(ctx context.Context, req *%s) (*%s, error)", m.GoName, g.QualifiedGoIdent(m.Input.GoIdent), g.QualifiedGoIdent(m.Output.GoIdent))
 }
 
 func serverStreamInterface(g *protogen.GeneratedFile, method *protogen.Method) string {
@@ -92,13 +123,13 @@ func serverStreamInterface(g *protogen.GeneratedFile, method *protogen.Method) s
 
 func serverSignature(g *protogen.GeneratedFile, m *protogen.Method) string {
 	if m.Desc.IsStreamingClient() && m.Desc.IsStreamingServer() {
-		return fmt.Sprintf("%s(stream %s) error", m.GoName, serverStreamInterface(g, m))
+		return fmt.Sprintf("%s(ctx context.Context, stream %s) error", m.GoName, serverStreamInterface(g, m))
 	} else if m.Desc.IsStreamingClient() {
-		return fmt.Sprintf("%s(stream %s) error", m.GoName, serverStreamInterface(g, m))
+		return fmt.Sprintf("%s(ctx context.Context, stream %s) error", m.GoName, serverStreamInterface(g, m))
 	} else if m.Desc.IsStreamingServer() {
-		return fmt.Sprintf("%s(req *%s, stream %s) error", m.GoName, m.Input.GoIdent.GoName, serverStreamInterface(g, m))
+		return fmt.Sprintf("%s(ctx context.Context, req *%s, stream %s) error", m.GoName, g.QualifiedGoIdent(m.Input.GoIdent), serverStreamInterface(g, m))
 	}
-	return fmt.Sprintf("%s(req *%s) (*%s, error)", m.GoName, m.Input.GoIdent.GoName, m.Output.GoIdent.GoName)
+	return fmt.Sprintf("%s(ctx context.Context, req *%s) (*%s, error)", m.GoName, g.QualifiedGoIdent(m.Input.GoIdent), g.QualifiedGoIdent(m.Output.GoIdent))
 }
 
 func generateFile(gen *protogen.Plugin, f *protogen.File) {
@@ -112,23 +143,38 @@ func generateFile(gen *protogen.Plugin, f *protogen.File) {
 	generateFileContent(g, f)
 }
 
+func generateMockFile(gen *protogen.Plugin, f *protogen.File) {
+	g := gen.NewGeneratedFile(f.GeneratedFilenamePrefix+".mqc.mock.pb.go", f.GoImportPath)
+	g.P("// Code generated by protoc-gen-mqc. DO NOT EDIT.")
+	g.P("// versions:")
+	g.P(fmt.Sprintf("// protoc-gen-go-mqc v%v", version))
+	g.P()
+	g.P("package ", f.GoPackageName)
+	g.P()
+	generateMockFileContent(g, f)
+}
+
 func generateFileContent(g *protogen.GeneratedFile, f *protogen.File) {
 	// generate imports
-	generateImports(g)
+	generateImports(g, f)
 
 	// generate service interfaces
 	for _, svc := range f.Services {
 		generateClientInterface(g, svc)
 		generateServerInterface(g, svc)
-		generateConsumerInterface(g, svc)
-		generatePublisherInterface(g, svc)
+		if len(pubsubMethods(svc)) > 0 {
+			generateConsumerInterface(g, svc)
+			generatePublisherInterface(g, svc)
+		}
 	}
 
 	// generate client structs and methods
 	for _, svc := range f.Services {
 		generateClient(g, svc)
-		generateConsumer(g, svc)
-		generatePublisher(g, svc)
+		if len(pubsubMethods(svc)) > 0 {
+			generateConsumer(g, svc)
+			generatePublisher(g, svc)
+		}
 	}
 
 	// generate server structs and methods
@@ -136,12 +182,17 @@ func generateFileContent(g *protogen.GeneratedFile, f *protogen.File) {
 		generateServerStub(g, svc)
 		generateServerRegistration(g, svc)
 	}
+
+	// generate service description and method options registration
+	for _, svc := range f.Services {
+		generateRegistration(g, f, svc)
+	}
 }
 
 func generateClientInterface(g *protogen.GeneratedFile, svc *protogen.Service) {
 	// generate service interface
 	g.P("type ", svc.GoName, "Client interface {")
-	for _, m := range svc.Methods {
+	for _, m := range rpcMethods(svc) {
 		g.P(clientSignature(g, m))
 	}
 	g.P("}")
@@ -151,7 +202,7 @@ func generateClientInterface(g *protogen.GeneratedFile, svc *protogen.Service) {
 func generateServerInterface(g *protogen.GeneratedFile, svc *protogen.Service) {
 	// generate service interface
 	g.P("type ", svc.GoName, "Server interface {")
-	for _, m := range svc.Methods {
+	for _, m := range rpcMethods(svc) {
 		g.P(serverSignature(g, m))
 	}
 	g.P("}")
@@ -161,14 +212,8 @@ func generateServerInterface(g *protogen.GeneratedFile, svc *protogen.Service) {
 func generateConsumerInterface(g *protogen.GeneratedFile, svc *protogen.Service) {
 	// generate service interface
 	g.P("type ", svc.GoName, "Consumer interface {")
-	for _, m := range svc.Methods {
-		if m.Input != m.Output {
-			continue
-		}
-
-		if m.Desc.IsStreamingClient() && m.Desc.IsStreamingServer() {
-			g.P(m.GoName, "(ctx context.Context) (", clientStreamInterface(g, m), ", error)")
-		}
+	for _, m := range pubsubMethods(svc) {
+		g.P(m.GoName, "(ctx context.Context) (mqc.Subscriber[", g.QualifiedGoIdent(m.Input.GoIdent), "], error)")
 	}
 	g.P("}")
 	g.P()
@@ -177,14 +222,8 @@ func generateConsumerInterface(g *protogen.GeneratedFile, svc *protogen.Service)
 func generatePublisherInterface(g *protogen.GeneratedFile, svc *protogen.Service) {
 	// generate service interface
 	g.P("type ", svc.GoName, "Publisher interface {")
-	for _, m := range svc.Methods {
-		if m.Input != m.Output {
-			continue
-		}
-
-		if m.Desc.IsStreamingClient() && m.Desc.IsStreamingServer() {
-			g.P(m.GoName, "(ctx context.Context) (", serverStreamInterface(g, m), ", error)")
-		}
+	for _, m := range pubsubMethods(svc) {
+		g.P(m.GoName, "(ctx context.Context) (mqc.Publisher[", g.QualifiedGoIdent(m.Input.GoIdent), "], error)")
 	}
 	g.P("}")
 	g.P()
@@ -195,27 +234,29 @@ func generateClient(g *protogen.GeneratedFile, svc *protogen.Service) {
 
 	// generate client struct
 	g.P("type ", typeName, " struct {")
-	g.P("transport mqc.Transport")
+	g.P("conn mqc.ClientConn")
 	g.P("}")
 	g.P()
 
 	// generate client constructor
-	g.P("func New", svc.GoName, "Client(transport mqc.Transport) *", typeName, " {")
-	g.P("return &", typeName, "{transport: transport}")
+	g.P("func New", svc.GoName, "Client(conn mqc.ClientConn) *", typeName, " {")
+	g.P("return &", typeName, "{conn: conn}")
 	g.P("}")
 	g.P()
+	g.P("var _ ", svc.GoName, "Client = (*", typeName, ")(nil)")
+	g.P()
 
 	// generate client methods
-	for _, m := range svc.Methods {
+	for _, m := range rpcMethods(svc) {
 		g.P("func (c *", typeName, ") ", clientSignature(g, m), " {")
 		if m.Desc.IsStreamingClient() && m.Desc.IsStreamingServer() {
-			g.P("return mqc.NewBidiStreamClient[", g.QualifiedGoIdent(m.Input.GoIdent), ", ", g.QualifiedGoIdent(m.Output.GoIdent), "](ctx, c.transport, ", methodCtor(svc, m, mqc.MethodTypeBidiStream), ")")
+			g.P("return mqc.NewBidiStreamClient[", g.QualifiedGoIdent(m.Input.GoIdent), ", ", g.QualifiedGoIdent(m.Output.GoIdent), "](ctx, c.conn, ", methodCtor(svc, m, mqc.MethodTypeBidiStream), ")")
 		} else if m.Desc.IsStreamingClient() {
-			g.P("return mqc.NewClientStreamClient[", g.QualifiedGoIdent(m.Input.GoIdent), ", ", g.QualifiedGoIdent(m.Output.GoIdent), "](ctx, c.transport, ", methodCtor(svc, m, mqc.MethodTypeClientStream), ")")
+			g.P("return mqc.NewClientStreamClient[", g.QualifiedGoIdent(m.Input.GoIdent), ", ", g.QualifiedGoIdent(m.Output.GoIdent), "](ctx, c.conn, ", methodCtor(svc, m, mqc.MethodTypeClientStream), ")")
 		} else if m.Desc.IsStreamingServer() {
-			g.P("return mqc.NewServerStreamClient[", g.QualifiedGoIdent(m.Input.GoIdent), ", ", g.QualifiedGoIdent(m.Output.GoIdent), "](ctx, c.transport, ", methodCtor(svc, m, mqc.MethodTypeServerStream), ", req)")
+			g.P("return mqc.NewServerStreamClient[", g.QualifiedGoIdent(m.Input.GoIdent), ", ", g.QualifiedGoIdent(m.Output.GoIdent), "](ctx, c.conn, ", methodCtor(svc, m, mqc.MethodTypeServerStream), ", req)")
 		} else {
-			g.P("return mqc.Rpc[", g.QualifiedGoIdent(m.Input.GoIdent), ", ", g.QualifiedGoIdent(m.Output.GoIdent), "](ctx, c.transport, ", methodCtor(svc, m, mqc.MethodTypeUnary), ", req)")
+			g.P("return mqc.Rpc[", g.QualifiedGoIdent(m.Input.GoIdent), ", ", g.QualifiedGoIdent(m.Output.GoIdent), "](ctx, c.conn, ", methodCtor(svc, m, mqc.MethodTypeUnary), ", req)")
 		}
 		g.P("}")
 		g.P()
@@ -227,28 +268,24 @@ func generatePublisher(g *protogen.GeneratedFile, svc *protogen.Service) {
 
 	// generate publisher struct
 	g.P("type ", typeName, " struct {")
-	g.P("transport mqc.Transport")
+	g.P("conn mqc.ClientConn")
 	g.P("}")
 	g.P()
 
 	// generate publisher constructor
-	g.P("func New", svc.GoName, "Publisher(transport mqc.Transport) *", typeName, " {")
-	g.P("return &", typeName, "{transport: transport}")
+	g.P("func New", svc.GoName, "Publisher(conn mqc.ClientConn) *", typeName, " {")
+	g.P("return &", typeName, "{conn: conn}")
 	g.P("}")
 	g.P()
+	g.P("var _ ", svc.GoName, "Publisher = (*", typeName, ")(nil)")
+	g.P()
 
 	// generate publisher methods
-	for _, m := range svc.Methods {
-		if m.Input != m.Output {
-			continue
-		}
-
-		if m.Desc.IsStreamingClient() && m.Desc.IsStreamingServer() {
-			g.P("func (c *", typeName, ") ", m.GoName, "(ctx context.Context) (mqc.ServerStreamServer[", g.QualifiedGoIdent(m.Input.GoIdent), "], error) {")
-			g.P("return mqc.NewPubSubClient[", g.QualifiedGoIdent(m.Input.GoIdent), "](ctx, c.transport, ", methodCtor(svc, m, mqc.MethodTypePublisher), ")")
-			g.P("}")
-			g.P()
-		}
+	for _, m := range pubsubMethods(svc) {
+		g.P("func (c *", typeName, ") ", m.GoName, "(ctx context.Context) (mqc.Publisher[", g.QualifiedGoIdent(m.Input.GoIdent), "], error) {")
+		g.P("return mqc.NewPublisher[", g.QualifiedGoIdent(m.Input.GoIdent), "](ctx, c.conn, ", methodCtor(svc, m, mqc.MethodTypePublisher), ")")
+		g.P("}")
+		g.P()
 	}
 }
 
@@ -257,28 +294,24 @@ func generateConsumer(g *protogen.GeneratedFile, svc *protogen.Service) {
 
 	// generate consumer struct
 	g.P("type ", typeName, " struct {")
-	g.P("transport mqc.Transport")
+	g.P("conn mqc.ClientConn")
 	g.P("}")
 	g.P()
 
 	// generate consumer constructor
-	g.P("func New", svc.GoName, "Consumer(transport mqc.Transport) *", typeName, " {")
-	g.P("return &", typeName, "{transport: transport}")
+	g.P("func New", svc.GoName, "Consumer(conn mqc.ClientConn) *", typeName, " {")
+	g.P("return &", typeName, "{conn: conn}")
 	g.P("}")
 	g.P()
+	g.P("var _ ", svc.GoName, "Consumer = (*", typeName, ")(nil)")
+	g.P()
 
 	// generate consumer methods
-	for _, m := range svc.Methods {
-		if m.Input != m.Output {
-			continue
-		}
-
-		if m.Desc.IsStreamingClient() && m.Desc.IsStreamingServer() {
-			g.P("func (c *", typeName, ") ", m.GoName, "(ctx context.Context) (mqc.ServerStreamClient[", g.QualifiedGoIdent(m.Input.GoIdent), "], error) {")
-			g.P("return mqc.NewPubSubClient[", g.QualifiedGoIdent(m.Input.GoIdent), "](ctx, c.transport, ", methodCtor(svc, m, mqc.MethodTypeConsumer), ")")
-			g.P("}")
-			g.P()
-		}
+	for _, m := range pubsubMethods(svc) {
+		g.P("func (c *", typeName, ") ", m.GoName, "(ctx context.Context) (mqc.Subscriber[", g.QualifiedGoIdent(m.Input.GoIdent), "], error) {")
+		g.P("return mqc.NewSubscriber[", g.QualifiedGoIdent(m.Input.GoIdent), "](ctx, c.conn, ", methodCtor(svc, m, mqc.MethodTypeConsumer), ")")
+		g.P("}")
+		g.P()
 	}
 }
 
@@ -288,15 +321,15 @@ func generateServerStub(g *protogen.GeneratedFile, svc *protogen.Service) {
 	g.P()
 
 	// generate unimplemented methods
-	for _, m := range svc.Methods {
+	for _, m := range rpcMethods(svc) {
 		g.P("func (s *Unimplemented", svc.GoName, "Server) ", serverSignature(g, m), " {")
 		if m.Desc.IsStreamingClient() || m.Desc.IsStreamingServer() {
-			g.P("return fmt.Errorf(\"method ", m.GoName, " not implemented\")")
+			g.P("return mqc.ErrUnimplemented")
 			g.P("}")
 			g.P()
 			continue
 		}
-		g.P("return nil, fmt.Errorf(\"method ", m.GoName, " not implemented\")")
+		g.P("return nil, mqc.ErrUnimplemented")
 		g.P("}")
 		g.P()
 	}
@@ -305,44 +338,326 @@ func generateServerStub(g *protogen.GeneratedFile, svc *protogen.Service) {
 func generateServerRegistration(g *protogen.GeneratedFile, svc *protogen.Service) {
 	// Create Register function
 	// Handlers are automatically registered
-	g.P("func Register", svc.GoName, "Server(transport mqc.Transport, server ", svc.GoName, "Server) {")
+	g.P("func Register", svc.GoName, "Server(transport mqc.Transport, server ", svc.GoName, "Server) error {")
 
-	for _, m := range svc.Methods {
-		g.P("transport.RegisterHandler(" + methodCtor(svc, m, -1) + ", func(conn mqc.Conn) error {")
+	for _, m := range rpcMethods(svc) {
+		g.P("if err := transport.RegisterHandler(" + methodCtor(svc, m, -1) + ", func(ctx context.Context, conn mqc.Conn) error {")
 		if m.Desc.IsStreamingClient() && m.Desc.IsStreamingServer() {
 			g.P("stream, err := mqc.NewBidiStreamServer[", g.QualifiedGoIdent(m.Input.GoIdent), ", ", g.QualifiedGoIdent(m.Output.GoIdent), "](transport, conn)")
 			g.P("if err != nil {")
 			g.P("return err")
 			g.P("}")
-			g.P("return server.", m.GoName, "(stream)")
+			g.P("return server.", m.GoName, "(ctx, stream)")
 		} else if m.Desc.IsStreamingClient() {
 			g.P("stream, err := mqc.NewClientStreamServer[", g.QualifiedGoIdent(m.Input.GoIdent), ", ", g.QualifiedGoIdent(m.Output.GoIdent), "](transport, conn)")
 			g.P("if err != nil {")
 			g.P("return err")
 			g.P("}")
-			g.P("return server.", m.GoName, "(stream)")
+			g.P("return server.", m.GoName, "(ctx, stream)")
 		} else if m.Desc.IsStreamingServer() {
-			g.P("stream, req, err := mqc.NewServerStreamServer[", g.QualifiedGoIdent(m.Input.GoIdent), ", ", g.QualifiedGoIdent(m.Output.GoIdent), "](transport, conn)")
+			g.P("stream, req, err := mqc.NewServerStreamServer[", g.QualifiedGoIdent(m.Input.GoIdent), ", ", g.QualifiedGoIdent(m.Output.GoIdent), "](ctx, transport, conn)")
 			g.P("if err != nil {")
 			g.P("return err")
 			g.P("}")
-			g.P("return server.", m.GoName, "(req, stream)")
+			g.P("return server.", m.GoName, "(ctx, req, stream)")
 		} else {
-			g.P("return mqc.RpcServer(conn, transport.Serializer(), func (req *", g.QualifiedGoIdent(m.Input.GoIdent), ") (*", g.QualifiedGoIdent(m.Output.GoIdent), ", error) {")
-			g.P("return server.", m.GoName, "(req)")
+			g.P("return mqc.RpcServer(ctx, conn, transport.Serializer(), func (ctx context.Context, req *", g.QualifiedGoIdent(m.Input.GoIdent), ") (*", g.QualifiedGoIdent(m.Output.GoIdent), ", error) {")
+			g.P("return server.", m.GoName, "(ctx, req)")
 			g.P("})")
 		}
+		g.P("}); err != nil {")
+		g.P("return err")
+		g.P("}")
+	}
+	g.P("return nil")
+	g.P("}")
+	g.P()
+
+	// Services are registered on all transports of a server
+	g.P("func Register", svc.GoName, "Service(s *mqc.Server, server ", svc.GoName, "Server) error {")
+	g.P("return s.RegisterService(", strconv.Quote(svc.GoName), ", func(transport mqc.Transport) error {")
+	g.P("return Register", svc.GoName, "Server(transport, server)")
+	g.P("})")
+	g.P("}")
+	g.P()
+}
+
+func generateImports(g *protogen.GeneratedFile, f *protogen.File) {
+	var needsTime, needsSerialization bool
+	for _, svc := range f.Services {
+		for _, m := range svc.Methods {
+			opts := methodOptions(svc, m)
+			needsTime = needsTime || opts.GetTimeoutMs() > 0
+			needsSerialization = needsSerialization || opts.GetSerializer() != mqcoptions.Serializer_SERIALIZER_UNSPECIFIED
+		}
+	}
+
+	importPackage(g, contextPackage)
+	if needsTime {
+		importPackage(g, timePackage)
+	}
+	importPackage(g, mqcPackage)
+	if needsSerialization {
+		importPackage(g, serializationPackage)
+	}
+}
+
+// importPackage adds a package to the import block of the generated file.
+// Generated code refers to these packages by their default name, so they
+// must be imported before any message type is qualified, which gives
+// clashing message packages a different name instead.
+func importPackage(g *protogen.GeneratedFile, importPath protogen.GoImportPath) {
+	g.QualifiedGoIdent(importPath.Ident(""))
+}
+
+// methodOptions returns the options of a method merged with the options of its service.
+// Options set on the method take precedence.
+func methodOptions(svc *protogen.Service, m *protogen.Method) *mqcoptions.MethodOptions {
+	merged := &mqcoptions.MethodOptions{}
+
+	if opts, ok := proto.GetExtension(svc.Desc.Options(), mqcoptions.E_Service).(*mqcoptions.ServiceOptions); ok && opts != nil {
+		merged.Kind = opts.Kind
+		merged.TimeoutMs = opts.TimeoutMs
+		merged.Idempotent = opts.Idempotent
+		merged.Qos = opts.Qos
+		merged.Topic = opts.Topic
+		merged.Serializer = opts.Serializer
+	}
+
+	if opts, ok := proto.GetExtension(m.Desc.Options(), mqcoptions.E_Method).(*mqcoptions.MethodOptions); ok && opts != nil {
+		if opts.Kind != mqcoptions.Kind_KIND_UNSPECIFIED {
+			merged.Kind = opts.Kind
+		}
+		if opts.TimeoutMs > 0 {
+			merged.TimeoutMs = opts.TimeoutMs
+		}
+		if opts.Idempotent {
+			merged.Idempotent = true
+		}
+		if opts.Qos != mqcoptions.QoS_QOS_UNSPECIFIED {
+			merged.Qos = opts.Qos
+		}
+		if opts.Topic != "" {
+			merged.Topic = opts.Topic
+		}
+		if opts.Serializer != mqcoptions.Serializer_SERIALIZER_UNSPECIFIED {
+			merged.Serializer = opts.Serializer
+		}
+	}
+
+	return merged
+}
+
+// isPubSub reports whether a method is exposed as pub-sub.
+// Without a declared kind, bidirectional streaming methods with
+// the same input and output type are exposed both as RPC and pub-sub.
+func isPubSub(svc *protogen.Service, m *protogen.Method) bool {
+	switch methodOptions(svc, m).GetKind() {
+	case mqcoptions.Kind_KIND_PUBSUB:
+		return true
+	case mqcoptions.Kind_KIND_RPC:
+		return false
+	}
+	return m.Input == m.Output && m.Desc.IsStreamingClient() && m.Desc.IsStreamingServer()
+}
+
+// isRpc reports whether a method is exposed as RPC.
+func isRpc(svc *protogen.Service, m *protogen.Method) bool {
+	return methodOptions(svc, m).GetKind() != mqcoptions.Kind_KIND_PUBSUB
+}
+
+func rpcMethods(svc *protogen.Service) []*protogen.Method {
+	var methods []*protogen.Method
+	for _, m := range svc.Methods {
+		if isRpc(svc, m) {
+			methods = append(methods, m)
+		}
+	}
+	return methods
+}
+
+func pubsubMethods(svc *protogen.Service) []*protogen.Method {
+	var methods []*protogen.Method
+	for _, m := range svc.Methods {
+		if isPubSub(svc, m) {
+			methods = append(methods, m)
+		}
+	}
+	return methods
+}
+
+// validateFile checks that the declared method options are consistent.
+func validateFile(f *protogen.File) error {
+	for _, svc := range f.Services {
+		for _, m := range svc.Methods {
+			if methodOptions(svc, m).GetKind() != mqcoptions.Kind_KIND_PUBSUB {
+				continue
+			}
+			if m.Input != m.Output {
+				return fmt.Errorf("%s: pub-sub method must have the same input and output type", m.Desc.FullName())
+			}
+		}
+	}
+	return nil
+}
+
+func generateRegistration(g *protogen.GeneratedFile, f *protogen.File, svc *protogen.Service) {
+	var methods []*protogen.Method
+	for _, m := range svc.Methods {
+		opts := methodOptions(svc, m)
+		if opts.TimeoutMs > 0 || opts.Idempotent || opts.Qos != mqcoptions.QoS_QOS_UNSPECIFIED ||
+			opts.Topic != "" || opts.Serializer != mqcoptions.Serializer_SERIALIZER_UNSPECIFIED {
+			methods = append(methods, m)
+		}
+	}
+
+	g.P("func init() {")
+	generateServiceDesc(g, f, svc)
+	for _, m := range methods {
+		opts := methodOptions(svc, m)
+		g.P("mqc.RegisterMethodOptions(", methodCtor(svc, m, -1), ", &mqc.MethodOptions{")
+		if opts.TimeoutMs > 0 {
+			g.P("Timeout: ", opts.TimeoutMs, " * time.Millisecond,")
+		}
+		if opts.Idempotent {
+			g.P("Idempotent: true,")
+		}
+		switch opts.Qos {
+		case mqcoptions.QoS_QOS_AT_MOST_ONCE:
+			g.P("QoS: mqc.QoSAtMostOnce,")
+		case mqcoptions.QoS_QOS_AT_LEAST_ONCE:
+			g.P("QoS: mqc.QoSAtLeastOnce,")
+		case mqcoptions.QoS_QOS_EXACTLY_ONCE:
+			g.P("QoS: mqc.QoSExactlyOnce,")
+		}
+		if opts.Topic != "" {
+			g.P("Topic: ", strconv.Quote(opts.Topic), ",")
+		}
+		switch opts.Serializer {
+		case mqcoptions.Serializer_SERIALIZER_PROTO:
+			g.P("Serializer: serialization.NewProtoSerializer(),")
+		case mqcoptions.Serializer_SERIALIZER_JSON:
+			g.P("Serializer: serialization.NewJSONSerializer(),")
+		}
 		g.P("})")
 	}
 	g.P("}")
 	g.P()
 }
 
-func generateImports(g *protogen.GeneratedFile) {
-	g.P("import (")
-	g.P("\"context\"")
-	g.P("\"fmt\"")
-	g.P("\"github.com/srand/mqc\"")
-	g.P(")")
+func generateServiceDesc(g *protogen.GeneratedFile, f *protogen.File, svc *protogen.Service) {
+	g.P("mqc.RegisterService(&mqc.ServiceDesc{")
+	g.P("Name: ", strconv.Quote(svc.GoName), ",")
+	g.P("FullName: ", strconv.Quote(string(svc.Desc.FullName())), ",")
+	g.P("File: ", strconv.Quote(f.Desc.Path()), ",")
+	g.P("Methods: []mqc.MethodDesc{")
+	for _, m := range svc.Methods {
+		kind := mqc.MethodTypePublisher
+		if isRpc(svc, m) {
+			kind = -1
+		}
+		g.P("{")
+		g.P("Name: ", strconv.Quote(m.GoName), ",")
+		g.P("Type: ", methodTypeIdent(m, kind), ",")
+		g.P("Input: ", strconv.Quote(string(m.Input.Desc.FullName())), ",")
+		g.P("Output: ", strconv.Quote(string(m.Output.Desc.FullName())), ",")
+		g.P("},")
+	}
+	g.P("},")
+	g.P("})")
+}
+
+func generateMockFileContent(g *protogen.GeneratedFile, f *protogen.File) {
+	importPackage(g, contextPackage)
+	importPackage(g, fmtPackage)
+	if hasStreamingMethods(f) {
+		importPackage(g, mqcPackage)
+		importPackage(g, mqctestPackage)
+	}
+
+	for _, svc := range f.Services {
+		generateMockClient(g, svc)
+		generateFakeStreams(g, svc)
+	}
+}
+
+func generateMockClient(g *protogen.GeneratedFile, svc *protogen.Service) {
+	typeName := "Mock" + svc.GoName + "Client"
+
+	// generate mock struct with one function field per method
+	g.P("// ", typeName, " is a mock ", svc.GoName, "Client.")
+	g.P("// Calls are forwarded to the function field named after the method.")
+	g.P("type ", typeName, " struct {")
+	for _, m := range rpcMethods(svc) {
+		g.P(m.GoName, "Func func", strings.TrimPrefix(clientSignature(g, m), m.GoName))
+	}
+	g.P("}")
+	g.P()
+	g.P("var _ ", svc.GoName, "Client = (*", typeName, ")(nil)")
 	g.P()
+
+	// generate mock methods
+	for _, m := range rpcMethods(svc) {
+		g.P("func (c *", typeName, ") ", clientSignature(g, m), " {")
+		g.P("if c.", m.GoName, "Func == nil {")
+		g.P("return nil, fmt.Errorf(\"method ", m.GoName, " not mocked\")")
+		g.P("}")
+		if m.Desc.IsStreamingClient() {
+			g.P("return c.", m.GoName, "Func(ctx)")
+		} else {
+			g.P("return c.", m.GoName, "Func(ctx, req)")
+		}
+		g.P("}")
+		g.P()
+	}
+}
+
+func generateFakeStreams(g *protogen.GeneratedFile, svc *protogen.Service) {
+	for _, m := range rpcMethods(svc) {
+		if !m.Desc.IsStreamingClient() && !m.Desc.IsStreamingServer() {
+			continue
+		}
+
+		input := g.QualifiedGoIdent(m.Input.GoIdent)
+		output := g.QualifiedGoIdent(m.Output.GoIdent)
+
+		var client, server string
+		if m.Desc.IsStreamingClient() && m.Desc.IsStreamingServer() {
+			client = "mqctest.FakeBidiStreamClient[" + input + ", " + output + "]"
+			server = "mqctest.FakeBidiStreamServer[" + input + ", " + output + "]"
+		} else if m.Desc.IsStreamingClient() {
+			client = "mqctest.FakeClientStreamClient[" + input + ", " + output + "]"
+			server = "mqctest.FakeClientStreamServer[" + input + ", " + output + "]"
+		} else {
+			client = "mqctest.FakeServerStreamClient[" + output + "]"
+			server = "mqctest.FakeServerStreamServer[" + output + "]"
+		}
+
+		g.P("// Fake", svc.GoName, m.GoName, "Client is an in-memory client stream for ", svc.GoName, ".", m.GoName, ".")
+		g.P("type Fake", svc.GoName, m.GoName, "Client = ", client)
+		g.P()
+		g.P("// Fake", svc.GoName, m.GoName, "Server is an in-memory server stream for ", svc.GoName, ".", m.GoName, ".")
+		g.P("type Fake", svc.GoName, m.GoName, "Server = ", server)
+		g.P()
+	}
+}
+
+func hasRpcMethods(f *protogen.File) bool {
+	for _, svc := range f.Services {
+		if len(rpcMethods(svc)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func hasStreamingMethods(f *protogen.File) bool {
+	for _, svc := range f.Services {
+		for _, m := range rpcMethods(svc) {
+			if m.Desc.IsStreamingClient() || m.Desc.IsStreamingServer() {
+				return true
+			}
+		}
+	}
+	return false
 }
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"flag"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	_ "github.com/srand/mqc/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gengo "google.golang.org/protobuf/cmd/protoc-gen-go/internal_gengo"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+
+	_ "google.golang.org/protobuf/types/known/emptypb"
+	_ "google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// Fixtures are CodeGeneratorRequests in text format. Files imported by a
+// fixture but not included in it are resolved from the registered Go
+// packages, e.g. the well-known types and the mqc options.
+const (
+	requestsDir = "testdata/requests"
+	goldenDir   = "testdata/golden"
+)
+
+// loadRequest reads a request fixture and adds its missing dependencies.
+func loadRequest(t *testing.T, name string) *pluginpb.CodeGeneratorRequest {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join(requestsDir, name+".textproto"))
+	require.NoError(t, err)
+
+	req := &pluginpb.CodeGeneratorRequest{}
+	require.NoError(t, prototext.Unmarshal(data, req))
+
+	// Dependencies must precede the files importing them
+	present := map[string]bool{}
+	for _, f := range req.ProtoFile {
+		present[f.GetName()] = true
+	}
+
+	var deps []*descriptorpb.FileDescriptorProto
+	var add func(name string)
+	add = func(name string) {
+		if present[name] {
+			return
+		}
+		present[name] = true
+
+		fd, err := protoregistry.GlobalFiles.FindFileByPath(name)
+		require.NoError(t, err, name)
+
+		file := protodesc.ToFileDescriptorProto(fd)
+		for _, dep := range file.Dependency {
+			add(dep)
+		}
+		deps = append(deps, file)
+	}
+	for _, f := range req.ProtoFile {
+		for _, dep := range f.Dependency {
+			add(dep)
+		}
+	}
+
+	req.ProtoFile = append(deps, req.ProtoFile...)
+	return req
+}
+
+// runPlugin runs protoc-gen-go and the generator on a request and returns
+// the generated files by name. Mocks are always generated.
+func runPlugin(t *testing.T, req *pluginpb.CodeGeneratorRequest) map[string]string {
+	t.Helper()
+
+	gen, err := protogen.Options{}.New(req)
+	require.NoError(t, err)
+
+	for _, f := range gen.Files {
+		if f.Generate {
+			gengo.GenerateFile(gen, f)
+		}
+	}
+	require.NoError(t, generate(gen, true))
+
+	res := gen.Response()
+	require.Empty(t, res.GetError())
+
+	files := map[string]string{}
+	for _, f := range res.File {
+		files[f.GetName()] = f.GetContent()
+	}
+	return files
+}
+
+// assertGolden compares the generated mqc files against the golden files.
+// Run the tests with -update to rewrite the golden files.
+func assertGolden(t *testing.T, name string, files map[string]string) {
+	t.Helper()
+
+	dir := filepath.Join(goldenDir, name)
+	if *update {
+		require.NoError(t, os.RemoveAll(dir))
+	}
+
+	var generated []string
+	for file, content := range files {
+		if !strings.Contains(file, ".mqc.") {
+			continue
+		}
+		generated = append(generated, filepath.FromSlash(file))
+
+		golden := filepath.Join(dir, filepath.FromSlash(file)+".golden")
+		if *update {
+			require.NoError(t, os.MkdirAll(filepath.Dir(golden), 0o755))
+			require.NoError(t, os.WriteFile(golden, []byte(content), 0o644))
+			continue
+		}
+
+		expected, err := os.ReadFile(golden)
+		require.NoError(t, err)
+		assert.Equal(t, string(expected), content, golden)
+	}
+
+	// Golden files without a generated counterpart are stale
+	var goldens []string
+	require.NoError(t, filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			rel, _ := filepath.Rel(dir, file)
+			goldens = append(goldens, strings.TrimSuffix(rel, ".golden"))
+		}
+		return err
+	}))
+
+	sort.Strings(generated)
+	sort.Strings(goldens)
+	assert.Equal(t, generated, goldens)
+}
+
+// sourceImporter type-checks packages that are not part of the generated
+// files from source. It is shared between tests to reuse checked packages.
+var sourceImporter = importer.ForCompiler(token.NewFileSet(), "source", nil)
+
+// typeCheck type-checks the generated files, grouped into packages by
+// directory. Generated packages may import each other.
+func typeCheck(t *testing.T, files map[string]string) {
+	t.Helper()
+
+	fset := token.NewFileSet()
+	sources := map[string][]*ast.File{}
+	for name, content := range files {
+		file, err := parser.ParseFile(fset, name, content, 0)
+		require.NoError(t, err, name)
+
+		dir := path.Dir(name)
+		sources[dir] = append(sources[dir], file)
+	}
+
+	checked := map[string]*types.Package{}
+	var check func(importPath string) (*types.Package, error)
+
+	imp := importerFunc(func(importPath string) (*types.Package, error) {
+		if _, ok := sources[importPath]; ok {
+			return check(importPath)
+		}
+		return sourceImporter.Import(importPath)
+	})
+
+	check = func(importPath string) (*types.Package, error) {
+		if pkg, ok := checked[importPath]; ok {
+			return pkg, nil
+		}
+		conf := types.Config{Importer: imp}
+		pkg, err := conf.Check(importPath, fset, sources[importPath], nil)
+		checked[importPath] = pkg
+		return pkg, err
+	}
+
+	for importPath := range sources {
+		_, err := check(importPath)
+		assert.NoError(t, err, importPath)
+	}
+}
+
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) {
+	return f(path)
+}
+
+func testFixture(t *testing.T, name string) {
+	files := runPlugin(t, loadRequest(t, name))
+	assertGolden(t, name, files)
+	typeCheck(t, files)
+}
+
+func TestGenerateStreaming(t *testing.T) {
+	testFixture(t, "streaming")
+}
+
+func TestGenerateImports(t *testing.T) {
+	testFixture(t, "imports")
+}
+
+func TestGenerateOptions(t *testing.T) {
+	testFixture(t, "options")
+}
+
+func TestGenerateRejectsInvalidPubSub(t *testing.T) {
+	req := loadRequest(t, "options")
+
+	// Publish is declared pub-sub and must not change the message type
+	file := req.ProtoFile[len(req.ProtoFile)-1]
+	file.MessageType = append(file.MessageType, &descriptorpb.DescriptorProto{Name: proto.String("Ack")})
+	file.Service[0].Method[1].OutputType = proto.String(".options.Ack")
+
+	gen, err := protogen.Options{}.New(req)
+	require.NoError(t, err)
+
+	err = generate(gen, false)
+	assert.EqualError(t, err, "options.Events.Publish: pub-sub method must have the same input and output type")
+}
@@ -0,0 +1,105 @@
+// Package credentials provides per-call credentials for clients and
+// authenticators validating them on servers.
+//
+// Credentials are configured with transport.WithCredentials and
+// authenticators with transport.WithAuthenticator:
+//
+//	client, err := tcp.NewTransport(
+//		transport.WithAddress(addr),
+//		transport.WithCredentials(credentials.BearerToken(token)),
+//	)
+//
+//	server, err := tcp.NewTransport(
+//		transport.WithAddress(addr),
+//		transport.WithAuthenticator(credentials.StaticTokens(tokens)),
+//	)
+package credentials
+
+import (
+	"context"
+	"crypto/subtle"
+	"strings"
+
+	"github.com/srand/mqc"
+)
+
+// AuthorizationKey is the metadata key carrying bearer tokens.
+const AuthorizationKey = "authorization"
+
+const bearerPrefix = "Bearer "
+
+type bearerToken struct {
+	token func(ctx context.Context) (string, error)
+}
+
+// BearerToken returns credentials sending a static bearer token with every call.
+func BearerToken(token string) mqc.Credentials {
+	return BearerTokenFunc(func(ctx context.Context) (string, error) {
+		return token, nil
+	})
+}
+
+// BearerTokenFunc returns credentials sending the bearer token returned by
+// the function with every call, e.g. to refresh expiring tokens.
+func BearerTokenFunc(token func(ctx context.Context) (string, error)) mqc.Credentials {
+	return &bearerToken{token: token}
+}
+
+func (c *bearerToken) Metadata(ctx context.Context, method *mqc.Method) (mqc.Metadata, error) {
+	token, err := c.token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return mqc.Metadata{AuthorizationKey: bearerPrefix + token}, nil
+}
+
+// BearerFromMetadata returns the bearer token sent with a call,
+// or an empty string if there is none.
+func BearerFromMetadata(md mqc.Metadata) string {
+	value := md[AuthorizationKey]
+	if !strings.HasPrefix(value, bearerPrefix) {
+		return ""
+	}
+	return strings.TrimPrefix(value, bearerPrefix)
+}
+
+// ValidateFunc validates a bearer token and returns the identity of the caller.
+type ValidateFunc func(ctx context.Context, token string) (*mqc.Identity, error)
+
+type bearerAuthenticator struct {
+	validate ValidateFunc
+}
+
+// BearerAuthenticator returns an authenticator validating bearer tokens with the function.
+// Calls without a bearer token are rejected.
+func BearerAuthenticator(validate ValidateFunc) mqc.Authenticator {
+	return &bearerAuthenticator{validate: validate}
+}
+
+func (a *bearerAuthenticator) Authenticate(ctx context.Context, method *mqc.Method, md mqc.Metadata) (*mqc.Identity, error) {
+	token := BearerFromMetadata(md)
+	if token == "" {
+		return nil, mqc.ErrUnauthenticated
+	}
+	return a.validate(ctx, token)
+}
+
+// StaticTokens returns an authenticator accepting a fixed set of bearer tokens,
+// each mapped to the identity of its caller.
+func StaticTokens(tokens map[string]*mqc.Identity) mqc.Authenticator {
+	return BearerAuthenticator(func(ctx context.Context, token string) (*mqc.Identity, error) {
+		var identity *mqc.Identity
+
+		// Compare all tokens in constant time
+		for candidate, id := range tokens {
+			if subtle.ConstantTimeCompare([]byte(candidate), []byte(token)) == 1 {
+				identity = id
+			}
+		}
+
+		if identity == nil {
+			return nil, mqc.ErrUnauthenticated
+		}
+		return identity, nil
+	})
+}
@@ -0,0 +1,143 @@
+package credentials
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/srand/mqc"
+)
+
+// Metadata keys of HMAC signed calls.
+const (
+	KeyIdKey     = "mqc-key-id"
+	TimestampKey = "mqc-timestamp"
+	NonceKey     = "mqc-nonce"
+	SignatureKey = "mqc-signature"
+)
+
+type hmacCredentials struct {
+	keyId string
+	key   []byte
+}
+
+// HMAC returns credentials signing the method name, the current time and a
+// random nonce of every call with HMAC-SHA256, using a key shared with the
+// server. Unlike bearer tokens, the key itself is never sent.
+func HMAC(keyId string, key []byte) mqc.Credentials {
+	return &hmacCredentials{keyId: keyId, key: key}
+}
+
+func (c *hmacCredentials) Metadata(ctx context.Context, method *mqc.Method) (mqc.Metadata, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	var random [16]byte
+	if _, err := rand.Read(random[:]); err != nil {
+		return nil, err
+	}
+	nonce := hex.EncodeToString(random[:])
+
+	return mqc.Metadata{
+		KeyIdKey:     c.keyId,
+		TimestampKey: timestamp,
+		NonceKey:     nonce,
+		SignatureKey: hmacSignature(c.key, method, timestamp, nonce),
+	}, nil
+}
+
+func hmacSignature(key []byte, method *mqc.Method, timestamp, nonce string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(method.FullName()))
+	mac.Write([]byte{0})
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte{0})
+	mac.Write([]byte(nonce))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+type hmacAuthenticator struct {
+	keys    map[string][]byte
+	maxSkew time.Duration
+
+	// mu guards the nonces of the calls accepted within maxSkew, with the
+	// time after which their signatures expire
+	mu     sync.Mutex
+	nonces map[string]time.Time
+	pruned time.Time
+}
+
+// HMACAuthenticator returns an authenticator validating calls signed by
+// HMAC credentials. Keys are looked up by their id, which becomes the
+// subject of the caller identity. Signatures older or newer than maxSkew
+// are rejected, and so are the nonces of calls already accepted within
+// maxSkew, so that calls cannot be replayed to the same authenticator.
+// Servers sharing keys, e.g. the servers of a shared MQTT subscription,
+// do not share their nonces.
+func HMACAuthenticator(keys map[string][]byte, maxSkew time.Duration) mqc.Authenticator {
+	return &hmacAuthenticator{keys: keys, maxSkew: maxSkew, nonces: map[string]time.Time{}}
+}
+
+func (a *hmacAuthenticator) Authenticate(ctx context.Context, method *mqc.Method, md mqc.Metadata) (*mqc.Identity, error) {
+	keyId := md[KeyIdKey]
+
+	key, ok := a.keys[keyId]
+	if !ok {
+		return nil, mqc.ErrUnauthenticated
+	}
+
+	timestamp := md[TimestampKey]
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, mqc.ErrUnauthenticated
+	}
+
+	skew := time.Since(time.Unix(seconds, 0))
+	if skew > a.maxSkew || skew < -a.maxSkew {
+		return nil, mqc.ErrUnauthenticated
+	}
+
+	nonce := md[NonceKey]
+	if nonce == "" {
+		return nil, mqc.ErrUnauthenticated
+	}
+
+	signature := hmacSignature(key, method, timestamp, nonce)
+	if !hmac.Equal([]byte(signature), []byte(md[SignatureKey])) {
+		return nil, mqc.ErrUnauthenticated
+	}
+
+	if !a.accept(keyId+"/"+nonce, time.Unix(seconds, 0).Add(a.maxSkew)) {
+		return nil, mqc.ErrUnauthenticated
+	}
+
+	return &mqc.Identity{Subject: keyId}, nil
+}
+
+// accept records the nonce of a call until its signature expires, and
+// reports whether it was not seen before.
+func (a *hmacAuthenticator) accept(nonce string, expiry time.Time) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// Expired nonces are forgotten at most once per skew window
+	now := time.Now()
+	if now.Sub(a.pruned) >= a.maxSkew {
+		for n, e := range a.nonces {
+			if now.After(e) {
+				delete(a.nonces, n)
+			}
+		}
+		a.pruned = now
+	}
+
+	if _, ok := a.nonces[nonce]; ok {
+		return false
+	}
+	a.nonces[nonce] = expiry
+	return true
+}
@@ -0,0 +1,125 @@
+package credentials
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/srand/mqc"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrTokenExpired = errors.New("token expired")
+)
+
+var jwtHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// SignJWT returns a JWT signed with HMAC-SHA256, for use with BearerToken.
+// The identity subject is stored in the "sub" claim and its attributes in
+// claims of the same name. The token expires after ttl, unless ttl is zero.
+func SignJWT(key []byte, identity *mqc.Identity, ttl time.Duration) (string, error) {
+	claims := map[string]any{}
+	for name, value := range identity.Attributes {
+		claims[name] = value
+	}
+
+	now := time.Now()
+	claims["sub"] = identity.Subject
+	claims["iat"] = now.Unix()
+	if ttl > 0 {
+		claims["exp"] = now.Add(ttl).Unix()
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signed := jwtHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signed + "." + jwtSignature(key, signed), nil
+}
+
+func jwtSignature(key []byte, signed string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(signed))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// JWTAuthenticator returns an authenticator accepting bearer JWTs signed
+// with HMAC-SHA256 using the key. The "exp" and "nbf" claims are enforced.
+// The caller identity is the "sub" claim, and all other string claims
+// become identity attributes.
+func JWTAuthenticator(key []byte) mqc.Authenticator {
+	return BearerAuthenticator(func(ctx context.Context, token string) (*mqc.Identity, error) {
+		return ParseJWT(key, token)
+	})
+}
+
+// ParseJWT validates a JWT signed with HMAC-SHA256 and returns the identity it carries.
+func ParseJWT(key []byte, token string) (*mqc.Identity, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+
+	header, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	var h struct {
+		Alg string `json:"alg"`
+	}
+	if err := json.Unmarshal(header, &h); err != nil || h.Alg != "HS256" {
+		return nil, ErrInvalidToken
+	}
+
+	signature := jwtSignature(key, parts[0]+"."+parts[1])
+	if !hmac.Equal([]byte(signature), []byte(parts[2])) {
+		return nil, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	var claims map[string]any
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	now := time.Now().Unix()
+	if exp, ok := claims["exp"].(float64); ok && now >= int64(exp) {
+		return nil, ErrTokenExpired
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now < int64(nbf) {
+		return nil, fmt.Errorf("%w: not valid yet", ErrInvalidToken)
+	}
+
+	identity := &mqc.Identity{Attributes: map[string]string{}}
+	for name, value := range claims {
+		s, ok := value.(string)
+		if !ok {
+			continue
+		}
+		if name == "sub" {
+			identity.Subject = s
+		} else {
+			identity.Attributes[name] = s
+		}
+	}
+
+	if identity.Subject == "" {
+		return nil, ErrInvalidToken
+	}
+
+	return identity, nil
+}
@@ -2,10 +2,23 @@ package mqc
 
 var (
 	// ErrNoAddress indicates that no address was provided to connect to.
-	ErrNoAddress          = &Error{"no address provided"}
-	ErrProtocolViolation  = &Error{"protocol violation"}
-	ErrNilRequest         = &Error{"nil request"}
-	ErrPubSubNotSupported = &Error{"pub/sub not supported by this transport"}
+	ErrNoAddress           = &Error{"no address provided"}
+	ErrProtocolViolation   = &Error{"protocol violation"}
+	ErrNilRequest          = &Error{"nil request"}
+	ErrPubSubNotSupported  = &Error{"pub/sub not supported by this transport"}
+	ErrUnsupportedProtocol = &Error{"unsupported protocol version"}
+	// ErrUnauthenticated indicates that the credentials of a call are missing or invalid.
+	ErrUnauthenticated = &Error{"unauthenticated"}
+	// ErrPermissionDenied indicates that the caller is not allowed to call a method.
+	ErrPermissionDenied = &Error{"permission denied"}
+	// ErrPinMismatch indicates that the public key of a server does not match its pinned key.
+	ErrPinMismatch = &Error{"server public key does not match the pinned key"}
+	// ErrUnimplemented indicates that the server does not implement the called method.
+	ErrUnimplemented = &Error{"method not implemented"}
+	// ErrUnavailable indicates that the server cannot handle calls, e.g. because it is shutting down.
+	ErrUnavailable = &Error{"service unavailable"}
+	// ErrInternal indicates that the server failed to handle a call, e.g. because its handler panicked.
+	ErrInternal = &Error{"internal error"}
 )
 
 // Error represents an error in the mqc package.
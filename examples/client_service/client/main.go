@@ -4,6 +4,7 @@ package main
 
 import (
 	"client_service"
+	"context"
 	"flag"
 	"time"
 
@@ -24,7 +25,7 @@ type service struct {
 	client_service.UnimplementedEchoServer
 }
 
-func (s *service) Echo(req *client_service.EchoRequest) (*client_service.EchoReply, error) {
+func (s *service) Echo(ctx context.Context, req *client_service.EchoRequest) (*client_service.EchoReply, error) {
 	println("Server called client service with message:", req.Message)
 	close(done)
 	return &client_service.EchoReply{Message: req.Message}, nil
@@ -36,7 +37,9 @@ func main() {
 		panic(err)
 	}
 
-	client_service.RegisterEchoServer(transport, &service{})
+	if err := client_service.RegisterEchoServer(transport, &service{}); err != nil {
+		panic(err)
+	}
 
 	if err := transport.Dial(); err != nil {
 		panic(err)
@@ -5,9 +5,8 @@
 package client_service
 
 import (
-	"context"
-	"fmt"
-	"github.com/srand/mqc"
+	context "context"
+	mqc "github.com/srand/mqc"
 )
 
 type EchoClient interface {
@@ -15,31 +14,58 @@ type EchoClient interface {
 }
 
 type EchoServer interface {
-	Echo(req *EchoRequest) (*EchoReply, error)
+	Echo(ctx context.Context, req *EchoRequest) (*EchoReply, error)
 }
 
 type echoClient struct {
-	transport mqc.Transport
+	conn mqc.ClientConn
 }
 
-func NewEchoClient(transport mqc.Transport) *echoClient {
-	return &echoClient{transport: transport}
+func NewEchoClient(conn mqc.ClientConn) *echoClient {
+	return &echoClient{conn: conn}
 }
 
+var _ EchoClient = (*echoClient)(nil)
+
 func (c *echoClient) Echo(ctx context.Context, req *EchoRequest) (*EchoReply, error) {
-	return mqc.Rpc[EchoRequest, EchoReply](ctx, c.transport, mqc.Method("Echo/Echo"), req)
+	return mqc.Rpc[EchoRequest, EchoReply](ctx, c.conn, mqc.NewMethod("Echo/Echo", mqc.MethodTypeUnary), req)
 }
 
 type UnimplementedEchoServer struct{}
 
-func (s *UnimplementedEchoServer) Echo(req *EchoRequest) (*EchoReply, error) {
-	return nil, fmt.Errorf("method Echo not implemented")
+func (s *UnimplementedEchoServer) Echo(ctx context.Context, req *EchoRequest) (*EchoReply, error) {
+	return nil, mqc.ErrUnimplemented
 }
 
-func RegisterEchoServer(transport mqc.Transport, server EchoServer) {
-	transport.RegisterHandler("Echo/Echo", func(conn mqc.Conn) error {
-		return mqc.RpcServer(conn, transport.Serializer(), func(req *EchoRequest) (*EchoReply, error) {
-			return server.Echo(req)
+func RegisterEchoServer(transport mqc.Transport, server EchoServer) error {
+	if err := transport.RegisterHandler(mqc.NewMethod("Echo/Echo", mqc.MethodTypeUnary), func(ctx context.Context, conn mqc.Conn) error {
+		return mqc.RpcServer(ctx, conn, transport.Serializer(), func(ctx context.Context, req *EchoRequest) (*EchoReply, error) {
+			return server.Echo(ctx, req)
 		})
+	}); err != nil {
+		return err
+	}
+	return nil
+}
+
+func RegisterEchoService(s *mqc.Server, server EchoServer) error {
+	return s.RegisterService("Echo", func(transport mqc.Transport) error {
+		return RegisterEchoServer(transport, server)
+	})
+}
+
+func init() {
+	mqc.RegisterService(&mqc.ServiceDesc{
+		Name:     "Echo",
+		FullName: "client_service.Echo",
+		File:     "service.proto",
+		Methods: []mqc.MethodDesc{
+			{
+				Name:   "Echo",
+				Type:   mqc.MethodTypeUnary,
+				Input:  "client_service.EchoRequest",
+				Output: "client_service.EchoReply",
+			},
+		},
 	})
 }
@@ -8,6 +8,7 @@ import (
 	"flag"
 	"helloworld"
 
+	"github.com/srand/mqc"
 	"github.com/srand/mqc/transport"
 	"github.com/srand/mqc/transport/tcp"
 )
@@ -28,7 +29,8 @@ func init() {
 func main() {
 	transport, err := tcp.NewTransport(
 		transport.WithAddress(*addr),
-		transport.WithSelfSignedCert(),
+		// Trust the server key on first use and reject other keys afterwards
+		transport.WithPublicKeyPin(mqc.TrustOnFirstUse("known_keys")),
 	)
 	if err != nil {
 		panic(err)
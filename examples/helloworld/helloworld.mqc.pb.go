@@ -5,9 +5,8 @@
 package helloworld
 
 import (
-	"context"
-	"fmt"
-	"github.com/srand/mqc"
+	context "context"
+	mqc "github.com/srand/mqc"
 )
 
 type GreeterClient interface {
@@ -15,31 +14,58 @@ type GreeterClient interface {
 }
 
 type GreeterServer interface {
-	SayHello(req *HelloRequest) (*HelloReply, error)
+	SayHello(ctx context.Context, req *HelloRequest) (*HelloReply, error)
 }
 
 type greeterClient struct {
-	transport mqc.Transport
+	conn mqc.ClientConn
 }
 
-func NewGreeterClient(transport mqc.Transport) *greeterClient {
-	return &greeterClient{transport: transport}
+func NewGreeterClient(conn mqc.ClientConn) *greeterClient {
+	return &greeterClient{conn: conn}
 }
 
+var _ GreeterClient = (*greeterClient)(nil)
+
 func (c *greeterClient) SayHello(ctx context.Context, req *HelloRequest) (*HelloReply, error) {
-	return mqc.Rpc[HelloRequest, HelloReply](ctx, c.transport, mqc.Method("Greeter/SayHello"), req)
+	return mqc.Rpc[HelloRequest, HelloReply](ctx, c.conn, mqc.NewMethod("Greeter/SayHello", mqc.MethodTypeUnary), req)
 }
 
 type UnimplementedGreeterServer struct{}
 
-func (s *UnimplementedGreeterServer) SayHello(req *HelloRequest) (*HelloReply, error) {
-	return nil, fmt.Errorf("method SayHello not implemented")
+func (s *UnimplementedGreeterServer) SayHello(ctx context.Context, req *HelloRequest) (*HelloReply, error) {
+	return nil, mqc.ErrUnimplemented
 }
 
-func RegisterGreeterServer(transport mqc.Transport, server GreeterServer) {
-	transport.RegisterHandler("Greeter/SayHello", func(conn mqc.Conn) error {
-		return mqc.RpcServer(conn, transport.Serializer(), func(req *HelloRequest) (*HelloReply, error) {
-			return server.SayHello(req)
+func RegisterGreeterServer(transport mqc.Transport, server GreeterServer) error {
+	if err := transport.RegisterHandler(mqc.NewMethod("Greeter/SayHello", mqc.MethodTypeUnary), func(ctx context.Context, conn mqc.Conn) error {
+		return mqc.RpcServer(ctx, conn, transport.Serializer(), func(ctx context.Context, req *HelloRequest) (*HelloReply, error) {
+			return server.SayHello(ctx, req)
 		})
+	}); err != nil {
+		return err
+	}
+	return nil
+}
+
+func RegisterGreeterService(s *mqc.Server, server GreeterServer) error {
+	return s.RegisterService("Greeter", func(transport mqc.Transport) error {
+		return RegisterGreeterServer(transport, server)
+	})
+}
+
+func init() {
+	mqc.RegisterService(&mqc.ServiceDesc{
+		Name:     "Greeter",
+		FullName: "helloworld.Greeter",
+		File:     "helloworld.proto",
+		Methods: []mqc.MethodDesc{
+			{
+				Name:   "SayHello",
+				Type:   mqc.MethodTypeUnary,
+				Input:  "helloworld.HelloRequest",
+				Output: "helloworld.HelloReply",
+			},
+		},
 	})
 }
@@ -4,6 +4,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"helloworld"
 
@@ -19,7 +20,7 @@ type server struct {
 	helloworld.UnimplementedGreeterServer
 }
 
-func (s *server) SayHello(req *helloworld.HelloRequest) (*helloworld.HelloReply, error) {
+func (s *server) SayHello(ctx context.Context, req *helloworld.HelloRequest) (*helloworld.HelloReply, error) {
 	return &helloworld.HelloReply{Message: "Hello " + req.Name}, nil
 }
 
@@ -30,13 +31,16 @@ func init() {
 func main() {
 	conn, err := tcp.NewTransport(
 		transport.WithAddress(*addr),
-		transport.WithSelfSignedCert(),
+		// The certificate is generated on first start and reused afterwards
+		transport.WithPersistentSelfSignedCert("server.pem", "server.key", "localhost"),
 	)
 	if err != nil {
 		panic(err)
 	}
 	defer conn.Close()
 
-	helloworld.RegisterGreeterServer(conn, &server{})
+	if err := helloworld.RegisterGreeterServer(conn, &server{}); err != nil {
+		panic(err)
+	}
 	panic(conn.Serve())
 }
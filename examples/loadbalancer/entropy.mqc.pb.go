@@ -5,9 +5,8 @@
 package loadbalancer
 
 import (
-	"context"
-	"fmt"
-	"github.com/srand/mqc"
+	context "context"
+	mqc "github.com/srand/mqc"
 )
 
 type EntropyClient interface {
@@ -15,33 +14,60 @@ type EntropyClient interface {
 }
 
 type EntropyServer interface {
-	GenerateIntegers(req *NumberRequest, stream mqc.ServerStreamServer[NumberReply]) error
+	GenerateIntegers(ctx context.Context, req *NumberRequest, stream mqc.ServerStreamServer[NumberReply]) error
 }
 
 type entropyClient struct {
-	transport mqc.Transport
+	conn mqc.ClientConn
 }
 
-func NewEntropyClient(transport mqc.Transport) *entropyClient {
-	return &entropyClient{transport: transport}
+func NewEntropyClient(conn mqc.ClientConn) *entropyClient {
+	return &entropyClient{conn: conn}
 }
 
+var _ EntropyClient = (*entropyClient)(nil)
+
 func (c *entropyClient) GenerateIntegers(ctx context.Context, req *NumberRequest) (mqc.ServerStreamClient[NumberReply], error) {
-	return mqc.NewServerStreamClient[NumberRequest, NumberReply](ctx, c.transport, mqc.Method("Entropy/GenerateIntegers"), req)
+	return mqc.NewServerStreamClient[NumberRequest, NumberReply](ctx, c.conn, mqc.NewMethod("Entropy/GenerateIntegers", mqc.MethodTypeServerStream), req)
 }
 
 type UnimplementedEntropyServer struct{}
 
-func (s *UnimplementedEntropyServer) GenerateIntegers(req *NumberRequest, stream mqc.ServerStreamServer[NumberReply]) error {
-	return fmt.Errorf("method GenerateIntegers not implemented")
+func (s *UnimplementedEntropyServer) GenerateIntegers(ctx context.Context, req *NumberRequest, stream mqc.ServerStreamServer[NumberReply]) error {
+	return mqc.ErrUnimplemented
 }
 
-func RegisterEntropyServer(transport mqc.Transport, server EntropyServer) {
-	transport.RegisterHandler("Entropy/GenerateIntegers", func(conn mqc.Conn) error {
-		stream, req, err := mqc.NewServerStreamServer[NumberRequest, NumberReply](transport, conn)
+func RegisterEntropyServer(transport mqc.Transport, server EntropyServer) error {
+	if err := transport.RegisterHandler(mqc.NewMethod("Entropy/GenerateIntegers", mqc.MethodTypeServerStream), func(ctx context.Context, conn mqc.Conn) error {
+		stream, req, err := mqc.NewServerStreamServer[NumberRequest, NumberReply](ctx, transport, conn)
 		if err != nil {
 			return err
 		}
-		return server.GenerateIntegers(req, stream)
+		return server.GenerateIntegers(ctx, req, stream)
+	}); err != nil {
+		return err
+	}
+	return nil
+}
+
+func RegisterEntropyService(s *mqc.Server, server EntropyServer) error {
+	return s.RegisterService("Entropy", func(transport mqc.Transport) error {
+		return RegisterEntropyServer(transport, server)
+	})
+}
+
+func init() {
+	mqc.RegisterService(&mqc.ServiceDesc{
+		Name:     "Entropy",
+		FullName: "loadbalancer.Entropy",
+		File:     "entropy.proto",
+		Methods: []mqc.MethodDesc{
+			{
+				Name:   "GenerateIntegers",
+				Type:   mqc.MethodTypeServerStream,
+				Input:  "loadbalancer.NumberRequest",
+				Output: "loadbalancer.NumberReply",
+			},
+		},
 	})
 }
@@ -30,9 +30,7 @@ type server struct {
 // GenerateIntegers generates a stream of random integers.
 // If multiple servers are running, the MQTT broker will load balance
 // the requests between them.
-func (s *server) GenerateIntegers(req *loadbalancer.NumberRequest, stream mqc.ServerStreamServer[loadbalancer.NumberReply]) error {
-	ctx := context.Background()
-
+func (s *server) GenerateIntegers(ctx context.Context, req *loadbalancer.NumberRequest, stream mqc.ServerStreamServer[loadbalancer.NumberReply]) error {
 	println("Generating ", req.Count, " random numbers")
 
 	for i := 0; i < int(req.Count); i++ {
@@ -64,7 +62,9 @@ func main() {
 	defer conn.Close()
 
 	// Register the entropy service on the server
-	loadbalancer.RegisterEntropyServer(conn, &server{})
+	if err := loadbalancer.RegisterEntropyServer(conn, &server{}); err != nil {
+		panic(err)
+	}
 
 	// Start the server
 	panic(conn.Serve().Error())
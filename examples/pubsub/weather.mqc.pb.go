@@ -5,10 +5,8 @@
 package pubsub
 
 import (
-	"context"
-	"fmt"
-
-	"github.com/srand/mqc"
+	context "context"
+	mqc "github.com/srand/mqc"
 )
 
 type WeatherClient interface {
@@ -16,65 +14,96 @@ type WeatherClient interface {
 }
 
 type WeatherServer interface {
-	Update(stream mqc.BidiStreamServer[WeatherUpdate, WeatherUpdate]) error
+	Update(ctx context.Context, stream mqc.BidiStreamServer[WeatherUpdate, WeatherUpdate]) error
 }
 
 type WeatherConsumer interface {
-	Update(ctx context.Context) (mqc.BidiStreamClient[WeatherUpdate, WeatherUpdate], error)
+	Update(ctx context.Context) (mqc.Subscriber[WeatherUpdate], error)
 }
 
 type WeatherPublisher interface {
-	Update(ctx context.Context) (mqc.BidiStreamServer[WeatherUpdate, WeatherUpdate], error)
+	Update(ctx context.Context) (mqc.Publisher[WeatherUpdate], error)
 }
 
 type weatherClient struct {
-	transport mqc.Transport
+	conn mqc.ClientConn
 }
 
-func NewWeatherClient(transport mqc.Transport) *weatherClient {
-	return &weatherClient{transport: transport}
+func NewWeatherClient(conn mqc.ClientConn) *weatherClient {
+	return &weatherClient{conn: conn}
 }
 
+var _ WeatherClient = (*weatherClient)(nil)
+
 func (c *weatherClient) Update(ctx context.Context) (mqc.BidiStreamClient[WeatherUpdate, WeatherUpdate], error) {
-	return mqc.NewBidiStreamClient[WeatherUpdate, WeatherUpdate](ctx, c.transport, mqc.NewMethod("Weather/Update", mqc.MethodTypeBidiStream))
+	return mqc.NewBidiStreamClient[WeatherUpdate, WeatherUpdate](ctx, c.conn, mqc.NewMethod("Weather/Update", mqc.MethodTypeBidiStream))
 }
 
 type weatherConsumer struct {
-	transport mqc.Transport
+	conn mqc.ClientConn
 }
 
-func NewWeatherConsumer(transport mqc.Transport) *weatherConsumer {
-	return &weatherConsumer{transport: transport}
+func NewWeatherConsumer(conn mqc.ClientConn) *weatherConsumer {
+	return &weatherConsumer{conn: conn}
 }
 
-func (c *weatherConsumer) Update(ctx context.Context) (mqc.ServerStreamClient[WeatherUpdate], error) {
-	return mqc.NewPubSubClient[WeatherUpdate](ctx, c.transport, mqc.NewMethod("Weather/Update", mqc.MethodTypeConsumer))
+var _ WeatherConsumer = (*weatherConsumer)(nil)
+
+func (c *weatherConsumer) Update(ctx context.Context) (mqc.Subscriber[WeatherUpdate], error) {
+	return mqc.NewSubscriber[WeatherUpdate](ctx, c.conn, mqc.NewMethod("Weather/Update", mqc.MethodTypeConsumer))
 }
 
 type weatherPublisher struct {
-	transport mqc.Transport
+	conn mqc.ClientConn
 }
 
-func NewWeatherPublisher(transport mqc.Transport) *weatherPublisher {
-	return &weatherPublisher{transport: transport}
+func NewWeatherPublisher(conn mqc.ClientConn) *weatherPublisher {
+	return &weatherPublisher{conn: conn}
 }
 
-func (c *weatherPublisher) Update(ctx context.Context) (mqc.ServerStreamServer[WeatherUpdate], error) {
-	return mqc.NewPubSubClient[WeatherUpdate](ctx, c.transport, mqc.NewMethod("Weather/Update", mqc.MethodTypePublisher))
+var _ WeatherPublisher = (*weatherPublisher)(nil)
+
+func (c *weatherPublisher) Update(ctx context.Context) (mqc.Publisher[WeatherUpdate], error) {
+	return mqc.NewPublisher[WeatherUpdate](ctx, c.conn, mqc.NewMethod("Weather/Update", mqc.MethodTypePublisher))
 }
 
 type UnimplementedWeatherServer struct{}
 
-func (s *UnimplementedWeatherServer) Update(stream mqc.BidiStreamServer[WeatherUpdate, WeatherUpdate]) error {
-	return fmt.Errorf("method Update not implemented")
+func (s *UnimplementedWeatherServer) Update(ctx context.Context, stream mqc.BidiStreamServer[WeatherUpdate, WeatherUpdate]) error {
+	return mqc.ErrUnimplemented
 }
 
-func RegisterWeatherServer(transport mqc.Transport, server WeatherServer) {
-	transport.RegisterHandler(mqc.NewMethod("Weather/Update", mqc.MethodTypeBidiStream), func(conn mqc.Conn) error {
+func RegisterWeatherServer(transport mqc.Transport, server WeatherServer) error {
+	if err := transport.RegisterHandler(mqc.NewMethod("Weather/Update", mqc.MethodTypeBidiStream), func(ctx context.Context, conn mqc.Conn) error {
 		stream, err := mqc.NewBidiStreamServer[WeatherUpdate, WeatherUpdate](transport, conn)
 		if err != nil {
 			return err
 		}
-		return server.Update(stream)
+		return server.Update(ctx, stream)
+	}); err != nil {
+		return err
+	}
+	return nil
+}
+
+func RegisterWeatherService(s *mqc.Server, server WeatherServer) error {
+	return s.RegisterService("Weather", func(transport mqc.Transport) error {
+		return RegisterWeatherServer(transport, server)
+	})
+}
+
+func init() {
+	mqc.RegisterService(&mqc.ServiceDesc{
+		Name:     "Weather",
+		FullName: "pubsub.Weather",
+		File:     "weather.proto",
+		Methods: []mqc.MethodDesc{
+			{
+				Name:   "Update",
+				Type:   mqc.MethodTypeBidiStream,
+				Input:  "pubsub.WeatherUpdate",
+				Output: "pubsub.WeatherUpdate",
+			},
+		},
 	})
 }
@@ -5,9 +5,8 @@
 package websocket
 
 import (
-	"context"
-	"fmt"
-	"github.com/srand/mqc"
+	context "context"
+	mqc "github.com/srand/mqc"
 )
 
 type IncrementerClient interface {
@@ -15,33 +14,96 @@ type IncrementerClient interface {
 }
 
 type IncrementerServer interface {
-	Increment(stream mqc.BidiStreamServer[Integer, Integer]) error
+	Increment(ctx context.Context, stream mqc.BidiStreamServer[Integer, Integer]) error
+}
+
+type IncrementerConsumer interface {
+	Increment(ctx context.Context) (mqc.Subscriber[Integer], error)
+}
+
+type IncrementerPublisher interface {
+	Increment(ctx context.Context) (mqc.Publisher[Integer], error)
 }
 
 type incrementerClient struct {
-	transport mqc.Transport
+	conn mqc.ClientConn
 }
 
-func NewIncrementerClient(transport mqc.Transport) *incrementerClient {
-	return &incrementerClient{transport: transport}
+func NewIncrementerClient(conn mqc.ClientConn) *incrementerClient {
+	return &incrementerClient{conn: conn}
 }
 
+var _ IncrementerClient = (*incrementerClient)(nil)
+
 func (c *incrementerClient) Increment(ctx context.Context) (mqc.BidiStreamClient[Integer, Integer], error) {
-	return mqc.NewBidiStreamClient[Integer, Integer](ctx, c.transport, mqc.Method("Incrementer/Increment"))
+	return mqc.NewBidiStreamClient[Integer, Integer](ctx, c.conn, mqc.NewMethod("Incrementer/Increment", mqc.MethodTypeBidiStream))
+}
+
+type incrementerConsumer struct {
+	conn mqc.ClientConn
+}
+
+func NewIncrementerConsumer(conn mqc.ClientConn) *incrementerConsumer {
+	return &incrementerConsumer{conn: conn}
+}
+
+var _ IncrementerConsumer = (*incrementerConsumer)(nil)
+
+func (c *incrementerConsumer) Increment(ctx context.Context) (mqc.Subscriber[Integer], error) {
+	return mqc.NewSubscriber[Integer](ctx, c.conn, mqc.NewMethod("Incrementer/Increment", mqc.MethodTypeConsumer))
+}
+
+type incrementerPublisher struct {
+	conn mqc.ClientConn
+}
+
+func NewIncrementerPublisher(conn mqc.ClientConn) *incrementerPublisher {
+	return &incrementerPublisher{conn: conn}
+}
+
+var _ IncrementerPublisher = (*incrementerPublisher)(nil)
+
+func (c *incrementerPublisher) Increment(ctx context.Context) (mqc.Publisher[Integer], error) {
+	return mqc.NewPublisher[Integer](ctx, c.conn, mqc.NewMethod("Incrementer/Increment", mqc.MethodTypePublisher))
 }
 
 type UnimplementedIncrementerServer struct{}
 
-func (s *UnimplementedIncrementerServer) Increment(stream mqc.BidiStreamServer[Integer, Integer]) error {
-	return fmt.Errorf("method Increment not implemented")
+func (s *UnimplementedIncrementerServer) Increment(ctx context.Context, stream mqc.BidiStreamServer[Integer, Integer]) error {
+	return mqc.ErrUnimplemented
 }
 
-func RegisterIncrementerServer(transport mqc.Transport, server IncrementerServer) {
-	transport.RegisterHandler("Incrementer/Increment", func(conn mqc.Conn) error {
+func RegisterIncrementerServer(transport mqc.Transport, server IncrementerServer) error {
+	if err := transport.RegisterHandler(mqc.NewMethod("Incrementer/Increment", mqc.MethodTypeBidiStream), func(ctx context.Context, conn mqc.Conn) error {
 		stream, err := mqc.NewBidiStreamServer[Integer, Integer](transport, conn)
 		if err != nil {
 			return err
 		}
-		return server.Increment(stream)
+		return server.Increment(ctx, stream)
+	}); err != nil {
+		return err
+	}
+	return nil
+}
+
+func RegisterIncrementerService(s *mqc.Server, server IncrementerServer) error {
+	return s.RegisterService("Incrementer", func(transport mqc.Transport) error {
+		return RegisterIncrementerServer(transport, server)
+	})
+}
+
+func init() {
+	mqc.RegisterService(&mqc.ServiceDesc{
+		Name:     "Incrementer",
+		FullName: "websocket.Incrementer",
+		File:     "bidi.proto",
+		Methods: []mqc.MethodDesc{
+			{
+				Name:   "Increment",
+				Type:   mqc.MethodTypeBidiStream,
+				Input:  "websocket.Integer",
+				Output: "websocket.Integer",
+			},
+		},
 	})
 }
@@ -17,8 +17,8 @@ type incrementerServer struct {
 	websocket.UnimplementedIncrementerServer
 }
 
-func (s *incrementerServer) Increment(stream mqc.BidiStreamServer[websocket.Integer, websocket.Integer]) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (s *incrementerServer) Increment(ctx context.Context, stream mqc.BidiStreamServer[websocket.Integer, websocket.Integer]) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	for {
@@ -40,7 +40,9 @@ func main() {
 		log.Fatalf("failed to create websocket transport: %v", err)
 	}
 
-	websocket.RegisterIncrementerServer(transport, &incrementerServer{})
+	if err := websocket.RegisterIncrementerServer(transport, &incrementerServer{}); err != nil {
+		log.Fatalf("failed to register incrementer server: %v", err)
+	}
 
 	http.Handle("/ws", mqc_http.NewHandler(transport))
 	http.ListenAndServe(":8080", nil)
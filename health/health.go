@@ -0,0 +1,223 @@
+//go:generate protoc -I. --go_out=. --go_opt=module=github.com/srand/mqc/health --go-mqc_out=. --go-mqc_opt=module=github.com/srand/mqc/health mqc/health.proto
+
+// Package health implements a service reporting whether a server and its
+// services are able to handle calls, for load balancers and orchestrators.
+//
+// Register the service on a server transport after the other services and
+// update the status as the server changes state:
+//
+//	RegisterGreeterServer(transport, &greeter{})
+//	healthServer, err := health.Register(transport)
+//	...
+//	healthServer.Shutdown()
+//
+// On an mqc.Server, RegisterServer reports the services of the server, which
+// are reported as not serving when the server shuts down:
+//
+//	RegisterGreeterService(server, &greeter{})
+//	healthServer, err := health.RegisterServer(server)
+//
+// The empty service name reports the overall status of the server.
+package health
+
+import (
+	"context"
+	"sync"
+
+	"github.com/srand/mqc"
+)
+
+var (
+	// ErrServiceUnknown indicates that the status of a service is not known.
+	ErrServiceUnknown = &mqc.Error{Message: "unknown service"}
+)
+
+// Server keeps the serving status of services and implements HealthServer.
+type Server struct {
+	mu       sync.Mutex
+	shutdown bool
+	statuses map[string]HealthCheckResponse_ServingStatus
+	watchers map[string]map[*watcher]struct{}
+}
+
+// watcher receives the latest status of a watched service.
+type watcher struct {
+	updates chan HealthCheckResponse_ServingStatus
+	done    chan struct{}
+}
+
+// Register registers the health service on a transport. The server and every
+// service with a method registered on the transport are reported as serving.
+func Register(transport mqc.Transport) (*Server, error) {
+	server := NewServer()
+	for _, method := range transport.Methods() {
+		server.SetServingStatus(method.Service, HealthCheckResponse_SERVING)
+	}
+	if err := RegisterHealthServer(transport, server); err != nil {
+		return nil, err
+	}
+	return server, nil
+}
+
+// RegisterServer registers the health service on all transports of an mqc
+// server. The server and its services are reported as serving until the
+// server shuts down.
+func RegisterServer(s *mqc.Server) (*Server, error) {
+	server := NewServer()
+	for _, desc := range s.Services() {
+		server.SetServingStatus(desc.Name, HealthCheckResponse_SERVING)
+	}
+	if err := RegisterHealthService(s, server); err != nil {
+		return nil, err
+	}
+	s.OnShutdown(server.Shutdown)
+	return server, nil
+}
+
+// NewServer creates a health server reporting the server as serving.
+func NewServer() *Server {
+	return &Server{
+		statuses: map[string]HealthCheckResponse_ServingStatus{"": HealthCheckResponse_SERVING},
+		watchers: map[string]map[*watcher]struct{}{},
+	}
+}
+
+// SetServingStatus sets the serving status of a service and notifies its
+// watchers. The status is not changed after Shutdown until Resume is called.
+func (s *Server) SetServingStatus(service string, status HealthCheckResponse_ServingStatus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.shutdown {
+		return
+	}
+	s.setServingStatus(service, status)
+}
+
+// ClearServingStatus removes the status of a service, which is then
+// reported as unknown.
+func (s *Server) ClearServingStatus(service string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.shutdown {
+		return
+	}
+	delete(s.statuses, service)
+	s.notify(service, HealthCheckResponse_SERVICE_UNKNOWN)
+}
+
+// Shutdown reports all services as not serving and ends all watch streams.
+// Later status changes are ignored until Resume is called.
+func (s *Server) Shutdown() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.shutdown = true
+	for service := range s.statuses {
+		s.setServingStatus(service, HealthCheckResponse_NOT_SERVING)
+	}
+	for _, watchers := range s.watchers {
+		for w := range watchers {
+			close(w.done)
+		}
+	}
+	s.watchers = map[string]map[*watcher]struct{}{}
+}
+
+// Resume reports all services as serving again after Shutdown.
+func (s *Server) Resume() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.shutdown = false
+	for service := range s.statuses {
+		s.setServingStatus(service, HealthCheckResponse_SERVING)
+	}
+}
+
+func (s *Server) setServingStatus(service string, status HealthCheckResponse_ServingStatus) {
+	s.statuses[service] = status
+	s.notify(service, status)
+}
+
+// notify delivers a status to the watchers of a service, replacing any
+// status they have not yet sent.
+func (s *Server) notify(service string, status HealthCheckResponse_ServingStatus) {
+	for w := range s.watchers[service] {
+		select {
+		case <-w.updates:
+		default:
+		}
+		w.updates <- status
+	}
+}
+
+func (s *Server) status(service string) HealthCheckResponse_ServingStatus {
+	if status, ok := s.statuses[service]; ok {
+		return status
+	}
+	return HealthCheckResponse_SERVICE_UNKNOWN
+}
+
+func (s *Server) Check(ctx context.Context, req *HealthCheckRequest) (*HealthCheckResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := s.status(req.Service)
+	if status == HealthCheckResponse_SERVICE_UNKNOWN {
+		return nil, ErrServiceUnknown
+	}
+	return &HealthCheckResponse{Status: status}, nil
+}
+
+func (s *Server) Watch(ctx context.Context, req *HealthCheckRequest, stream mqc.ServerStreamServer[HealthCheckResponse]) error {
+	w := &watcher{
+		updates: make(chan HealthCheckResponse_ServingStatus, 1),
+		done:    make(chan struct{}),
+	}
+
+	s.mu.Lock()
+	if s.shutdown {
+		status := s.status(req.Service)
+		s.mu.Unlock()
+		return stream.Send(ctx, &HealthCheckResponse{Status: status})
+	}
+	if s.watchers[req.Service] == nil {
+		s.watchers[req.Service] = map[*watcher]struct{}{}
+	}
+	s.watchers[req.Service][w] = struct{}{}
+	w.updates <- s.status(req.Service)
+	s.mu.Unlock()
+
+	defer s.removeWatcher(req.Service, w)
+
+	for {
+		select {
+		case status := <-w.updates:
+			if err := stream.Send(ctx, &HealthCheckResponse{Status: status}); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-w.done:
+			// Deliver the final status before ending the stream
+			select {
+			case status := <-w.updates:
+				return stream.Send(ctx, &HealthCheckResponse{Status: status})
+			default:
+				return nil
+			}
+		}
+	}
+}
+
+func (s *Server) removeWatcher(service string, w *watcher) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.watchers[service], w)
+	if len(s.watchers[service]) == 0 {
+		delete(s.watchers, service)
+	}
+}
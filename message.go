@@ -5,6 +5,7 @@ import (
 	"errors"
 
 	"github.com/srand/mqc/serialization"
+	"google.golang.org/protobuf/proto"
 )
 
 func NewAckMessage() *Message {
@@ -12,11 +13,22 @@ func NewAckMessage() *Message {
 		Type: Message_ACK,
 	}
 }
-func NewCallMessage(method *Method) *Message {
+
+// NewCallMessage creates an INVOKE message for a method,
+// carrying the metadata of the call.
+func NewCallMessage(method *Method, md Metadata) (*Message, error) {
+	invoke := method.Invoke()
+	invoke.Metadata = md
+
+	data, err := proto.Marshal(invoke)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Message{
 		Type: Message_INVOKE,
-		Data: []byte(method.String()),
-	}
+		Data: data,
+	}, nil
 }
 
 func NewCloseMessage() *Message {
@@ -66,8 +78,28 @@ func (m *Message) Error() error {
 	return nil
 }
 
-func (m *Message) Method() *Method {
-	return NewMethodFromString(string(m.Data))
+// Method decodes the method carried by an INVOKE message.
+func (m *Message) Method() (*Method, error) {
+	method, _, err := m.Invoke()
+	return method, err
+}
+
+// Invoke decodes the method and the metadata carried by an INVOKE message.
+func (m *Message) Invoke() (*Method, Metadata, error) {
+	if !m.IsCall() {
+		return nil, nil, ErrProtocolViolation
+	}
+
+	var invoke Invoke
+	if err := proto.Unmarshal(m.Data, &invoke); err != nil {
+		return nil, nil, err
+	}
+
+	method, err := NewMethodFromInvoke(&invoke)
+	if err != nil {
+		return nil, nil, err
+	}
+	return method, invoke.Metadata, nil
 }
 
 func (m *Message) DataBytes() []byte {
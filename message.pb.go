@@ -1,6 +1,6 @@
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // versions:
-// 	protoc-gen-go v1.36.10
+// 	protoc-gen-go v1.36.6
 // 	protoc        v3.21.12
 // source: message.proto
 
@@ -78,9 +78,11 @@ func (Message_Type) EnumDescriptor() ([]byte, []int) {
 }
 
 type Message struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Type          Message_Type           `protobuf:"varint,1,opt,name=type,proto3,enum=mqc.Message_Type" json:"type,omitempty"`
-	Data          []byte                 `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Type  Message_Type           `protobuf:"varint,1,opt,name=type,proto3,enum=mqc.Message_Type" json:"type,omitempty"`
+	Data  []byte                 `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	// Metadata sent with a published message, e.g. the trace context.
+	Metadata      map[string]string `protobuf:"bytes,3,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -129,21 +131,121 @@ func (x *Message) GetData() []byte {
 	return nil
 }
 
+func (x *Message) GetMetadata() map[string]string {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+// Invoke is the payload of an INVOKE message.
+// It identifies the method being called.
+type Invoke struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Service         string                 `protobuf:"bytes,1,opt,name=service,proto3" json:"service,omitempty"`
+	Method          string                 `protobuf:"bytes,2,opt,name=method,proto3" json:"method,omitempty"`
+	MethodType      int32                  `protobuf:"varint,3,opt,name=method_type,json=methodType,proto3" json:"method_type,omitempty"`
+	ProtocolVersion uint32                 `protobuf:"varint,4,opt,name=protocol_version,json=protocolVersion,proto3" json:"protocol_version,omitempty"`
+	// Metadata sent with the call, e.g. credentials.
+	Metadata      map[string]string `protobuf:"bytes,5,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Invoke) Reset() {
+	*x = Invoke{}
+	mi := &file_message_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Invoke) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Invoke) ProtoMessage() {}
+
+func (x *Invoke) ProtoReflect() protoreflect.Message {
+	mi := &file_message_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Invoke.ProtoReflect.Descriptor instead.
+func (*Invoke) Descriptor() ([]byte, []int) {
+	return file_message_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Invoke) GetService() string {
+	if x != nil {
+		return x.Service
+	}
+	return ""
+}
+
+func (x *Invoke) GetMethod() string {
+	if x != nil {
+		return x.Method
+	}
+	return ""
+}
+
+func (x *Invoke) GetMethodType() int32 {
+	if x != nil {
+		return x.MethodType
+	}
+	return 0
+}
+
+func (x *Invoke) GetProtocolVersion() uint32 {
+	if x != nil {
+		return x.ProtocolVersion
+	}
+	return 0
+}
+
+func (x *Invoke) GetMetadata() map[string]string {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
 var File_message_proto protoreflect.FileDescriptor
 
 const file_message_proto_rawDesc = "" +
 	"\n" +
-	"\rmessage.proto\x12\x03mqc\"\x81\x01\n" +
+	"\rmessage.proto\x12\x03mqc\"\xf6\x01\n" +
 	"\aMessage\x12%\n" +
 	"\x04type\x18\x01 \x01(\x0e2\x11.mqc.Message.TypeR\x04type\x12\x12\n" +
-	"\x04data\x18\x02 \x01(\fR\x04data\";\n" +
+	"\x04data\x18\x02 \x01(\fR\x04data\x126\n" +
+	"\bmetadata\x18\x03 \x03(\v2\x1a.mqc.Message.MetadataEntryR\bmetadata\x1a;\n" +
+	"\rMetadataEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\";\n" +
 	"\x04Type\x12\n" +
 	"\n" +
 	"\x06INVOKE\x10\x00\x12\a\n" +
 	"\x03ACK\x10\x01\x12\t\n" +
 	"\x05CLOSE\x10\x02\x12\t\n" +
 	"\x05ERROR\x10\x03\x12\b\n" +
-	"\x04DATA\x10\x04B\aZ\x05./mqcb\x06proto3"
+	"\x04DATA\x10\x04\"\xfa\x01\n" +
+	"\x06Invoke\x12\x18\n" +
+	"\aservice\x18\x01 \x01(\tR\aservice\x12\x16\n" +
+	"\x06method\x18\x02 \x01(\tR\x06method\x12\x1f\n" +
+	"\vmethod_type\x18\x03 \x01(\x05R\n" +
+	"methodType\x12)\n" +
+	"\x10protocol_version\x18\x04 \x01(\rR\x0fprotocolVersion\x125\n" +
+	"\bmetadata\x18\x05 \x03(\v2\x19.mqc.Invoke.MetadataEntryR\bmetadata\x1a;\n" +
+	"\rMetadataEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01B\aZ\x05./mqcb\x06proto3"
 
 var (
 	file_message_proto_rawDescOnce sync.Once
@@ -158,18 +260,23 @@ func file_message_proto_rawDescGZIP() []byte {
 }
 
 var file_message_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_message_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_message_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
 var file_message_proto_goTypes = []any{
 	(Message_Type)(0), // 0: mqc.Message.Type
 	(*Message)(nil),   // 1: mqc.Message
+	(*Invoke)(nil),    // 2: mqc.Invoke
+	nil,               // 3: mqc.Message.MetadataEntry
+	nil,               // 4: mqc.Invoke.MetadataEntry
 }
 var file_message_proto_depIdxs = []int32{
 	0, // 0: mqc.Message.type:type_name -> mqc.Message.Type
-	1, // [1:1] is the sub-list for method output_type
-	1, // [1:1] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+	3, // 1: mqc.Message.metadata:type_name -> mqc.Message.MetadataEntry
+	4, // 2: mqc.Invoke.metadata:type_name -> mqc.Invoke.MetadataEntry
+	3, // [3:3] is the sub-list for method output_type
+	3, // [3:3] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
 }
 
 func init() { file_message_proto_init() }
@@ -183,7 +290,7 @@ func file_message_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_message_proto_rawDesc), len(file_message_proto_rawDesc)),
 			NumEnums:      1,
-			NumMessages:   1,
+			NumMessages:   4,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
@@ -1,6 +1,6 @@
 package mqc
 
-import "fmt"
+import "strings"
 
 const (
 	// MethodTypeUnary represents a unary RPC method.
@@ -17,28 +17,86 @@ const (
 	MethodTypeConsumer = 5
 )
 
-// Method represents an RPC method name,
+// ProtocolVersion is the version of the call protocol spoken by this package.
+// It is announced in every INVOKE message.
+const ProtocolVersion = 1
+
+// Method represents an RPC method,
 // that is used to identify the method being invoked.
-// It is formatted as <service>/<method>.
 type Method struct {
+	// Service is the name of the service the method belongs to.
+	Service string
+	// Name is the name of the method within the service.
 	Name string
+	// Type is the method type, one of the MethodType constants.
 	Type int
 }
 
+// NewMethod creates a new method from a name formatted as <service>/<method>.
 func NewMethod(name string, methodType int) *Method {
-	return &Method{Name: name, Type: methodType}
+	m := &Method{Name: name, Type: methodType}
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		m.Service = name[:i]
+		m.Name = name[i+1:]
+	}
+	return m
 }
 
-func NewMethodFromString(s string) *Method {
-	var m Method
-	fmt.Sscanf(s, "%s/%d", &m.Name, &m.Type)
-	return &m
+// NewMethodFromInvoke creates a new method from an INVOKE payload.
+func NewMethodFromInvoke(invoke *Invoke) (*Method, error) {
+	if invoke.ProtocolVersion != ProtocolVersion {
+		return nil, ErrUnsupportedProtocol
+	}
+	if invoke.Method == "" {
+		return nil, ErrProtocolViolation
+	}
+
+	return &Method{
+		Service: invoke.Service,
+		Name:    invoke.Method,
+		Type:    int(invoke.MethodType),
+	}, nil
+}
+
+// FullName returns the method name formatted as <service>/<method>.
+func (m *Method) FullName() string {
+	if m.Service == "" {
+		return m.Name
+	}
+	return m.Service + "/" + m.Name
+}
+
+// Invoke returns the INVOKE payload identifying the method.
+func (m *Method) Invoke() *Invoke {
+	return &Invoke{
+		Service:         m.Service,
+		Method:          m.Name,
+		MethodType:      int32(m.Type),
+		ProtocolVersion: ProtocolVersion,
+	}
 }
 
 func (m *Method) String() string {
-	return fmt.Sprintf("%s/%d", m.Name, m.Type)
+	return m.FullName()
+}
+
+// Names of the method types.
+var methodTypeNames = map[int]string{
+	MethodTypeUnary:        "unary",
+	MethodTypeServerStream: "server-stream",
+	MethodTypeClientStream: "client-stream",
+	MethodTypeBidiStream:   "bidi-stream",
+	MethodTypePublisher:    "publisher",
+	MethodTypeConsumer:     "consumer",
 }
 
+// TypeName returns the name of the method type, e.g. "unary" or "bidi-stream".
+func (m *Method) TypeName() string {
+	if name, ok := methodTypeNames[m.Type]; ok {
+		return name
+	}
+	return "unknown"
+}
 func (m *Method) IsPubSub() bool {
 	return m.Type == MethodTypePublisher || m.Type == MethodTypeConsumer
 }
@@ -0,0 +1,335 @@
+// Package metrics records Prometheus-style metrics of calls, streams and
+// connections of mqc transports.
+//
+//	registry := metrics.NewRegistry()
+//	t, err := tcp.NewTransport(transport.WithMetrics(metrics.New(registry)), ...)
+//	http.Handle("/metrics", registry)
+//
+// Metrics are created with a Registry, which is either the in-memory registry
+// serving the Prometheus text format, or an adapter to another metrics library.
+//
+// The following metrics are recorded:
+//
+//	mqc_calls_started_total{role, transport, service, method, type}
+//	mqc_calls_completed_total{role, transport, service, method, type, code}
+//	mqc_call_duration_seconds{role, transport, service, method}
+//	mqc_call_messages_sent{role, transport, service, method}
+//	mqc_call_messages_received{role, transport, service, method}
+//	mqc_bytes_sent_total{transport}
+//	mqc_bytes_received_total{transport}
+//	mqc_sessions_active{transport}
+//	mqc_streams_active{transport}
+//	mqc_mqtt_reconnects_total
+//	mqc_pubsub_published_total{service, method}
+//	mqc_pubsub_received_total{service, method}
+//
+// The role is "client" or "server", and the code classifies the status of
+// a completed call, see Code. Messages are counted per call, and received
+// pub-sub messages are counted by every consumer, giving the fan-out of
+// published messages.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/srand/mqc"
+)
+
+// Roles of calls.
+const (
+	RoleClient = "client"
+	RoleServer = "server"
+)
+
+// DurationBuckets are the buckets of the call duration histogram, in seconds.
+var DurationBuckets = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// MessageBuckets are the buckets of the messages per call histograms.
+var MessageBuckets = []float64{1, 2, 5, 10, 25, 50, 100, 250, 500, 1000}
+
+// Metrics records the metrics of mqc transports. A nil *Metrics records nothing.
+type Metrics struct {
+	callsStarted     Counter
+	callsCompleted   Counter
+	callDuration     Histogram
+	messagesSent     Histogram
+	messagesReceived Histogram
+	bytesSent        Counter
+	bytesReceived    Counter
+	sessions         Gauge
+	streams          Gauge
+	mqttReconnects   Counter
+	published        Counter
+	received         Counter
+}
+
+// New creates the mqc metrics in the registry. Transports sharing
+// the registry may share the returned metrics.
+func New(registry Registry) *Metrics {
+	call := []string{"role", "transport", "service", "method"}
+
+	return &Metrics{
+		callsStarted: registry.Counter("mqc_calls_started_total",
+			"Number of calls started.", append(call, "type")...),
+		callsCompleted: registry.Counter("mqc_calls_completed_total",
+			"Number of calls completed, by status code.", append(call, "type", "code")...),
+		callDuration: registry.Histogram("mqc_call_duration_seconds",
+			"Duration of calls in seconds.", DurationBuckets, call...),
+		messagesSent: registry.Histogram("mqc_call_messages_sent",
+			"Number of messages sent per call.", MessageBuckets, call...),
+		messagesReceived: registry.Histogram("mqc_call_messages_received",
+			"Number of messages received per call.", MessageBuckets, call...),
+		bytesSent: registry.Counter("mqc_bytes_sent_total",
+			"Number of bytes written to connections.", "transport"),
+		bytesReceived: registry.Counter("mqc_bytes_received_total",
+			"Number of bytes read from connections.", "transport"),
+		sessions: registry.Gauge("mqc_sessions_active",
+			"Number of open multiplexed sessions.", "transport"),
+		streams: registry.Gauge("mqc_streams_active",
+			"Number of open multiplexed streams.", "transport"),
+		mqttReconnects: registry.Counter("mqc_mqtt_reconnects_total",
+			"Number of reconnects of MQTT clients to the broker."),
+		published: registry.Counter("mqc_pubsub_published_total",
+			"Number of messages published to pub-sub methods.", "service", "method"),
+		received: registry.Counter("mqc_pubsub_received_total",
+			"Number of pub-sub messages received by consumers.", "service", "method"),
+	}
+}
+
+// Code classifies the status of a completed call: "ok", "canceled",
+// "deadline_exceeded", "unauthenticated", "permission_denied",
+// "unimplemented", "unavailable", "internal" or "unknown".
+// Errors received from the peer are classified by their message.
+func Code(err error) string {
+	switch {
+	case err == nil:
+		return "ok"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "deadline_exceeded"
+	}
+
+	for _, c := range codes {
+		if errors.Is(err, c.err) || err.Error() == c.err.Error() {
+			return c.code
+		}
+	}
+	return "unknown"
+}
+
+var codes = []struct {
+	code string
+	err  error
+}{
+	{"unauthenticated", mqc.ErrUnauthenticated},
+	{"permission_denied", mqc.ErrPermissionDenied},
+	{"unimplemented", mqc.ErrUnimplemented},
+	{"unavailable", mqc.ErrUnavailable},
+	{"internal", mqc.ErrInternal},
+}
+
+// Call records the metrics of a call. A nil *Call records nothing.
+type Call struct {
+	metrics  *Metrics
+	method   *mqc.Method
+	labels   []string
+	typeName string
+	start    time.Time
+	sent     atomic.Int64
+	received atomic.Int64
+	once     sync.Once
+}
+
+// StartCall records the start of a call in the role over the transport.
+func (m *Metrics) StartCall(role, transport string, method *mqc.Method) *Call {
+	if m == nil {
+		return nil
+	}
+
+	c := &Call{
+		metrics:  m,
+		method:   method,
+		labels:   []string{role, transport, method.Service, method.Name},
+		typeName: method.TypeName(),
+		start:    time.Now(),
+	}
+	m.callsStarted.Add(1, append(c.labels, c.typeName)...)
+	return c
+}
+
+// End records the completion of the call with its status, nil on success.
+// Only the first call to End is recorded.
+func (c *Call) End(err error) {
+	if c == nil {
+		return
+	}
+
+	c.once.Do(func() {
+		m := c.metrics
+		m.callsCompleted.Add(1, append(c.labels, c.typeName, Code(err))...)
+		m.callDuration.Observe(time.Since(c.start).Seconds(), c.labels...)
+		m.messagesSent.Observe(float64(c.sent.Load()), c.labels...)
+		m.messagesReceived.Observe(float64(c.received.Load()), c.labels...)
+	})
+}
+
+// Conn returns a connection counting the messages of the call. The call of
+// a client connection ends when the connection is closed or receiving fails,
+// e.g. at the end of a stream, while server calls end with their handler.
+func (c *Call) Conn(conn mqc.Conn) mqc.Conn {
+	if c == nil {
+		return conn
+	}
+	return &callConn{Conn: conn, call: c, client: c.labels[0] == RoleClient}
+}
+
+type callConn struct {
+	mqc.Conn
+	call   *Call
+	client bool
+}
+
+// Method returns the method the connection was invoked for.
+func (c *callConn) Method() *mqc.Method {
+	return c.call.method
+}
+
+func (c *callConn) Send(ctx context.Context, data []byte) error {
+	err := c.Conn.Send(ctx, data)
+	if err == nil {
+		c.call.sent.Add(1)
+	} else if c.client {
+		c.call.End(err)
+	}
+	return err
+}
+
+func (c *callConn) Recv(ctx context.Context) ([]byte, error) {
+	data, err := c.Conn.Recv(ctx)
+	if err == nil {
+		c.call.received.Add(1)
+	} else if c.client && errors.Is(err, io.EOF) {
+		c.call.End(nil)
+	} else if c.client {
+		c.call.End(err)
+	}
+	return data, err
+}
+
+func (c *callConn) Close() error {
+	if c.client {
+		c.call.End(nil)
+	}
+	return c.Conn.Close()
+}
+
+// CountBytes returns a connection counting the bytes read and written.
+func (m *Metrics) CountBytes(conn io.ReadWriteCloser, transport string) io.ReadWriteCloser {
+	if m == nil {
+		return conn
+	}
+	return &countingConn{ReadWriteCloser: conn, metrics: m, transport: transport}
+}
+
+type countingConn struct {
+	io.ReadWriteCloser
+	metrics   *Metrics
+	transport string
+}
+
+func (c *countingConn) Read(p []byte) (int, error) {
+	n, err := c.ReadWriteCloser.Read(p)
+	if n > 0 {
+		c.metrics.bytesReceived.Add(float64(n), c.transport)
+	}
+	return n, err
+}
+
+func (c *countingConn) Write(p []byte) (int, error) {
+	n, err := c.ReadWriteCloser.Write(p)
+	if n > 0 {
+		c.metrics.bytesSent.Add(float64(n), c.transport)
+	}
+	return n, err
+}
+
+// AddBytes records bytes sent and received by transports without
+// connections of their own, e.g. MQTT message payloads.
+func (m *Metrics) AddBytes(transport string, sent, received int) {
+	if m == nil {
+		return
+	}
+	if sent > 0 {
+		m.bytesSent.Add(float64(sent), transport)
+	}
+	if received > 0 {
+		m.bytesReceived.Add(float64(received), transport)
+	}
+}
+
+// OpenSession records an open multiplexed session and returns a function
+// recording that it was closed.
+func (m *Metrics) OpenSession(transport string) (closed func()) {
+	if m == nil {
+		return func() {}
+	}
+
+	m.sessions.Add(1, transport)
+	var once sync.Once
+	return func() {
+		once.Do(func() { m.sessions.Add(-1, transport) })
+	}
+}
+
+// OpenStream returns a multiplexed stream recorded as open until it is closed.
+func (m *Metrics) OpenStream(conn net.Conn, transport string) net.Conn {
+	if m == nil {
+		return conn
+	}
+
+	m.streams.Add(1, transport)
+	return &streamConn{Conn: conn, metrics: m, transport: transport}
+}
+
+type streamConn struct {
+	net.Conn
+	metrics   *Metrics
+	transport string
+	once      sync.Once
+}
+
+func (c *streamConn) Close() error {
+	c.once.Do(func() { c.metrics.streams.Add(-1, c.transport) })
+	return c.Conn.Close()
+}
+
+// MQTTReconnect records a reconnect of an MQTT client to the broker.
+func (m *Metrics) MQTTReconnect() {
+	if m == nil {
+		return
+	}
+	m.mqttReconnects.Add(1)
+}
+
+// Published records a message published to a pub-sub method.
+func (m *Metrics) Published(method *mqc.Method) {
+	if m == nil {
+		return
+	}
+	m.published.Add(1, method.Service, method.Name)
+}
+
+// Received records a pub-sub message received by a consumer.
+func (m *Metrics) Received(method *mqc.Method) {
+	if m == nil {
+		return
+	}
+	m.received.Add(1, method.Service, method.Name)
+}
@@ -0,0 +1,250 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Counter is a metric that only increases, partitioned by label values.
+type Counter interface {
+	// Add increases the counter of the label values by delta.
+	Add(delta float64, labelValues ...string)
+}
+
+// Gauge is a metric that increases and decreases, partitioned by label values.
+type Gauge interface {
+	// Add changes the gauge of the label values by delta.
+	Add(delta float64, labelValues ...string)
+}
+
+// Histogram samples observations into buckets, partitioned by label values.
+type Histogram interface {
+	// Observe adds an observation to the histogram of the label values.
+	Observe(value float64, labelValues ...string)
+}
+
+// Registry creates the metrics recorded by mqc, allowing them to be exported
+// with any metrics library. Label values are given in the order of the label
+// names the metric was created with. Creating a metric with the name of an
+// existing metric returns the existing metric.
+type Registry interface {
+	Counter(name, help string, labelNames ...string) Counter
+	Gauge(name, help string, labelNames ...string) Gauge
+	Histogram(name, help string, buckets []float64, labelNames ...string) Histogram
+}
+
+// MemoryRegistry keeps metrics in memory and serves them in the
+// Prometheus text exposition format.
+type MemoryRegistry struct {
+	mu       sync.Mutex
+	families map[string]*family
+}
+
+var (
+	_ Registry     = (*MemoryRegistry)(nil)
+	_ http.Handler = (*MemoryRegistry)(nil)
+)
+
+// NewRegistry returns an empty in-memory registry.
+func NewRegistry() *MemoryRegistry {
+	return &MemoryRegistry{families: make(map[string]*family)}
+}
+
+type family struct {
+	registry   *MemoryRegistry
+	name       string
+	help       string
+	kind       string
+	labelNames []string
+	buckets    []float64
+	series     map[string]*series
+}
+
+type series struct {
+	labelValues []string
+	value       float64
+
+	// Histograms count observations per bucket, and in total
+	counts []uint64
+	count  uint64
+}
+
+func (r *MemoryRegistry) Counter(name, help string, labelNames ...string) Counter {
+	return r.family(name, help, "counter", nil, labelNames)
+}
+
+func (r *MemoryRegistry) Gauge(name, help string, labelNames ...string) Gauge {
+	return r.family(name, help, "gauge", nil, labelNames)
+}
+
+func (r *MemoryRegistry) Histogram(name, help string, buckets []float64, labelNames ...string) Histogram {
+	buckets = append([]float64{}, buckets...)
+	sort.Float64s(buckets)
+	return r.family(name, help, "histogram", buckets, labelNames)
+}
+
+func (r *MemoryRegistry) family(name, help, kind string, buckets []float64, labelNames []string) *family {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if f, ok := r.families[name]; ok {
+		if f.kind != kind || strings.Join(f.labelNames, ",") != strings.Join(labelNames, ",") {
+			panic(fmt.Sprintf("metric %s is already registered as a %s with labels %v", name, f.kind, f.labelNames))
+		}
+		return f
+	}
+
+	f := &family{
+		registry:   r,
+		name:       name,
+		help:       help,
+		kind:       kind,
+		labelNames: append([]string{}, labelNames...),
+		buckets:    buckets,
+		series:     make(map[string]*series),
+	}
+	r.families[name] = f
+	return f
+}
+
+// get returns the series of the label values.
+// It must be called with the registry mutex held.
+func (f *family) get(labelValues []string) *series {
+	if len(labelValues) != len(f.labelNames) {
+		panic(fmt.Sprintf("metric %s has %d labels, got %d values", f.name, len(f.labelNames), len(labelValues)))
+	}
+
+	key := strings.Join(labelValues, "\xff")
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labelValues: append([]string{}, labelValues...)}
+		if f.kind == "histogram" {
+			s.counts = make([]uint64, len(f.buckets))
+		}
+		f.series[key] = s
+	}
+	return s
+}
+
+func (f *family) Add(delta float64, labelValues ...string) {
+	f.registry.mu.Lock()
+	defer f.registry.mu.Unlock()
+	f.get(labelValues).value += delta
+}
+
+func (f *family) Observe(value float64, labelValues ...string) {
+	f.registry.mu.Lock()
+	defer f.registry.mu.Unlock()
+
+	s := f.get(labelValues)
+	for i, bound := range f.buckets {
+		if value <= bound {
+			s.counts[i]++
+		}
+	}
+	s.count++
+	s.value += value
+}
+
+// Value returns the value of a counter or gauge, or the number of
+// observations of a histogram, for the label values. It returns 0
+// for unknown metrics and label values.
+func (r *MemoryRegistry) Value(name string, labelValues ...string) float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	f, ok := r.families[name]
+	if !ok {
+		return 0
+	}
+	s, ok := f.series[strings.Join(labelValues, "\xff")]
+	if !ok {
+		return 0
+	}
+	if f.kind == "histogram" {
+		return float64(s.count)
+	}
+	return s.value
+}
+
+// WriteText writes all metrics in the Prometheus text exposition format.
+func (r *MemoryRegistry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	names := make([]string, 0, len(r.families))
+	for name := range r.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		f := r.families[name]
+
+		keys := make([]string, 0, len(f.series))
+		for key := range f.series {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		fmt.Fprintf(&b, "# HELP %s %s\n", f.name, strings.ReplaceAll(f.help, "\n", " "))
+		fmt.Fprintf(&b, "# TYPE %s %s\n", f.name, f.kind)
+
+		for _, key := range keys {
+			s := f.series[key]
+			labels := formatLabels(f.labelNames, s.labelValues)
+
+			if f.kind != "histogram" {
+				fmt.Fprintf(&b, "%s%s %s\n", f.name, labels, formatValue(s.value))
+				continue
+			}
+
+			for i, bound := range f.buckets {
+				le := formatLabels(slices.Concat(f.labelNames, []string{"le"}), slices.Concat(s.labelValues, []string{formatValue(bound)}))
+				fmt.Fprintf(&b, "%s_bucket%s %d\n", f.name, le, s.counts[i])
+			}
+			le := formatLabels(slices.Concat(f.labelNames, []string{"le"}), slices.Concat(s.labelValues, []string{"+Inf"}))
+			fmt.Fprintf(&b, "%s_bucket%s %d\n", f.name, le, s.count)
+			fmt.Fprintf(&b, "%s_sum%s %s\n", f.name, labels, formatValue(s.value))
+			fmt.Fprintf(&b, "%s_count%s %d\n", f.name, labels, s.count)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// ServeHTTP serves all metrics in the Prometheus text exposition format.
+func (r *MemoryRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.WriteText(w)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = fmt.Sprintf(`%s="%s"`, name, labelEscaper.Replace(values[i]))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatValue(value float64) string {
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
@@ -0,0 +1,228 @@
+package mqctest
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/srand/mqc"
+	"github.com/srand/mqc/serialization"
+	"github.com/srand/mqc/transport"
+)
+
+// FakeClientConn is an in-memory mqc.ClientConn calling the handlers
+// registered on it. It is also an mqc.Transport, so servers can be
+// registered with generated Register<Service>Server functions and called
+// with generated clients without a network.
+//
+// Invoke returns Err if set, and records the invoked methods.
+type FakeClientConn struct {
+	// Err is returned by Invoke if set
+	Err error
+
+	handlers   *transport.Handlers
+	serializer serialization.Serializer
+
+	mu      sync.Mutex
+	state   mqc.ConnState
+	invoked []*mqc.Method
+	closed  chan struct{}
+}
+
+var _ mqc.Transport = (*FakeClientConn)(nil)
+
+// NewFakeClientConn creates a ready fake using the proto serializer.
+func NewFakeClientConn() *FakeClientConn {
+	return &FakeClientConn{
+		handlers:   transport.NewHandlers(),
+		serializer: serialization.NewProtoSerializer(),
+		state:      mqc.ConnStateReady,
+		closed:     make(chan struct{}),
+	}
+}
+
+// Invoke starts a call of the handler of a method. Errors returned by the
+// handler are received by the client like remote errors.
+func (f *FakeClientConn) Invoke(ctx context.Context, method *mqc.Method) (mqc.Conn, error) {
+	f.mu.Lock()
+	f.invoked = append(f.invoked, method)
+	state := f.state
+	f.mu.Unlock()
+
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	if state == mqc.ConnStateShutdown {
+		return nil, mqc.ErrUnavailable
+	}
+
+	handler, ok := f.handlers.Get(method)
+	if !ok {
+		return nil, mqc.ErrUnimplemented
+	}
+
+	requests, responses := newQueue(), newQueue()
+	client := &fakeConn{method: method, in: responses, out: requests}
+	server := &fakeConn{method: method, in: requests, out: responses}
+
+	go func() {
+		if err := mqc.CallHandler(context.WithoutCancel(ctx), handler, method, server); err != nil {
+			// Only the message of remote errors reaches the client
+			responses.push(frame{err: errors.New(err.Error())})
+			return
+		}
+		responses.push(frame{err: io.EOF})
+	}()
+
+	return client, nil
+}
+
+// Invoked returns the methods invoked so far.
+func (f *FakeClientConn) Invoked() []*mqc.Method {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]*mqc.Method(nil), f.invoked...)
+}
+
+// SetState sets the state reported by State.
+func (f *FakeClientConn) SetState(state mqc.ConnState) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.state = state
+}
+
+func (f *FakeClientConn) State() mqc.ConnState {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.state
+}
+
+func (f *FakeClientConn) Serializer() serialization.Serializer {
+	return f.serializer
+}
+
+// Close shuts the fake down. Later calls fail with mqc.ErrUnavailable.
+func (f *FakeClientConn) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.state != mqc.ConnStateShutdown {
+		f.state = mqc.ConnStateShutdown
+		close(f.closed)
+	}
+	return nil
+}
+
+func (f *FakeClientConn) Dial() error {
+	return nil
+}
+
+func (f *FakeClientConn) RegisterHandler(method *mqc.Method, handler mqc.MethodHandler) error {
+	f.handlers.Register(method, handler)
+	return nil
+}
+
+func (f *FakeClientConn) UnregisterHandler(method *mqc.Method) error {
+	f.handlers.Unregister(method)
+	return nil
+}
+
+func (f *FakeClientConn) Methods() []*mqc.Method {
+	return f.handlers.Methods()
+}
+
+// Serve blocks until the fake is closed.
+func (f *FakeClientConn) Serve() error {
+	<-f.closed
+	return nil
+}
+
+// frame is a message, or the end of a stream if err is set.
+type frame struct {
+	data []byte
+	err  error
+}
+
+// queue is an unbounded queue of frames in one direction of a call.
+// The final frame is kept, so that every later Recv returns its error.
+type queue struct {
+	mu     sync.Mutex
+	frames []frame
+	ended  bool
+	signal chan struct{}
+}
+
+func newQueue() *queue {
+	return &queue{signal: make(chan struct{}, 1)}
+}
+
+func (q *queue) push(f frame) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.ended {
+		return io.ErrClosedPipe
+	}
+	q.frames = append(q.frames, f)
+	q.ended = f.err != nil
+
+	select {
+	case q.signal <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (q *queue) pop(ctx context.Context) ([]byte, error) {
+	for {
+		q.mu.Lock()
+		if len(q.frames) > 0 {
+			f := q.frames[0]
+			if f.err == nil {
+				q.frames = q.frames[1:]
+			}
+			q.mu.Unlock()
+			return f.data, f.err
+		}
+		q.mu.Unlock()
+
+		select {
+		case <-q.signal:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// fakeConn is one end of an in-memory call.
+type fakeConn struct {
+	method *mqc.Method
+	in     *queue
+	out    *queue
+}
+
+func (c *fakeConn) Method() *mqc.Method {
+	return c.method
+}
+
+func (c *fakeConn) Send(ctx context.Context, data []byte) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return c.out.push(frame{data: data})
+}
+
+func (c *fakeConn) SendClose(ctx context.Context) error {
+	return c.out.push(frame{err: io.EOF})
+}
+
+func (c *fakeConn) Recv(ctx context.Context) ([]byte, error) {
+	return c.in.pop(ctx)
+}
+
+// Close ends both directions of the call.
+func (c *fakeConn) Close() error {
+	c.out.push(frame{err: io.EOF})
+	c.in.push(frame{err: io.ErrClosedPipe})
+	return nil
+}
@@ -0,0 +1,225 @@
+// Package mqctest provides in-memory fakes of the mqc stream interfaces and
+// of a client connection, allowing clients and servers to be unit tested
+// without a transport.
+//
+// Fakes replay scripted messages from their Requests or Responses fields
+// and record everything sent to them. Once the script is exhausted, Recv
+// returns Err if set, or io.EOF otherwise.
+package mqctest
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/srand/mqc"
+)
+
+// script guards access to the scripted and recorded messages of a fake.
+type script[T any] struct {
+	mu sync.Mutex
+}
+
+func (s *script[T]) next(ctx context.Context, messages *[]*T, err error) (*T, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(*messages) == 0 {
+		if err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+
+	msg := (*messages)[0]
+	*messages = (*messages)[1:]
+	return msg, nil
+}
+
+func (s *script[T]) record(ctx context.Context, sent *[]*T, msg *T, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	*sent = append(*sent, msg)
+	return nil
+}
+
+// FakeClientStreamClient is an in-memory mqc.ClientStreamClient.
+type FakeClientStreamClient[Req any, Res any] struct {
+	script[Req]
+
+	// Response is returned by CloseAndRecv.
+	Response *Res
+	// Err is returned by CloseAndRecv instead of Response, if set.
+	Err error
+	// SendErr is returned by Send, if set.
+	SendErr error
+
+	// Sent records the requests sent by the client.
+	Sent []*Req
+	// Closed is set when CloseAndRecv has been called.
+	Closed bool
+}
+
+var _ mqc.ClientStreamClient[any, any] = (*FakeClientStreamClient[any, any])(nil)
+
+func (f *FakeClientStreamClient[Req, Res]) Send(ctx context.Context, req *Req) error {
+	return f.record(ctx, &f.Sent, req, f.SendErr)
+}
+
+func (f *FakeClientStreamClient[Req, Res]) CloseAndRecv(ctx context.Context) (*Res, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.Closed = true
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	return f.Response, nil
+}
+
+// FakeServerStreamClient is an in-memory mqc.ServerStreamClient.
+type FakeServerStreamClient[Res any] struct {
+	script[Res]
+
+	// Responses are returned by Recv, in order.
+	Responses []*Res
+	// Err is returned by Recv once all responses have been received, if set.
+	Err error
+}
+
+var _ mqc.ServerStreamClient[any] = (*FakeServerStreamClient[any])(nil)
+
+func (f *FakeServerStreamClient[Res]) Recv(ctx context.Context) (*Res, error) {
+	return f.next(ctx, &f.Responses, f.Err)
+}
+
+// FakeBidiStreamClient is an in-memory mqc.BidiStreamClient.
+type FakeBidiStreamClient[Req any, Res any] struct {
+	requests  script[Req]
+	responses script[Res]
+
+	// Responses are returned by Recv, in order.
+	Responses []*Res
+	// Err is returned by Recv once all responses have been received, if set.
+	Err error
+	// SendErr is returned by Send, if set.
+	SendErr error
+
+	// Sent records the requests sent by the client.
+	Sent []*Req
+	// Closed is set when CloseSend has been called.
+	Closed bool
+}
+
+var _ mqc.BidiStreamClient[any, any] = (*FakeBidiStreamClient[any, any])(nil)
+
+func (f *FakeBidiStreamClient[Req, Res]) Send(ctx context.Context, req *Req) error {
+	return f.requests.record(ctx, &f.Sent, req, f.SendErr)
+}
+
+func (f *FakeBidiStreamClient[Req, Res]) Recv(ctx context.Context) (*Res, error) {
+	return f.responses.next(ctx, &f.Responses, f.Err)
+}
+
+func (f *FakeBidiStreamClient[Req, Res]) CloseSend() error {
+	f.requests.mu.Lock()
+	defer f.requests.mu.Unlock()
+
+	f.Closed = true
+	return nil
+}
+
+// FakeClientStreamServer is an in-memory mqc.ClientStreamServer.
+type FakeClientStreamServer[Req any, Res any] struct {
+	requests  script[Req]
+	responses script[Res]
+
+	// Requests are returned by Recv, in order.
+	Requests []*Req
+	// Err is returned by Recv once all requests have been received, if set.
+	Err error
+	// SendErr is returned by SendAndClose, if set.
+	SendErr error
+
+	// Sent records the response sent by the server.
+	Sent []*Res
+}
+
+var _ mqc.ClientStreamServer[any, any] = (*FakeClientStreamServer[any, any])(nil)
+
+func (f *FakeClientStreamServer[Req, Res]) Recv(ctx context.Context) (*Req, error) {
+	return f.requests.next(ctx, &f.Requests, f.Err)
+}
+
+func (f *FakeClientStreamServer[Req, Res]) SendAndClose(ctx context.Context, res *Res) error {
+	return f.responses.record(ctx, &f.Sent, res, f.SendErr)
+}
+
+// FakeServerStreamServer is an in-memory mqc.ServerStreamServer.
+type FakeServerStreamServer[Res any] struct {
+	script[Res]
+
+	// SendErr is returned by Send, if set.
+	SendErr error
+
+	// Sent records the responses sent by the server.
+	Sent []*Res
+}
+
+var _ mqc.ServerStreamServer[any] = (*FakeServerStreamServer[any])(nil)
+
+func (f *FakeServerStreamServer[Res]) Send(ctx context.Context, res *Res) error {
+	return f.record(ctx, &f.Sent, res, f.SendErr)
+}
+
+// FakeBidiStreamServer is an in-memory mqc.BidiStreamServer.
+type FakeBidiStreamServer[Req any, Res any] struct {
+	requests  script[Req]
+	responses script[Res]
+
+	// Requests are returned by Recv, in order.
+	Requests []*Req
+	// Err is returned by Recv once all requests have been received, if set.
+	Err error
+	// SendErr is returned by Send, if set.
+	SendErr error
+
+	// Sent records the responses sent by the server.
+	Sent []*Res
+	// Closed is set when CloseSend has been called.
+	Closed bool
+}
+
+var _ mqc.BidiStreamServer[any, any] = (*FakeBidiStreamServer[any, any])(nil)
+
+func (f *FakeBidiStreamServer[Req, Res]) Send(ctx context.Context, res *Res) error {
+	return f.responses.record(ctx, &f.Sent, res, f.SendErr)
+}
+
+func (f *FakeBidiStreamServer[Req, Res]) Recv(ctx context.Context) (*Req, error) {
+	return f.requests.next(ctx, &f.Requests, f.Err)
+}
+
+func (f *FakeBidiStreamServer[Req, Res]) CloseSend() error {
+	f.responses.mu.Lock()
+	defer f.responses.mu.Unlock()
+
+	f.Closed = true
+	return nil
+}
@@ -0,0 +1,153 @@
+package mqctest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/srand/mqc"
+)
+
+// SpanRecord is a span ended by a Tracer.
+type SpanRecord struct {
+	// Name is the full name of the called method.
+	Name      string
+	Kind      mqc.SpanKind
+	Method    mqc.Method
+	Transport string
+
+	// TraceID and SpanID identify the span as hex strings, and ParentID
+	// is the ID of the parent span, empty for root spans.
+	TraceID  string
+	SpanID   string
+	ParentID string
+
+	// Tracestate is the W3C tracestate continued from the parent span.
+	Tracestate string
+
+	// Links are the traceparent values of the spans linked to,
+	// i.e. the publishers of received messages.
+	Links []string
+
+	// Err is the status the span ended with.
+	Err error
+}
+
+// Traceparent returns the W3C traceparent of the span.
+func (s *SpanRecord) Traceparent() string {
+	return fmt.Sprintf("00-%s-%s-01", s.TraceID, s.SpanID)
+}
+
+// Tracer is an in-memory mqc.Tracer recording ended spans, allowing
+// the spans and propagated trace context of calls to be tested without
+// a tracing backend.
+type Tracer struct {
+	mu    sync.Mutex
+	spans []SpanRecord
+}
+
+var _ mqc.Tracer = (*Tracer)(nil)
+
+// NewTracer returns an in-memory tracer.
+func NewTracer() *Tracer {
+	return &Tracer{}
+}
+
+type spanKey struct{}
+
+type span struct {
+	tracer *Tracer
+	record SpanRecord
+	once   sync.Once
+}
+
+// Start starts a span, continuing the trace of the remote caller of server
+// spans and linking consumer spans to the remote publisher.
+func (t *Tracer) Start(ctx context.Context, info mqc.SpanInfo) (context.Context, mqc.Span) {
+	s := &span{
+		tracer: t,
+		record: SpanRecord{
+			Name:      info.Method.FullName(),
+			Kind:      info.Kind,
+			Method:    *info.Method,
+			Transport: info.Transport,
+			SpanID:    randomHex(8),
+		},
+	}
+
+	remoteTrace, remoteSpan, remoteOk := parseTraceparent(info.Remote[mqc.TraceparentKey])
+
+	if parent, ok := ctx.Value(spanKey{}).(*span); ok {
+		s.record.TraceID = parent.record.TraceID
+		s.record.ParentID = parent.record.SpanID
+		s.record.Tracestate = parent.record.Tracestate
+	} else if info.Kind == mqc.SpanKindServer && remoteOk {
+		s.record.TraceID = remoteTrace
+		s.record.ParentID = remoteSpan
+		s.record.Tracestate = info.Remote[mqc.TracestateKey]
+	} else {
+		s.record.TraceID = randomHex(16)
+	}
+
+	if info.Kind == mqc.SpanKindConsumer && remoteOk {
+		s.record.Links = append(s.record.Links, info.Remote[mqc.TraceparentKey])
+	}
+
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+// Inject adds the traceparent and tracestate of the span in the context
+// to the metadata.
+func (t *Tracer) Inject(ctx context.Context, md mqc.Metadata) {
+	s, ok := ctx.Value(spanKey{}).(*span)
+	if !ok {
+		return
+	}
+
+	md[mqc.TraceparentKey] = s.record.Traceparent()
+	if s.record.Tracestate != "" {
+		md[mqc.TracestateKey] = s.record.Tracestate
+	}
+}
+
+// Spans returns the ended spans in the order they ended.
+func (t *Tracer) Spans() []SpanRecord {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]SpanRecord{}, t.spans...)
+}
+
+// Reset forgets the ended spans.
+func (t *Tracer) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.spans = nil
+}
+
+func (s *span) End(err error) {
+	s.once.Do(func() {
+		s.record.Err = err
+
+		s.tracer.mu.Lock()
+		defer s.tracer.mu.Unlock()
+		s.tracer.spans = append(s.tracer.spans, s.record)
+	})
+}
+
+// parseTraceparent returns the trace and span IDs of a W3C traceparent.
+func parseTraceparent(traceparent string) (traceID, spanID string, ok bool) {
+	parts := strings.Split(traceparent, "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return "", "", false
+	}
+	return parts[1], parts[2], true
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,100 @@
+// Package opentelemetry traces mqc calls with OpenTelemetry.
+//
+//	tracer := opentelemetry.NewTracer(provider)
+//	t, err := tcp.NewTransport(transport.WithTracer(tracer), ...)
+//
+// Spans are named after the called method, e.g. "helloworld.Greeter/SayHello",
+// and follow the OpenTelemetry RPC semantic conventions. The trace context is
+// propagated in the call metadata using the W3C trace context format.
+package opentelemetry
+
+import (
+	"context"
+
+	"github.com/srand/mqc"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentationName is the name of the OpenTelemetry tracer creating spans.
+const InstrumentationName = "github.com/srand/mqc"
+
+var spanKinds = map[mqc.SpanKind]trace.SpanKind{
+	mqc.SpanKindClient:   trace.SpanKindClient,
+	mqc.SpanKindServer:   trace.SpanKindServer,
+	mqc.SpanKindProducer: trace.SpanKindProducer,
+	mqc.SpanKindConsumer: trace.SpanKindConsumer,
+}
+
+// Tracer creates OpenTelemetry spans for mqc calls.
+type Tracer struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+var _ mqc.Tracer = (*Tracer)(nil)
+
+// NewTracer returns a tracer creating spans with the provider,
+// or with the global provider if nil.
+func NewTracer(provider trace.TracerProvider) *Tracer {
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	return &Tracer{
+		tracer:     provider.Tracer(InstrumentationName),
+		propagator: propagation.TraceContext{},
+	}
+}
+
+// Start starts a span for the call. Server spans continue the trace of the
+// remote caller and consumer spans link to the span of the publisher.
+func (t *Tracer) Start(ctx context.Context, info mqc.SpanInfo) (context.Context, mqc.Span) {
+	opts := []trace.SpanStartOption{
+		trace.WithSpanKind(spanKinds[info.Kind]),
+		trace.WithAttributes(
+			attribute.String("rpc.system", "mqc"),
+			attribute.String("rpc.service", info.Method.Service),
+			attribute.String("rpc.method", info.Method.Name),
+			attribute.String("mqc.method_type", info.Method.TypeName()),
+			attribute.String("mqc.transport", info.Transport),
+		),
+	}
+
+	if info.Remote != nil {
+		remote := t.propagator.Extract(context.Background(), propagation.MapCarrier(info.Remote))
+
+		switch info.Kind {
+		case mqc.SpanKindServer:
+			ctx = trace.ContextWithRemoteSpanContext(ctx, trace.SpanContextFromContext(remote))
+		case mqc.SpanKindConsumer:
+			if sc := trace.SpanContextFromContext(remote); sc.IsValid() {
+				opts = append(opts, trace.WithLinks(trace.Link{SpanContext: sc}))
+			}
+		}
+	}
+
+	ctx, span := t.tracer.Start(ctx, info.Method.FullName(), opts...)
+	return ctx, &otelSpan{span: span}
+}
+
+// Inject adds the W3C trace context of the span in the context to the metadata.
+func (t *Tracer) Inject(ctx context.Context, md mqc.Metadata) {
+	t.propagator.Inject(ctx, propagation.MapCarrier(md))
+}
+
+type otelSpan struct {
+	span trace.Span
+}
+
+func (s *otelSpan) End(err error) {
+	if err != nil {
+		s.span.RecordError(err)
+		s.span.SetStatus(codes.Error, err.Error())
+	} else {
+		s.span.SetStatus(codes.Ok, "")
+	}
+	s.span.End()
+}
@@ -0,0 +1,118 @@
+package mqc
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/srand/mqc/serialization"
+)
+
+// QoS is the delivery guarantee requested from message brokers.
+type QoS int
+
+const (
+	// QoSDefault uses the transport default.
+	QoSDefault QoS = iota
+	// QoSAtMostOnce delivers a message at most once.
+	QoSAtMostOnce
+	// QoSAtLeastOnce delivers a message at least once.
+	QoSAtLeastOnce
+	// QoSExactlyOnce delivers a message exactly once.
+	QoSExactlyOnce
+)
+
+// MethodOptions describes the behavior of a method as declared in its schema.
+// Generated code registers the options of every annotated method.
+type MethodOptions struct {
+	// Timeout is the default deadline for calls whose context has none,
+	// overriding the call timeout of the transport.
+	Timeout time.Duration
+
+	// Idempotent reports whether the method can safely be called more than once.
+	Idempotent bool
+
+	// QoS is the delivery guarantee requested from message brokers.
+	QoS QoS
+
+	// Topic is the topic template for pub-sub methods.
+	// {service} and {method} are replaced by the service and method names.
+	Topic string
+
+	// Serializer overrides the transport serializer for the method payloads.
+	Serializer serialization.Serializer
+}
+
+var (
+	methodOptionsMu sync.RWMutex
+	methodOptions   = map[string]*MethodOptions{}
+)
+
+// RegisterMethodOptions registers the options of a method.
+// Options apply to all method types sharing the method name,
+// e.g. both the publisher and consumer of a pub-sub method.
+func RegisterMethodOptions(method *Method, options *MethodOptions) {
+	methodOptionsMu.Lock()
+	defer methodOptionsMu.Unlock()
+	methodOptions[method.FullName()] = options
+}
+
+// GetMethodOptions returns the registered options of a method,
+// or nil if the method has none.
+func GetMethodOptions(method *Method) *MethodOptions {
+	methodOptionsMu.RLock()
+	defer methodOptionsMu.RUnlock()
+	return methodOptions[method.FullName()]
+}
+
+// Topic expands the topic template of the method,
+// returning an empty string if the method has no template.
+func (m *Method) Topic() string {
+	options := GetMethodOptions(m)
+	if options == nil || options.Topic == "" {
+		return ""
+	}
+
+	return strings.NewReplacer("{service}", m.Service, "{method}", m.Name).Replace(options.Topic)
+}
+
+// WithDefaultTimeout returns a context with the given timeout,
+// unless the context already has a deadline or the timeout is zero.
+func WithDefaultTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok || timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// withCallTimeout applies the declared default timeout of a method, or else
+// the call timeout of the client connection, to calls whose context has no deadline.
+func withCallTimeout(ctx context.Context, conn ClientConn, method *Method) (context.Context, context.CancelFunc) {
+	if options := GetMethodOptions(method); options != nil && options.Timeout > 0 {
+		return WithDefaultTimeout(ctx, options.Timeout)
+	}
+	if t, ok := conn.(interface{ CallTimeout() time.Duration }); ok {
+		return WithDefaultTimeout(ctx, t.CallTimeout())
+	}
+	return ctx, func() {}
+}
+
+// methodSerializer returns the serializer for the payloads of a method.
+func methodSerializer(conn ClientConn, method *Method) serialization.Serializer {
+	if options := GetMethodOptions(method); options != nil && options.Serializer != nil {
+		return options.Serializer
+	}
+	return conn.Serializer()
+}
+
+// connSerializer returns the serializer for the payloads of a connection,
+// falling back to the given serializer if the method does not override it.
+func connSerializer(serializer serialization.Serializer, conn Conn) serialization.Serializer {
+	if c, ok := conn.(interface{ Method() *Method }); ok {
+		if options := GetMethodOptions(c.Method()); options != nil && options.Serializer != nil {
+			return options.Serializer
+		}
+	}
+	return serializer
+}
@@ -0,0 +1,13 @@
+//go:generate protoc -I. --go_out=. --go_opt=module=github.com/srand/mqc/options mqc/options.proto
+
+// Package options holds the protobuf extensions declaring mqc method behavior.
+//
+// Import "mqc/options.proto" in your service definitions, with this directory
+// on the protoc include path, and annotate services and methods:
+//
+//	service Weather {
+//	  rpc Update(stream WeatherUpdate) returns (stream WeatherUpdate) {
+//	    option (mqc.method).kind = KIND_PUBSUB;
+//	  }
+//	}
+package options
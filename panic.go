@@ -0,0 +1,57 @@
+package mqc
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicPolicy decides what a server does after a handler panicked.
+// In both cases the caller receives ErrInternal.
+type PanicPolicy int
+
+const (
+	// PanicRecover recovers the panic and keeps serving other calls.
+	PanicRecover PanicPolicy = iota
+	// PanicRepanic panics again after the caller was notified,
+	// crashing the process unless recovered elsewhere.
+	PanicRepanic
+)
+
+// PanicHandler is called with every recovered handler panic,
+// e.g. to report crashes. The context is the context of the call.
+type PanicHandler func(ctx context.Context, p *PanicError)
+
+// PanicError is the error of a call whose handler panicked.
+// It matches ErrInternal with errors.Is.
+type PanicError struct {
+	// Method is the called method.
+	Method *Method
+
+	// Value is the value passed to panic.
+	Value any
+
+	// Stack is the stack trace of the panicking goroutine.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic in handler of %s: %v", e.Method.FullName(), e.Value)
+}
+
+func (e *PanicError) Unwrap() error {
+	return ErrInternal
+}
+
+// CallHandler calls the handler of an incoming call and returns a panic
+// of the handler as a *PanicError. Servers send ErrInternal to the caller
+// instead, hiding the details of the panic.
+func CallHandler(ctx context.Context, handler MethodHandler, method *Method, conn Conn) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Method: method, Value: r, Stack: debug.Stack()}
+		}
+	}()
+
+	return handler(ctx, conn)
+}
@@ -0,0 +1,120 @@
+package mqc
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"log/slog"
+	"net"
+	"strings"
+)
+
+// Peer describes the remote end of the connection of a call.
+type Peer struct {
+	// Addr is the remote address of the connection.
+	Addr net.Addr
+
+	// TLS is the state of the TLS connection, or nil if it is not secured.
+	TLS *tls.ConnectionState
+
+	// Identity is the identity of the verified certificate of the peer,
+	// or nil if the peer did not present a verified certificate.
+	Identity *Identity
+
+	// Unix holds the credentials of the peer process on unix sockets,
+	// or nil if they are not available on the platform.
+	Unix *UnixCredentials
+}
+
+// LogValue describes the peer in structured logs by its address, and the
+// subject of its certificate or the process ID of its unix socket if known.
+func (p *Peer) LogValue() slog.Value {
+	if p == nil {
+		return slog.GroupValue()
+	}
+
+	var attrs []slog.Attr
+	if p.Addr != nil {
+		attrs = append(attrs, slog.String("addr", p.Addr.String()))
+	}
+	if p.Identity != nil {
+		attrs = append(attrs, slog.String("subject", p.Identity.Subject))
+	}
+	if p.Unix != nil {
+		attrs = append(attrs, slog.Int("pid", p.Unix.Pid))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// UnixCredentials are the credentials of the process on the other end of
+// a unix socket, as reported by the operating system.
+type UnixCredentials struct {
+	Pid int
+	Uid int
+	Gid int
+}
+
+type peerKey struct{}
+
+// PeerFromContext returns the peer of an incoming call,
+// or nil if the transport does not provide one.
+func PeerFromContext(ctx context.Context) *Peer {
+	peer, _ := ctx.Value(peerKey{}).(*Peer)
+	return peer
+}
+
+// NewContextWithPeer returns a context carrying the peer of an incoming call.
+func NewContextWithPeer(ctx context.Context, peer *Peer) context.Context {
+	return context.WithValue(ctx, peerKey{}, peer)
+}
+
+// TLSIdentity returns the identity of the verified certificate of a TLS peer,
+// or nil if the peer certificate was not verified.
+func TLSIdentity(state *tls.ConnectionState) *Identity {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.PeerCertificates) == 0 {
+		return nil
+	}
+	return CertificateIdentity(state.PeerCertificates[0])
+}
+
+// CertificateIdentity returns the identity described by a certificate.
+// The subject is the common name, or the first subject alternative name
+// if the certificate has no common name.
+func CertificateIdentity(cert *x509.Certificate) *Identity {
+	sans := CertificateSANs(cert)
+
+	identity := &Identity{
+		Subject:    cert.Subject.CommonName,
+		Attributes: map[string]string{},
+	}
+
+	if identity.Subject == "" && len(sans) > 0 {
+		identity.Subject = sans[0]
+	}
+	if len(cert.Subject.Organization) > 0 {
+		identity.Attributes["organization"] = strings.Join(cert.Subject.Organization, ",")
+	}
+	if len(cert.Subject.OrganizationalUnit) > 0 {
+		identity.Attributes["organizational_unit"] = strings.Join(cert.Subject.OrganizationalUnit, ",")
+	}
+	if len(sans) > 0 {
+		identity.Attributes["san"] = strings.Join(sans, ",")
+	}
+
+	return identity
+}
+
+// CertificateSANs returns the subject alternative names of a certificate:
+// URIs, DNS names, email addresses and IP addresses, in that order.
+func CertificateSANs(cert *x509.Certificate) []string {
+	var sans []string
+	for _, uri := range cert.URIs {
+		sans = append(sans, uri.String())
+	}
+	sans = append(sans, cert.DNSNames...)
+	sans = append(sans, cert.EmailAddresses...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	return sans
+}
@@ -0,0 +1,90 @@
+package mqc
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"sync"
+)
+
+// PinVerifier verifies the public key fingerprint of the certificate of a
+// server, see SPKIFingerprint. It returns an error to reject the server.
+type PinVerifier func(addr, fingerprint string) error
+
+// PinFingerprints returns a verifier accepting servers whose certificate
+// has one of the public key fingerprints.
+func PinFingerprints(fingerprints ...string) PinVerifier {
+	return func(addr, fingerprint string) error {
+		for _, pinned := range fingerprints {
+			if pinned == fingerprint {
+				return nil
+			}
+		}
+		return ErrPinMismatch
+	}
+}
+
+// TrustOnFirstUse returns a verifier trusting the public key of a server the
+// first time it is seen, and rejecting any other key for the same address
+// afterwards. Trusted keys are saved to the file, one address and
+// fingerprint per line, so that they are kept across restarts.
+// A key changed on purpose is trusted again after removing its line.
+func TrustOnFirstUse(file string) PinVerifier {
+	var mu sync.Mutex
+
+	return func(addr, fingerprint string) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		known, err := readKnownKeys(file)
+		if err != nil {
+			return err
+		}
+
+		if pinned, ok := known[addr]; ok {
+			if pinned != fingerprint {
+				return ErrPinMismatch
+			}
+			return nil
+		}
+
+		f, err := os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			return fmt.Errorf("failed to save trusted key: %v", err)
+		}
+		defer f.Close()
+
+		if _, err := fmt.Fprintf(f, "%s %s\n", addr, fingerprint); err != nil {
+			return fmt.Errorf("failed to save trusted key: %v", err)
+		}
+		return nil
+	}
+}
+
+// readKnownKeys reads the trusted keys saved by TrustOnFirstUse.
+func readKnownKeys(file string) (map[string]string, error) {
+	known := map[string]string{}
+
+	f, err := os.Open(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return known, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trusted keys: %v", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		known[fields[0]] = fields[1]
+	}
+
+	return known, scanner.Err()
+}
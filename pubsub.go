@@ -8,27 +8,77 @@ import (
 	"github.com/srand/mqc/serialization"
 )
 
-type PubSubClient[T any] interface {
+// Publisher publishes messages to the topic of a pub-sub method.
+type Publisher[T any] interface {
+	// Send publishes a message to all subscribers of the topic.
+	Send(ctx context.Context, msg *T) error
+
+	// Close stops publishing to the topic.
+	Close() error
+}
+
+// Subscriber receives the messages published to the topic of a pub-sub method.
+type Subscriber[T any] interface {
+	// Recv receives the next message published to the topic.
+	// When the subscription has ended, Recv returns io.EOF.
 	Recv(ctx context.Context) (*T, error)
-	Send(ctx context.Context, req *T) error
+
+	// Close cancels the subscription.
+	Close() error
+}
+
+type publisherImpl[T any] struct {
+	call       Conn
+	serializer serialization.Serializer
 }
 
-type pubsubImpl[T any] struct {
+type subscriberImpl[T any] struct {
 	call       Conn
 	serializer serialization.Serializer
 	eof        bool
 }
 
-func NewPubSubClient[T any](ctx context.Context, transport Transport, method *Method) (PubSubClient[T], error) {
-	call, err := transport.Invoke(ctx, method)
+// NewPublisher creates a publisher for a pub-sub method.
+func NewPublisher[T any](ctx context.Context, conn ClientConn, method *Method) (Publisher[T], error) {
+	if !method.IsPublisher() {
+		return nil, ErrProtocolViolation
+	}
+
+	call, err := conn.Invoke(ctx, method)
+	if err != nil {
+		return nil, err
+	}
+
+	return &publisherImpl[T]{call: call, serializer: methodSerializer(conn, method)}, nil
+}
+
+// NewSubscriber subscribes to the topic of a pub-sub method.
+func NewSubscriber[T any](ctx context.Context, conn ClientConn, method *Method) (Subscriber[T], error) {
+	if !method.IsConsumer() {
+		return nil, ErrProtocolViolation
+	}
+
+	call, err := conn.Invoke(ctx, method)
 	if err != nil {
 		return nil, err
 	}
 
-	return &pubsubImpl[T]{call: call, serializer: transport.Serializer()}, nil
+	return &subscriberImpl[T]{call: call, serializer: methodSerializer(conn, method)}, nil
+}
+
+func (s *publisherImpl[T]) Send(ctx context.Context, msg *T) error {
+	data, err := marshal[T](s.serializer, msg)
+	if err != nil {
+		return err
+	}
+	return s.call.Send(ctx, data)
 }
 
-func (s *pubsubImpl[T]) Recv(ctx context.Context) (*T, error) {
+func (s *publisherImpl[T]) Close() error {
+	return s.call.Close()
+}
+
+func (s *subscriberImpl[T]) Recv(ctx context.Context) (*T, error) {
 	if s.eof {
 		return nil, io.EOF
 	}
@@ -46,13 +96,6 @@ func (s *pubsubImpl[T]) Recv(ctx context.Context) (*T, error) {
 	return unmarshal[T](s.serializer, data)
 }
 
-func (s *pubsubImpl[T]) Send(ctx context.Context, req *T) error {
-	data, err := marshal[T](s.serializer, req)
-	if err != nil {
-		return err
-	}
-	if err := s.call.Send(ctx, data); err != nil {
-		return err
-	}
-	return nil
+func (s *subscriberImpl[T]) Close() error {
+	return s.call.Close()
 }
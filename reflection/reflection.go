@@ -0,0 +1,146 @@
+//go:generate protoc -I. --go_out=. --go_opt=module=github.com/srand/mqc/reflection --go-mqc_out=. --go-mqc_opt=module=github.com/srand/mqc/reflection mqc/reflection.proto
+
+// Package reflection implements a service describing the methods served
+// by a transport, so that generic tools can call them without the schema.
+//
+// Register the service on a server transport after the other services:
+//
+//	RegisterGreeterServer(transport, &greeter{})
+//	reflection.Register(transport)
+//
+// On an mqc.Server, RegisterServer describes the services on every transport.
+//
+// Message types and schema files are reported for services generated by
+// protoc-gen-go-mqc, which registers their descriptions with mqc.
+package reflection
+
+import (
+	"context"
+	"sort"
+
+	"github.com/srand/mqc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+var (
+	// ErrNotFound indicates that a requested symbol or file is unknown.
+	ErrNotFound = &mqc.Error{Message: "not found"}
+)
+
+type server struct {
+	transport mqc.Transport
+	files     *protoregistry.Files
+}
+
+// Register registers the reflection service on a transport.
+func Register(transport mqc.Transport) error {
+	return RegisterServerReflectionServer(transport, NewServer(transport, protoregistry.GlobalFiles))
+}
+
+// RegisterServer registers the reflection service on all transports of a server.
+func RegisterServer(s *mqc.Server) error {
+	return s.RegisterService("ServerReflection", Register)
+}
+
+// NewServer creates a reflection server describing the methods registered on
+// a transport. Schema files are resolved from files.
+func NewServer(transport mqc.Transport, files *protoregistry.Files) ServerReflectionServer {
+	return &server{transport: transport, files: files}
+}
+
+func (s *server) ListServices(ctx context.Context, req *ListServicesRequest) (*ListServicesResponse, error) {
+	services := map[string]*ServiceInfo{}
+
+	for _, method := range s.transport.Methods() {
+		info, ok := services[method.Service]
+		if !ok {
+			info = &ServiceInfo{Name: method.Service}
+			if desc := mqc.GetService(method.Service); desc != nil {
+				info.FullName = desc.FullName
+				info.File = desc.File
+			}
+			services[method.Service] = info
+		}
+
+		methodInfo := &MethodInfo{Name: method.Name, Type: int32(method.Type)}
+		if desc := mqc.GetService(method.Service); desc != nil {
+			if methodDesc := desc.Method(method.Name); methodDesc != nil {
+				methodInfo.InputType = methodDesc.Input
+				methodInfo.OutputType = methodDesc.Output
+			}
+		}
+		info.Methods = append(info.Methods, methodInfo)
+	}
+
+	res := &ListServicesResponse{}
+	for _, info := range services {
+		sort.Slice(info.Methods, func(i, j int) bool {
+			return info.Methods[i].Name < info.Methods[j].Name
+		})
+		res.Services = append(res.Services, info)
+	}
+	sort.Slice(res.Services, func(i, j int) bool {
+		return res.Services[i].Name < res.Services[j].Name
+	})
+
+	return res, nil
+}
+
+func (s *server) FileContainingSymbol(ctx context.Context, req *FileContainingSymbolRequest) (*FileDescriptorResponse, error) {
+	name := req.Symbol
+	if desc := mqc.GetService(name); desc != nil {
+		name = desc.FullName
+	}
+
+	desc, err := s.files.FindDescriptorByName(protoreflect.FullName(name))
+	if err != nil {
+		return nil, ErrNotFound
+	}
+
+	return fileDescriptorResponse(desc.ParentFile())
+}
+
+func (s *server) FileByFilename(ctx context.Context, req *FileByFilenameRequest) (*FileDescriptorResponse, error) {
+	file, err := s.files.FindFileByPath(req.Filename)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+
+	return fileDescriptorResponse(file)
+}
+
+// fileDescriptorResponse returns a file followed by its transitive dependencies.
+func fileDescriptorResponse(file protoreflect.FileDescriptor) (*FileDescriptorResponse, error) {
+	res := &FileDescriptorResponse{}
+	seen := map[string]bool{}
+
+	var add func(file protoreflect.FileDescriptor) error
+	add = func(file protoreflect.FileDescriptor) error {
+		if seen[file.Path()] {
+			return nil
+		}
+		seen[file.Path()] = true
+
+		data, err := proto.Marshal(protodesc.ToFileDescriptorProto(file))
+		if err != nil {
+			return err
+		}
+		res.FileDescriptorProto = append(res.FileDescriptorProto, data)
+
+		imports := file.Imports()
+		for i := 0; i < imports.Len(); i++ {
+			if err := add(imports.Get(i).FileDescriptor); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := add(file); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
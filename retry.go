@@ -0,0 +1,304 @@
+package mqc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// RetryPolicy configures the automatic retries of unary and server-streaming
+// calls by WithRetry.
+//
+// A call failing before the server accepted it, e.g. because the connection
+// to the broker was lost before the INVOKE was acknowledged, is retried
+// unless the server rejected the call. Once accepted, a call is retried only
+// if its method is idempotent, it failed with one of the RetryableErrors,
+// and no response was received yet.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts of a call, including the
+	// first one. Calls are not retried if it is below 2.
+	MaxAttempts int
+
+	// InitialBackoff is the delay before the first retry.
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the delay between attempts. Zero means no cap.
+	MaxBackoff time.Duration
+
+	// BackoffMultiplier multiplies the delay after every retry.
+	// Zero means 2. Delays are randomized between half and the full delay.
+	BackoffMultiplier float64
+
+	// RetryableErrors are the errors calls are retried on, matched with
+	// errors.Is or, for errors received from the server, by their message.
+	// Nil means ErrUnavailable.
+	RetryableErrors []error
+}
+
+// rejections are the errors with which servers reject calls.
+var rejections = []error{
+	ErrUnauthenticated,
+	ErrPermissionDenied,
+	ErrUnimplemented,
+	ErrUnavailable,
+	ErrInternal,
+	ErrProtocolViolation,
+	ErrUnsupportedProtocol,
+}
+
+// matches reports whether an error is one of the errors, possibly received
+// from the server with only its message.
+func matches(err error, errs []error) bool {
+	for _, e := range errs {
+		if errors.Is(err, e) || err.Error() == e.Error() {
+			return true
+		}
+	}
+	return false
+}
+
+// retryable reports whether a failed attempt may be retried. Failures of
+// accepted calls are retried on the retryable errors only, as they may be
+// errors of the handler.
+func (p *RetryPolicy) retryable(err error, accepted bool) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	retryable := p.RetryableErrors
+	if retryable == nil {
+		retryable = []error{ErrUnavailable}
+	}
+	if matches(err, retryable) {
+		return true
+	}
+
+	var mqcErr *Error
+	return !accepted && !errors.As(err, &mqcErr) && !matches(err, rejections)
+}
+
+// backoff returns the delay before a retry, counting from 1.
+func (p *RetryPolicy) backoff(retry int) time.Duration {
+	multiplier := p.BackoffMultiplier
+	if multiplier == 0 {
+		multiplier = 2
+	}
+
+	delay := float64(p.InitialBackoff) * math.Pow(multiplier, float64(retry-1))
+	if p.MaxBackoff > 0 && delay > float64(p.MaxBackoff) {
+		delay = float64(p.MaxBackoff)
+	}
+	return time.Duration(delay/2 + rand.Float64()*delay/2)
+}
+
+// wait waits before a retry until the context is done.
+func (p *RetryPolicy) wait(ctx context.Context, retry int) error {
+	timer := time.NewTimer(p.backoff(retry))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// retryClientConn retries the calls of a client connection.
+type retryClientConn struct {
+	ClientConn
+	policy RetryPolicy
+}
+
+// WithRetry returns a client connection retrying the failed unary and
+// server-streaming calls of conn according to the policy. Other calls are
+// passed through.
+//
+//	conn := mqc.WithRetry(transport, mqc.RetryPolicy{
+//		MaxAttempts:    3,
+//		InitialBackoff: 100 * time.Millisecond,
+//		MaxBackoff:     time.Second,
+//	})
+//	client := NewGreeterClient(conn)
+func WithRetry(conn ClientConn, policy RetryPolicy) ClientConn {
+	return &retryClientConn{ClientConn: conn, policy: policy}
+}
+
+func (c *retryClientConn) Invoke(ctx context.Context, method *Method) (Conn, error) {
+	if c.policy.MaxAttempts < 2 || !(method.IsUnary() || method.IsServerStream()) {
+		return c.ClientConn.Invoke(ctx, method)
+	}
+
+	call := &retryConn{client: c.ClientConn, policy: &c.policy, method: method}
+	if options := GetMethodOptions(method); options != nil {
+		call.idempotent = options.Idempotent
+	}
+
+	conn, err := c.ClientConn.Invoke(ctx, method)
+	call.attempts = 1
+	if err != nil {
+		if !c.policy.retryable(err, false) {
+			return nil, err
+		}
+		if conn, err = call.attempt(ctx, err); err != nil {
+			return nil, err
+		}
+	}
+
+	call.conn = conn
+	return call, nil
+}
+
+// CallTimeout returns the call timeout of the wrapped connection, which
+// applies to all attempts of a call.
+func (c *retryClientConn) CallTimeout() time.Duration {
+	if t, ok := c.ClientConn.(interface{ CallTimeout() time.Duration }); ok {
+		return t.CallTimeout()
+	}
+	return 0
+}
+
+// retryConn is a call replaying its requests to a new attempt when it fails.
+// Only the requests sent before the first response are replayed, as calls
+// are not retried once a response was received.
+type retryConn struct {
+	client     ClientConn
+	policy     *RetryPolicy
+	method     *Method
+	idempotent bool
+
+	// attempts counts the invoked attempts, and sent and sendClosed record
+	// the requests to replay
+	attempts   int
+	sent       [][]byte
+	sendClosed bool
+	received   bool
+
+	// mu guards the connection of the current attempt, which may be closed
+	// concurrently
+	mu     sync.Mutex
+	conn   Conn
+	closed bool
+}
+
+// attempt invokes new attempts after a failure until one succeeds, replaying
+// the requests sent so far. It returns the last error if the call cannot be
+// retried anymore.
+func (c *retryConn) attempt(ctx context.Context, err error) (Conn, error) {
+	for c.attempts < c.policy.MaxAttempts {
+		if c.policy.wait(ctx, c.attempts) != nil {
+			return nil, err
+		}
+		c.attempts++
+
+		var conn Conn
+		if conn, err = c.client.Invoke(ctx, c.method); err != nil {
+			if !c.policy.retryable(err, false) {
+				return nil, err
+			}
+			continue
+		}
+
+		if err = c.replay(ctx, conn); err != nil {
+			conn.Close()
+			if !c.policy.retryable(err, true) {
+				return nil, err
+			}
+			continue
+		}
+		return conn, nil
+	}
+	return nil, err
+}
+
+// replay sends the requests sent so far to a new attempt.
+func (c *retryConn) replay(ctx context.Context, conn Conn) error {
+	for _, data := range c.sent {
+		if err := conn.Send(ctx, data); err != nil {
+			return err
+		}
+	}
+	if c.sendClosed {
+		return conn.SendClose(ctx)
+	}
+	return nil
+}
+
+// retry replaces the connection of a failed accepted attempt, returning the
+// error to report if the call cannot be retried.
+func (c *retryConn) retry(ctx context.Context, err error) error {
+	if c.received || !c.idempotent || !c.policy.retryable(err, true) {
+		return err
+	}
+
+	conn, err := c.attempt(ctx, err)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		conn.Close()
+		return io.ErrClosedPipe
+	}
+	c.conn.Close()
+	c.conn = conn
+	return nil
+}
+
+func (c *retryConn) current() Conn {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn
+}
+
+func (c *retryConn) Send(ctx context.Context, data []byte) error {
+	if !c.received {
+		c.sent = append(c.sent, data)
+	}
+
+	err := c.current().Send(ctx, data)
+	if err != nil {
+		return c.retry(ctx, err)
+	}
+	return nil
+}
+
+func (c *retryConn) SendClose(ctx context.Context) error {
+	c.sendClosed = true
+
+	err := c.current().SendClose(ctx)
+	if err != nil {
+		return c.retry(ctx, err)
+	}
+	return nil
+}
+
+func (c *retryConn) Recv(ctx context.Context) ([]byte, error) {
+	for {
+		data, err := c.current().Recv(ctx)
+		if err == nil {
+			c.received = true
+			c.sent = nil
+			return data, nil
+		}
+		if err == io.EOF {
+			return nil, err
+		}
+		if err := c.retry(ctx, err); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (c *retryConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	return c.conn.Close()
+}
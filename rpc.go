@@ -8,7 +8,7 @@ import (
 
 // Rpc performs a remote procedure call to the specified method with the given request.
 // It sends the request and waits for a response, returning the response object or an error.
-func Rpc[Req any, Res any](ctx context.Context, transport Transport, method *Method, req *Req) (*Res, error) {
+func Rpc[Req any, Res any](ctx context.Context, conn ClientConn, method *Method, req *Req) (*Res, error) {
 	if ctx == nil {
 		ctx = context.Background()
 	}
@@ -17,10 +17,13 @@ func Rpc[Req any, Res any](ctx context.Context, transport Transport, method *Met
 		return nil, ErrNilRequest
 	}
 
-	serializer := transport.Serializer()
+	ctx, cancel := withCallTimeout(ctx, conn, method)
+	defer cancel()
+
+	serializer := methodSerializer(conn, method)
 
 	// Create a new connection for the RPC call
-	stream, err := transport.Invoke(ctx, method)
+	stream, err := conn.Invoke(ctx, method)
 	if err != nil {
 		return nil, err
 	}
@@ -56,8 +59,8 @@ func Rpc[Req any, Res any](ctx context.Context, transport Transport, method *Met
 // RpcServer handles an incoming RPC call on the server side.
 // It receives the request, processes it using the provided handler function,
 // and sends back the response or an error.
-func RpcServer[Req any, Res any](conn Conn, serializer serialization.Serializer, handler func(req *Req) (*Res, error)) error {
-	ctx := context.Background()
+func RpcServer[Req any, Res any](ctx context.Context, conn Conn, serializer serialization.Serializer, handler func(ctx context.Context, req *Req) (*Res, error)) error {
+	serializer = connSerializer(serializer, conn)
 
 	// Receive request
 	data, err := conn.Recv(ctx)
@@ -73,7 +76,7 @@ func RpcServer[Req any, Res any](conn Conn, serializer serialization.Serializer,
 	}
 
 	// Handle request
-	res, err := handler(&req)
+	res, err := handler(ctx, &req)
 	if err != nil {
 		return err
 	}
@@ -1,7 +1,11 @@
 package serialization
 
 var (
-	ErrInvalidMessage = &SerializationError{Msg: "invalid message"}
+	ErrInvalidMessage     = &SerializationError{Msg: "invalid message"}
+	ErrFrameTooLarge      = &SerializationError{Msg: "frame too large"}
+	ErrInvalidFrame       = &SerializationError{Msg: "invalid frame"}
+	ErrUnsupportedVersion = &SerializationError{Msg: "unsupported frame version"}
+	ErrVersionMismatch    = &SerializationError{Msg: "frame version does not match the negotiated version"}
 )
 
 type SerializationError struct {
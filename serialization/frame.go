@@ -0,0 +1,371 @@
+package serialization
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"io"
+	"sync"
+)
+
+// Frame layout on the wire:
+//
+//	+-----------------+-------+------+----------------+----------------------+
+//	| magic | version | flags | type | varint length  | payload              |
+//	+-----------------+-------+------+----------------+----------------------+
+//	   1 byte           1 byte  1 byte  1-10 bytes       length bytes
+//
+// The high nibble of the first byte is a fixed magic value and the low
+// nibble the frame format version. If FlagMetadata is set, the payload
+// starts with a varint length followed by that many bytes of metadata.
+// If FlagCompressed is set, metadata and payload are DEFLATE compressed.
+
+const (
+	// FrameVersion is the newest frame format version supported.
+	FrameVersion = 1
+
+	// MinFrameVersion is the oldest frame format version supported.
+	MinFrameVersion = 1
+
+	// MaxFrameSize is the largest frame payload accepted by a FrameReader.
+	MaxFrameSize = 64 << 20
+
+	frameMagic      = 0xA0
+	frameMagicMask  = 0xF0
+	frameHeaderSize = 3 + binary.MaxVarintLen64
+
+	// maxPooledBufferSize is the largest buffer returned to the pool.
+	// Larger buffers are left to the garbage collector so that a single
+	// oversized message does not pin memory for the lifetime of the process.
+	maxPooledBufferSize = 1 << 20
+)
+
+// FrameFlags are the per-frame flag bits.
+type FrameFlags byte
+
+const (
+	// FlagCompressed indicates that the frame body is DEFLATE compressed.
+	FlagCompressed FrameFlags = 1 << iota
+	// FlagEndStream indicates that the sender will not send further frames.
+	FlagEndStream
+	// FlagMetadata indicates that the frame carries a metadata block.
+	FlagMetadata
+)
+
+// Has reports whether all bits in flag are set.
+func (f FrameFlags) Has(flag FrameFlags) bool {
+	return f&flag == flag
+}
+
+// Frame is a single unit on a framed stream.
+type Frame struct {
+	// Version is the frame format version. Set by FrameReader.
+	Version byte
+	// Type identifies the frame contents. Opaque to the framing layer.
+	Type byte
+	// Flags holds the frame flags. FlagMetadata is derived from Metadata when writing.
+	Flags FrameFlags
+	// Metadata is an optional block of out-of-band data.
+	Metadata []byte
+	// Payload is the frame body.
+	Payload []byte
+}
+
+var framePreface = [3]byte{'M', 'Q', 'C'}
+
+// minReadVersion and maxReadVersion bound the versions of the frames read,
+// and are only changed by tests to read frames of other versions.
+var minReadVersion, maxReadVersion byte = MinFrameVersion, FrameVersion
+
+var bufferPool = sync.Pool{
+	New: func() any {
+		buf := make([]byte, 0, 4096)
+		return &buf
+	},
+}
+
+func getBuffer() *[]byte {
+	return bufferPool.Get().(*[]byte)
+}
+
+func putBuffer(buf *[]byte) {
+	if cap(*buf) > maxPooledBufferSize {
+		return
+	}
+	*buf = (*buf)[:0]
+	bufferPool.Put(buf)
+}
+
+// WritePreface writes the connection preface announcing the given frame version.
+// Framed transports exchange prefaces before any frames to negotiate a version.
+func WritePreface(w io.Writer, version byte) error {
+	_, err := w.Write(append(framePreface[:], version))
+	return err
+}
+
+// ReadPreface reads a connection preface and returns the announced frame version.
+func ReadPreface(r io.Reader) (byte, error) {
+	var preface [len(framePreface) + 1]byte
+	if _, err := io.ReadFull(r, preface[:]); err != nil {
+		return 0, err
+	}
+	if !bytes.Equal(preface[:len(framePreface)], framePreface[:]) {
+		return 0, ErrInvalidFrame
+	}
+	return preface[len(framePreface)], nil
+}
+
+// FrameWriter writes frames to an underlying writer.
+// Each frame is assembled in a pooled buffer and handed to the writer
+// in a single Write call, so concurrent writers never interleave frames
+// as long as the underlying writer serializes individual writes.
+type FrameWriter struct {
+	writer  io.Writer
+	version byte
+}
+
+// NewFrameWriter creates a new FrameWriter writing frames of the newest
+// version to w.
+func NewFrameWriter(w io.Writer) *FrameWriter {
+	return NewVersionedFrameWriter(w, FrameVersion)
+}
+
+// NewVersionedFrameWriter creates a new FrameWriter writing frames of the
+// given version to w, e.g. the version negotiated with the peer.
+func NewVersionedFrameWriter(w io.Writer, version byte) *FrameWriter {
+	return &FrameWriter{writer: w, version: version}
+}
+
+// WriteFrame writes a single frame.
+// If FlagCompressed is set, the frame body is compressed before writing.
+func (w *FrameWriter) WriteFrame(frame *Frame) error {
+	return w.WriteFrameFunc(frame, func(buf []byte) ([]byte, error) {
+		return append(buf, frame.Payload...), nil
+	})
+}
+
+// WriteFrameFunc writes a single frame whose payload is produced by appendPayload
+// instead of frame.Payload. The function receives a pooled buffer and must return
+// it with the payload appended, which lets serializers marshal directly into the
+// frame without an intermediate copy.
+func (w *FrameWriter) WriteFrameFunc(frame *Frame, appendPayload func(buf []byte) ([]byte, error)) error {
+	bufp := getBuffer()
+	defer putBuffer(bufp)
+
+	flags := frame.Flags &^ FlagMetadata
+	if len(frame.Metadata) > 0 {
+		flags |= FlagMetadata
+	}
+
+	// The body is built after a gap large enough for the largest header,
+	// which is then written right in front of it.
+	buf := append((*bufp)[:0], make([]byte, frameHeaderSize)...)
+	if flags.Has(FlagMetadata) {
+		buf = binary.AppendUvarint(buf, uint64(len(frame.Metadata)))
+		buf = append(buf, frame.Metadata...)
+	}
+	buf, err := appendPayload(buf)
+	if err != nil {
+		return err
+	}
+	*bufp = buf
+
+	body := buf[frameHeaderSize:]
+	if flags.Has(FlagCompressed) {
+		compressed := getBuffer()
+		defer putBuffer(compressed)
+
+		*compressed = append((*compressed)[:0], make([]byte, frameHeaderSize)...)
+		*compressed, err = deflate(*compressed, body)
+		if err != nil {
+			return err
+		}
+		buf = *compressed
+		body = buf[frameHeaderSize:]
+	}
+
+	if len(body) > MaxFrameSize {
+		return ErrFrameTooLarge
+	}
+
+	var header [frameHeaderSize]byte
+	header[0] = frameMagic | w.version
+	header[1] = byte(flags)
+	header[2] = frame.Type
+	n := 3 + binary.PutUvarint(header[3:], uint64(len(body)))
+
+	start := frameHeaderSize - n
+	copy(buf[start:], header[:n])
+
+	_, err = w.writer.Write(buf[start:])
+	return err
+}
+
+// FrameReader reads frames from an underlying reader.
+type FrameReader struct {
+	reader  io.Reader
+	version byte
+	header  [3]byte
+	varint  [1]byte
+}
+
+// NewFrameReader creates a new FrameReader reading frames of any supported
+// version from r.
+func NewFrameReader(r io.Reader) *FrameReader {
+	return &FrameReader{reader: r}
+}
+
+// NewVersionedFrameReader creates a new FrameReader reading frames of the
+// given version from r, e.g. the version negotiated with the peer.
+// Frames of other versions are rejected with ErrVersionMismatch.
+func NewVersionedFrameReader(r io.Reader, version byte) *FrameReader {
+	return &FrameReader{reader: r, version: version}
+}
+
+func (r *FrameReader) readHeader(frame *Frame) (int, error) {
+	if _, err := io.ReadFull(r.reader, r.header[:]); err != nil {
+		return 0, err
+	}
+
+	if r.header[0]&frameMagicMask != frameMagic {
+		return 0, ErrInvalidFrame
+	}
+
+	version := r.header[0] &^ frameMagicMask
+	if version < minReadVersion || version > maxReadVersion {
+		return 0, ErrUnsupportedVersion
+	}
+	if r.version != 0 && version != r.version {
+		return 0, ErrVersionMismatch
+	}
+
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return 0, unexpectedEOF(err)
+	}
+	if size > MaxFrameSize {
+		return 0, ErrFrameTooLarge
+	}
+
+	frame.Version = version
+	frame.Flags = FrameFlags(r.header[1])
+	frame.Type = r.header[2]
+	return int(size), nil
+}
+
+// ReadByte implements io.ByteReader for varint decoding of the frame length.
+func (r *FrameReader) ReadByte() (byte, error) {
+	if _, err := io.ReadFull(r.reader, r.varint[:]); err != nil {
+		return 0, err
+	}
+	return r.varint[0], nil
+}
+
+// ReadFrame reads the next frame.
+// Metadata and payload are allocated for the caller and may be retained.
+func (r *FrameReader) ReadFrame() (*Frame, error) {
+	frame := &Frame{}
+
+	size, err := r.readHeader(frame)
+	if err != nil {
+		return nil, err
+	}
+
+	body := make([]byte, size)
+	if _, err := io.ReadFull(r.reader, body); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+
+	if err := frame.decodeBody(body); err != nil {
+		return nil, err
+	}
+	return frame, nil
+}
+
+// ReadFrameFunc reads the next frame into a pooled buffer and passes it to fn.
+// The frame metadata and payload are only valid for the duration of the call
+// and must not be retained.
+func (r *FrameReader) ReadFrameFunc(fn func(frame *Frame) error) error {
+	var frame Frame
+
+	size, err := r.readHeader(&frame)
+	if err != nil {
+		return err
+	}
+
+	bufp := getBuffer()
+	defer putBuffer(bufp)
+
+	if cap(*bufp) < size {
+		*bufp = make([]byte, size)
+	}
+	body := (*bufp)[:size]
+
+	if _, err := io.ReadFull(r.reader, body); err != nil {
+		return unexpectedEOF(err)
+	}
+
+	if err := frame.decodeBody(body); err != nil {
+		return err
+	}
+	return fn(&frame)
+}
+
+func (f *Frame) decodeBody(body []byte) error {
+	if f.Flags.Has(FlagCompressed) {
+		var err error
+		body, err = inflate(body)
+		if err != nil {
+			return err
+		}
+	}
+
+	if f.Flags.Has(FlagMetadata) {
+		size, n := binary.Uvarint(body)
+		if n <= 0 || uint64(len(body)-n) < size {
+			return ErrInvalidFrame
+		}
+		f.Metadata = body[n : n+int(size)]
+		body = body[n+int(size):]
+	}
+
+	f.Payload = body
+	return nil
+}
+
+func deflate(dst, src []byte) ([]byte, error) {
+	buf := bytes.NewBuffer(dst)
+	fw, err := flate.NewWriter(buf, flate.DefaultCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fw.Write(src); err != nil {
+		return nil, err
+	}
+	if err := fw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func inflate(src []byte) ([]byte, error) {
+	fr := flate.NewReader(bytes.NewReader(src))
+	defer fr.Close()
+
+	data, err := io.ReadAll(io.LimitReader(fr, MaxFrameSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxFrameSize {
+		return nil, ErrFrameTooLarge
+	}
+	return data, nil
+}
+
+// A clean EOF is only valid on a frame boundary.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
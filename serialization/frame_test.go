@@ -0,0 +1,42 @@
+package serialization
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Readers pinned to the negotiated version reject frames of other supported
+// versions, e.g. from a peer not downgraded to the negotiated version.
+func TestVersionedFrameReaderRejectsOtherVersions(t *testing.T) {
+	// Support a newer version than the current one
+	defer func(min, max byte) { minReadVersion, maxReadVersion = min, max }(minReadVersion, maxReadVersion)
+	minReadVersion, maxReadVersion = 1, 2
+
+	frame := &Frame{Type: 4, Payload: []byte("hello")}
+
+	for _, versions := range [][2]byte{{1, 2}, {2, 1}} {
+		negotiated, sent := versions[0], versions[1]
+
+		var buf bytes.Buffer
+		require.NoError(t, NewVersionedFrameWriter(&buf, sent).WriteFrame(frame))
+
+		// Both versions are supported by unpinned readers
+		read, err := NewFrameReader(bytes.NewReader(buf.Bytes())).ReadFrame()
+		require.NoError(t, err)
+		assert.Equal(t, sent, read.Version)
+
+		_, err = NewVersionedFrameReader(&buf, negotiated).ReadFrame()
+		assert.Equal(t, ErrVersionMismatch, err)
+	}
+
+	// Frames of the negotiated version are read
+	var buf bytes.Buffer
+	require.NoError(t, NewVersionedFrameWriter(&buf, 2).WriteFrame(frame))
+	read, err := NewVersionedFrameReader(&buf, 2).ReadFrame()
+	require.NoError(t, err)
+	assert.Equal(t, byte(2), read.Version)
+	assert.Equal(t, "hello", string(read.Payload))
+}
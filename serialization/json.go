@@ -7,6 +7,14 @@ import (
 
 type JSONSerializer struct{}
 
+type jsonEncoder struct {
+	writer *FrameWriter
+}
+
+type jsonDecoder struct {
+	reader *FrameReader
+}
+
 func NewJSONSerializer() *JSONSerializer {
 	return &JSONSerializer{}
 }
@@ -20,9 +28,24 @@ func (s *JSONSerializer) Unmarshal(data []byte, v interface{}) error {
 }
 
 func (s *JSONSerializer) NewDecoder(reader io.Reader) Decoder {
-	return json.NewDecoder(reader)
+	return &jsonDecoder{reader: NewFrameReader(reader)}
 }
 
 func (s *JSONSerializer) NewEncoder(writer io.Writer) Encoder {
-	return json.NewEncoder(writer)
+	return &jsonEncoder{writer: NewFrameWriter(writer)}
+}
+
+func (e *jsonEncoder) Encode(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return e.writer.WriteFrame(&Frame{Payload: data})
+}
+
+func (d *jsonDecoder) Decode(v any) error {
+	// json.Unmarshal copies what it keeps, so the pooled frame buffer can be reused
+	return d.reader.ReadFrameFunc(func(frame *Frame) error {
+		return json.Unmarshal(frame.Payload, v)
+	})
 }
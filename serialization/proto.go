@@ -1,7 +1,6 @@
 package serialization
 
 import (
-	"encoding/binary"
 	"io"
 
 	"google.golang.org/protobuf/proto"
@@ -10,11 +9,11 @@ import (
 type protoSerializer struct{}
 
 type protoEncoder struct {
-	writer io.Writer
+	writer *FrameWriter
 }
 
 type protoDecoder struct {
-	reader io.Reader
+	reader *FrameReader
 }
 
 var _ Serializer = (*protoSerializer)(nil)
@@ -40,11 +39,11 @@ func (s *protoSerializer) Unmarshal(data []byte, v any) error {
 }
 
 func (s *protoSerializer) NewDecoder(reader io.Reader) Decoder {
-	return &protoDecoder{reader: reader}
+	return &protoDecoder{reader: NewFrameReader(reader)}
 }
 
 func (s *protoSerializer) NewEncoder(writer io.Writer) Encoder {
-	return &protoEncoder{writer: writer}
+	return &protoEncoder{writer: NewFrameWriter(writer)}
 }
 
 func (e *protoEncoder) Encode(v any) error {
@@ -53,21 +52,10 @@ func (e *protoEncoder) Encode(v any) error {
 		return ErrInvalidMessage
 	}
 
-	// Marshal the message
-	data, err := proto.Marshal(msg)
-	if err != nil {
-		return err
-	}
-
-	size := make([]byte, 4)
-	binary.LittleEndian.PutUint32(size, uint32(len(data)))
-
-	if _, err := e.writer.Write(size); err != nil {
-		return err
-	}
-
-	_, err = e.writer.Write(data)
-	return err
+	// Marshal the message directly into the frame buffer
+	return e.writer.WriteFrameFunc(&Frame{}, func(buf []byte) ([]byte, error) {
+		return proto.MarshalOptions{}.MarshalAppend(buf, msg)
+	})
 }
 
 func (d *protoDecoder) Decode(v any) error {
@@ -76,17 +64,8 @@ func (d *protoDecoder) Decode(v any) error {
 		return ErrInvalidMessage
 	}
 
-	// Read the size prefix
-	sizeBuf := make([]byte, 4)
-	if _, err := io.ReadFull(d.reader, sizeBuf); err != nil {
-		return err
-	}
-	size := binary.LittleEndian.Uint32(sizeBuf)
-
-	// Read the message data
-	data := make([]byte, size)
-	if _, err := io.ReadFull(d.reader, data); err != nil {
-		return err
-	}
-	return proto.Unmarshal(data, msg)
+	// proto.Unmarshal copies bytes fields, so the pooled frame buffer can be reused
+	return d.reader.ReadFrameFunc(func(frame *Frame) error {
+		return proto.Unmarshal(frame.Payload, msg)
+	})
 }
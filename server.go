@@ -0,0 +1,347 @@
+package mqc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Interceptor intercepts the incoming calls of a server, e.g. to log or
+// validate them. It continues the call by calling handler.
+type Interceptor func(ctx context.Context, method *Method, conn Conn, handler MethodHandler) error
+
+// ServerOption configures a Server.
+type ServerOption func(*Server)
+
+// WithInterceptors adds interceptors to the calls of the server.
+// The first interceptor is the outermost.
+func WithInterceptors(interceptors ...Interceptor) ServerOption {
+	return func(s *Server) {
+		s.interceptors = append(s.interceptors, interceptors...)
+	}
+}
+
+// Server serves a set of services on several transports at once,
+// e.g. tcp, websocket and MQTT, with one lifecycle.
+//
+//	server := mqc.NewServer()
+//	server.AddTransport(tcpTransport)
+//	server.AddTransport(mqttTransport)
+//	RegisterGreeterService(server, &greeter{})
+//	go server.Serve()
+//	...
+//	server.Shutdown(ctx)
+type Server struct {
+	mu           sync.Mutex
+	interceptors []Interceptor
+	transports   []Transport
+	services     map[string]*serverService
+	onShutdown   []func()
+	serving      bool
+	shutdown     bool
+
+	// done is closed on shutdown, and errs receives the first error of a transport
+	done chan struct{}
+	errs chan error
+
+	// serves counts the running transports, and calls the calls being handled
+	serves sync.WaitGroup
+	calls  sync.WaitGroup
+}
+
+// serverService is a service registered on a server.
+type serverService struct {
+	name     string
+	register func(Transport) error
+	methods  map[Method]struct{}
+}
+
+// NewServer creates a server without transports or services.
+func NewServer(options ...ServerOption) *Server {
+	s := &Server{
+		services: make(map[string]*serverService),
+		done:     make(chan struct{}),
+		errs:     make(chan error, 1),
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// AddTransport serves the services of the server on a transport. Transports
+// added to a serving server start serving right away. The server closes its
+// transports on shutdown. The transport is not added if a service fails to
+// register on it.
+func (s *Server) AddTransport(transport Transport) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.shutdown {
+		return ErrUnavailable
+	}
+
+	var registered []*serverService
+	for _, service := range s.services {
+		registered = append(registered, service)
+		if err := service.register(s.wrap(transport, service)); err != nil {
+			for _, service := range registered {
+				service.unregister(transport)
+			}
+			return fmt.Errorf("failed to register service %s: %w", service.name, err)
+		}
+	}
+
+	s.transports = append(s.transports, transport)
+	if s.serving {
+		s.serve(transport)
+	}
+	return nil
+}
+
+// RegisterService registers a service on all transports of the server,
+// including transports added later. The register function registers the
+// handlers of the service on a transport, e.g. a generated
+// Register<Service>Server function. Generated Register<Service>Service
+// functions call RegisterService with the service name.
+// The service is removed from all transports if it fails to register on one.
+func (s *Server) RegisterService(name string, register func(Transport) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.shutdown {
+		return ErrUnavailable
+	}
+	if _, ok := s.services[name]; ok {
+		return fmt.Errorf("service %s is already registered", name)
+	}
+
+	service := &serverService{
+		name:     name,
+		register: register,
+		methods:  make(map[Method]struct{}),
+	}
+
+	for i, transport := range s.transports {
+		if err := register(s.wrap(transport, service)); err != nil {
+			for _, transport := range s.transports[:i+1] {
+				service.unregister(transport)
+			}
+			return fmt.Errorf("failed to register service %s: %w", name, err)
+		}
+	}
+
+	s.services[name] = service
+	return nil
+}
+
+// UnregisterService removes a service from all transports of the server.
+// Ongoing calls of the service continue.
+func (s *Server) UnregisterService(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	service, ok := s.services[name]
+	if !ok {
+		return nil
+	}
+	delete(s.services, name)
+
+	var errs []error
+	for _, transport := range s.transports {
+		if err := service.unregister(transport); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// unregister removes the handlers of the service from a transport.
+func (s *serverService) unregister(transport Transport) error {
+	var errs []error
+	for method := range s.methods {
+		if err := transport.UnregisterHandler(&method); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Services returns the descriptions of the services of the server, sorted by
+// name. Services without a generated description are described by the names
+// and types of their methods.
+func (s *Server) Services() []*ServiceDesc {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make([]*ServiceDesc, 0, len(s.services))
+	for _, service := range s.services {
+		if desc := GetService(service.name); desc != nil {
+			result = append(result, desc)
+			continue
+		}
+
+		desc := &ServiceDesc{Name: service.name}
+		for method := range service.methods {
+			desc.Methods = append(desc.Methods, MethodDesc{Name: method.Name, Type: method.Type})
+		}
+		sort.Slice(desc.Methods, func(i, j int) bool {
+			return desc.Methods[i].Name < desc.Methods[j].Name
+		})
+		result = append(result, desc)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// OnShutdown registers a function called when the server starts shutting
+// down, before ongoing calls are awaited, e.g. to report the server as not
+// serving to health checks.
+func (s *Server) OnShutdown(f func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onShutdown = append(s.onShutdown, f)
+}
+
+// Serve serves on all transports until the server is shut down, and returns
+// nil after Shutdown. If a transport fails, the server is shut down without
+// waiting for ongoing calls and Serve returns the error.
+func (s *Server) Serve() error {
+	s.mu.Lock()
+	if s.shutdown {
+		s.mu.Unlock()
+		return ErrUnavailable
+	}
+	if s.serving {
+		s.mu.Unlock()
+		return fmt.Errorf("server is already serving")
+	}
+	s.serving = true
+	for _, transport := range s.transports {
+		s.serve(transport)
+	}
+	s.mu.Unlock()
+
+	select {
+	case err := <-s.errs:
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		s.Shutdown(ctx)
+		return err
+	case <-s.done:
+		s.serves.Wait()
+		return nil
+	}
+}
+
+// serve serves on a transport until it is closed.
+// It must be called with the mutex held.
+func (s *Server) serve(transport Transport) {
+	s.serves.Add(1)
+	go func() {
+		defer s.serves.Done()
+
+		if err := transport.Serve(); err != nil {
+			select {
+			case <-s.done:
+			case s.errs <- err:
+			default:
+			}
+		}
+	}()
+}
+
+// Shutdown stops the server gracefully: new calls fail with ErrUnavailable,
+// ongoing calls are awaited until the context is done, and the transports
+// are closed. It returns the context error if calls were still ongoing.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	if s.shutdown {
+		s.mu.Unlock()
+		return nil
+	}
+	s.shutdown = true
+	close(s.done)
+	hooks := s.onShutdown
+	transports := s.transports
+	s.mu.Unlock()
+
+	for _, hook := range hooks {
+		hook()
+	}
+
+	idle := make(chan struct{})
+	go func() {
+		s.calls.Wait()
+		close(idle)
+	}()
+
+	var err error
+	select {
+	case <-idle:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	for _, transport := range transports {
+		transport.Close()
+	}
+	return err
+}
+
+// beginCall records the start of a call unless the server is shutting down.
+func (s *Server) beginCall() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.shutdown {
+		return false
+	}
+	s.calls.Add(1)
+	return true
+}
+
+// handler wraps the handler of a method with the interceptors of the server.
+func (s *Server) handler(method *Method, handler MethodHandler) MethodHandler {
+	for i := len(s.interceptors) - 1; i >= 0; i-- {
+		interceptor, next := s.interceptors[i], handler
+		handler = func(ctx context.Context, conn Conn) error {
+			return interceptor(ctx, method, conn, next)
+		}
+	}
+
+	return func(ctx context.Context, conn Conn) error {
+		if !s.beginCall() {
+			return ErrUnavailable
+		}
+		defer s.calls.Done()
+		return handler(ctx, conn)
+	}
+}
+
+// wrap returns the transport as seen by the register function of a service.
+// It must be called with the mutex held.
+func (s *Server) wrap(transport Transport, service *serverService) Transport {
+	return &serverTransport{Transport: transport, server: s, service: service}
+}
+
+// serverTransport registers the handlers of a service with the
+// interceptors of the server and records the methods of the service.
+type serverTransport struct {
+	Transport
+	server  *Server
+	service *serverService
+}
+
+func (t *serverTransport) RegisterHandler(method *Method, handler MethodHandler) error {
+	if handler == nil {
+		return fmt.Errorf("handler cannot be nil")
+	}
+
+	t.service.methods[*method] = struct{}{}
+	return t.Transport.RegisterHandler(method, t.server.handler(method, handler))
+}
@@ -0,0 +1,85 @@
+package mqc
+
+import (
+	"sort"
+	"sync"
+)
+
+// ServiceDesc describes a service as declared in its schema.
+// Generated code registers the description of every service,
+// which lets the reflection service report message types and
+// schema files for the methods registered on a transport.
+type ServiceDesc struct {
+	// Name is the service name used in method names.
+	Name string
+
+	// FullName is the fully qualified schema name of the service.
+	FullName string
+
+	// File is the path of the schema file declaring the service.
+	File string
+
+	// Methods describes the methods of the service.
+	Methods []MethodDesc
+}
+
+// MethodDesc describes a method as declared in its schema.
+type MethodDesc struct {
+	// Name is the name of the method within the service.
+	Name string
+
+	// Type is the method type, one of the MethodType constants.
+	// Pub-sub methods are described by MethodTypePublisher.
+	Type int
+
+	// Input is the fully qualified schema name of the request message.
+	Input string
+
+	// Output is the fully qualified schema name of the response message.
+	Output string
+}
+
+var (
+	servicesMu sync.RWMutex
+	services   = map[string]*ServiceDesc{}
+)
+
+// RegisterService registers the description of a service.
+func RegisterService(desc *ServiceDesc) {
+	servicesMu.Lock()
+	defer servicesMu.Unlock()
+	services[desc.Name] = desc
+}
+
+// GetService returns the registered description of a service,
+// or nil if the service has none.
+func GetService(name string) *ServiceDesc {
+	servicesMu.RLock()
+	defer servicesMu.RUnlock()
+	return services[name]
+}
+
+// Services returns the descriptions of all registered services, sorted by name.
+func Services() []*ServiceDesc {
+	servicesMu.RLock()
+	defer servicesMu.RUnlock()
+
+	result := make([]*ServiceDesc, 0, len(services))
+	for _, desc := range services {
+		result = append(result, desc)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// Method returns the description of a method, or nil if the service has no such method.
+func (s *ServiceDesc) Method(name string) *MethodDesc {
+	for i := range s.Methods {
+		if s.Methods[i].Name == name {
+			return &s.Methods[i]
+		}
+	}
+	return nil
+}
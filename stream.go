@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"io"
+	"sync"
+	"sync/atomic"
 
 	"github.com/srand/mqc/serialization"
 )
@@ -78,56 +80,129 @@ type clientStreamImpl[Req any, Res any] struct {
 	call       Conn
 	serializer serialization.Serializer
 	eof        bool
+
+	// sendClosed is set once the client has sent all requests
+	sendClosed bool
+
+	// ctx bounds all operations of the call, e.g. by its default deadline,
+	// and cancel releases it when the call ends
+	ctx      context.Context
+	cancel   context.CancelFunc
+	released atomic.Bool
+	endOnce  sync.Once
 }
 
-func NewClientStreamClient[Req any, Res any](ctx context.Context, transport Transport, method *Method) (ClientStreamClient[Req, Res], error) {
-	call, err := transport.Invoke(ctx, method)
+func NewClientStreamClient[Req any, Res any](ctx context.Context, conn ClientConn, method *Method) (ClientStreamClient[Req, Res], error) {
+	ctx, cancel := withCallTimeout(ctx, conn, method)
+
+	call, err := conn.Invoke(ctx, method)
 	if err != nil {
+		cancel()
 		return nil, err
 	}
 
-	return &clientStreamImpl[Req, Res]{call: call, serializer: transport.Serializer()}, nil
+	return &clientStreamImpl[Req, Res]{call: call, serializer: methodSerializer(conn, method), ctx: ctx, cancel: cancel}, nil
 }
 
-func NewServerStreamClient[Req, Res any](ctx context.Context, transport Transport, method *Method, req *Req) (ServerStreamClient[Res], error) {
-	serializer := transport.Serializer()
+func NewServerStreamClient[Req, Res any](ctx context.Context, conn ClientConn, method *Method, req *Req) (ServerStreamClient[Res], error) {
+	ctx, cancel := withCallTimeout(ctx, conn, method)
+
+	serializer := methodSerializer(conn, method)
 
-	call, err := transport.Invoke(ctx, method)
+	call, err := conn.Invoke(ctx, method)
 	if err != nil {
+		cancel()
 		return nil, err
 	}
 
 	data, err := serializer.Marshal(req)
 	if err != nil {
+		call.Close()
+		cancel()
 		return nil, err
 	}
 
 	err = call.Send(ctx, data)
 	if err != nil {
+		call.Close()
+		cancel()
 		return nil, err
 	}
 
-	return &clientStreamImpl[any, Res]{call: call, serializer: serializer}, nil
+	return &clientStreamImpl[any, Res]{call: call, serializer: serializer, sendClosed: true, ctx: ctx, cancel: cancel}, nil
 }
 
-func NewBidiStreamClient[Req any, Res any](ctx context.Context, transport Transport, method *Method) (BidiStreamClient[Req, Res], error) {
-	call, err := transport.Invoke(ctx, method)
+func NewBidiStreamClient[Req any, Res any](ctx context.Context, conn ClientConn, method *Method) (BidiStreamClient[Req, Res], error) {
+	ctx, cancel := withCallTimeout(ctx, conn, method)
+
+	call, err := conn.Invoke(ctx, method)
 	if err != nil {
+		cancel()
 		return nil, err
 	}
-	return &clientStreamImpl[Req, Res]{call: call, serializer: transport.Serializer()}, nil
+	return &clientStreamImpl[Req, Res]{call: call, serializer: methodSerializer(conn, method), ctx: ctx, cancel: cancel}, nil
+}
+
+// bound returns the context of an operation, ending at the deadline of the
+// call or when the call is canceled.
+func (s *clientStreamImpl[Req, Res]) bound(ctx context.Context) (context.Context, context.CancelFunc) {
+	var cancel context.CancelFunc
+	if deadline, ok := s.ctx.Deadline(); ok {
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+
+	// The deadline of the call is reported by the operation itself, and
+	// releasing the call does not cancel the remaining operations
+	stop := context.AfterFunc(s.ctx, func() {
+		if !s.released.Load() && !errors.Is(s.ctx.Err(), context.DeadlineExceeded) {
+			cancel()
+		}
+	})
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// release releases the context of the call once no more messages are received.
+func (s *clientStreamImpl[Req, Res]) release() {
+	s.released.Store(true)
+	s.cancel()
+}
+
+// end closes the call after its last message or an error.
+func (s *clientStreamImpl[Req, Res]) end() {
+	s.endOnce.Do(func() {
+		s.release()
+		s.call.Close()
+	})
 }
 
 func (s *clientStreamImpl[Req, Res]) CloseAndRecv(ctx context.Context) (*Res, error) {
+	// The call ends with the response
+	defer s.end()
+
+	ctx, cancel := s.bound(ctx)
+	defer cancel()
+
 	// Send close signal
 	if err := s.call.SendClose(ctx); err != nil {
 		return nil, err
 	}
 
-	return s.Recv(ctx)
+	return s.recv(ctx)
 }
 
 func (s *clientStreamImpl[Req, Res]) Recv(ctx context.Context) (*Res, error) {
+	ctx, cancel := s.bound(ctx)
+	defer cancel()
+
+	return s.recv(ctx)
+}
+
+func (s *clientStreamImpl[Req, Res]) recv(ctx context.Context) (*Res, error) {
 	if s.eof {
 		return nil, io.EOF
 	}
@@ -135,10 +210,17 @@ func (s *clientStreamImpl[Req, Res]) Recv(ctx context.Context) (*Res, error) {
 	data, err := s.call.Recv(ctx)
 	if errors.Is(err, io.EOF) {
 		s.eof = true
+		s.release()
+
+		// Release the stream once both sides are done sending
+		if s.sendClosed {
+			s.end()
+		}
 		return nil, io.EOF
 	}
 
 	if err != nil {
+		s.end()
 		return nil, err
 	}
 
@@ -164,14 +246,20 @@ func (s *clientStreamImpl[Req, Res]) Send(ctx context.Context, req *Req) error {
 	if err != nil {
 		return err
 	}
-	if err := s.call.Send(ctx, data); err != nil {
-		return err
-	}
-	return nil
+
+	ctx, cancel := s.bound(ctx)
+	defer cancel()
+
+	return s.call.Send(ctx, data)
 }
 
 func (s *clientStreamImpl[Req, Res]) CloseSend() error {
-	return s.call.SendClose(context.Background())
+	s.sendClosed = true
+
+	ctx, cancel := s.bound(context.Background())
+	defer cancel()
+
+	return s.call.SendClose(ctx)
 }
 
 type serverStreamImpl[Req, Res any] struct {
@@ -181,19 +269,19 @@ type serverStreamImpl[Req, Res any] struct {
 }
 
 func NewClientStreamServer[Req, Res any](transport Transport, call Conn) (ClientStreamServer[Req, Res], error) {
-	return &serverStreamImpl[Req, Res]{call: call, serializer: transport.Serializer()}, nil
+	return &serverStreamImpl[Req, Res]{call: call, serializer: connSerializer(transport.Serializer(), call)}, nil
 }
 
-func NewServerStreamServer[Req, Res any](transport Transport, call Conn) (ServerStreamServer[Res], *Req, error) {
-	stream := &serverStreamImpl[any, Res]{call: call, serializer: transport.Serializer()}
+func NewServerStreamServer[Req, Res any](ctx context.Context, transport Transport, call Conn) (ServerStreamServer[Res], *Req, error) {
+	stream := &serverStreamImpl[any, Res]{call: call, serializer: connSerializer(transport.Serializer(), call)}
 
 	// Read initial request message
-	data, err := call.Recv(context.Background())
+	data, err := call.Recv(ctx)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	req, err := unmarshal[Req](transport.Serializer(), data)
+	req, err := unmarshal[Req](stream.serializer, data)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -202,7 +290,7 @@ func NewServerStreamServer[Req, Res any](transport Transport, call Conn) (Server
 }
 
 func NewBidiStreamServer[Req, Res any](transport Transport, call Conn) (BidiStreamServer[Req, Res], error) {
-	return &serverStreamImpl[Req, Res]{call: call, serializer: transport.Serializer()}, nil
+	return &serverStreamImpl[Req, Res]{call: call, serializer: connSerializer(transport.Serializer(), call)}, nil
 }
 
 func (s *serverStreamImpl[Req, Res]) Recv(ctx context.Context) (*Req, error) {
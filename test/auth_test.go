@@ -0,0 +1,156 @@
+package test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/srand/mqc"
+	"github.com/srand/mqc/credentials"
+	"github.com/srand/mqc/transport"
+	tpc "github.com/srand/mqc/transport/tcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// identityServer echoes requests and records the identity of the caller.
+type identityServer struct {
+	identity chan *mqc.Identity
+}
+
+func (s *identityServer) Rpc(ctx context.Context, req *TestRequest) (*TestReply, error) {
+	s.identity <- mqc.IdentityFromContext(ctx)
+	return &TestReply{Value: req.Value}, nil
+}
+
+func startAuthServer(t *testing.T, address string, authenticator mqc.Authenticator) *identityServer {
+	os.Remove(address)
+
+	server, err := tpc.NewTransport(
+		transport.WithProtocol("unix"),
+		transport.WithAddress(address),
+		transport.WithAuthenticator(authenticator),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { server.Close() })
+
+	impl := &identityServer{identity: make(chan *mqc.Identity, 1)}
+	RegisterRpcTestServer(server, impl)
+
+	go server.Serve()
+	time.Sleep(100 * time.Millisecond) // Give the server some time to start
+
+	return impl
+}
+
+func newAuthClient(t *testing.T, address string, options ...transport.TransportOption) RpcTestClient {
+	options = append(options, transport.WithProtocol("unix"), transport.WithAddress(address))
+
+	client, err := tpc.NewTransport(options...)
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+
+	return NewRpcTestClient(client)
+}
+
+func TestBearerTokenAuthentication(t *testing.T) {
+	const address = "/tmp/mqc-auth.sock"
+
+	alice := &mqc.Identity{Subject: "alice", Attributes: map[string]string{"role": "admin"}}
+	server := startAuthServer(t, address, credentials.StaticTokens(map[string]*mqc.Identity{
+		"secret": alice,
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Calls without credentials are rejected before the handler runs
+	_, err := newAuthClient(t, address).Rpc(ctx, &TestRequest{Value: 1})
+	assert.EqualError(t, err, mqc.ErrUnauthenticated.Error())
+
+	_, err = newAuthClient(t, address, transport.WithCredentials(credentials.BearerToken("wrong"))).Rpc(ctx, &TestRequest{Value: 1})
+	assert.EqualError(t, err, mqc.ErrUnauthenticated.Error())
+	assert.Empty(t, server.identity)
+
+	reply, err := newAuthClient(t, address, transport.WithCredentials(credentials.BearerToken("secret"))).Rpc(ctx, &TestRequest{Value: 1})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), reply.Value)
+	assert.Equal(t, alice, <-server.identity)
+}
+
+func TestJWTAuthentication(t *testing.T) {
+	const address = "/tmp/mqc-auth-jwt.sock"
+
+	key := []byte("jwt-key")
+	server := startAuthServer(t, address, credentials.JWTAuthenticator(key))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	token, err := credentials.SignJWT(key, &mqc.Identity{Subject: "bob", Attributes: map[string]string{"team": "ops"}}, time.Minute)
+	require.NoError(t, err)
+
+	_, err = newAuthClient(t, address, transport.WithCredentials(credentials.BearerToken(token))).Rpc(ctx, &TestRequest{})
+	require.NoError(t, err)
+
+	identity := <-server.identity
+	assert.Equal(t, "bob", identity.Subject)
+	assert.Equal(t, "ops", identity.Attributes["team"])
+
+	// Tokens signed with another key are rejected
+	forged, err := credentials.SignJWT([]byte("other-key"), &mqc.Identity{Subject: "bob"}, time.Minute)
+	require.NoError(t, err)
+
+	_, err = newAuthClient(t, address, transport.WithCredentials(credentials.BearerToken(forged))).Rpc(ctx, &TestRequest{})
+	assert.EqualError(t, err, mqc.ErrUnauthenticated.Error())
+}
+
+func TestHMACAuthentication(t *testing.T) {
+	const address = "/tmp/mqc-auth-hmac.sock"
+
+	server := startAuthServer(t, address, credentials.HMACAuthenticator(map[string][]byte{
+		"device-1": []byte("device-key"),
+	}, time.Minute))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	_, err := newAuthClient(t, address, transport.WithCredentials(credentials.HMAC("device-1", []byte("device-key")))).Rpc(ctx, &TestRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "device-1", (<-server.identity).Subject)
+
+	_, err = newAuthClient(t, address, transport.WithCredentials(credentials.HMAC("device-1", []byte("wrong-key")))).Rpc(ctx, &TestRequest{})
+	assert.EqualError(t, err, mqc.ErrUnauthenticated.Error())
+}
+
+func TestHMACReplay(t *testing.T) {
+	authenticator := credentials.HMACAuthenticator(map[string][]byte{
+		"device-1": []byte("device-key"),
+	}, time.Minute)
+
+	ctx := context.Background()
+	method := mqc.NewMethod("RpcTest/Rpc", mqc.MethodTypeUnary)
+
+	md, err := credentials.HMAC("device-1", []byte("device-key")).Metadata(ctx, method)
+	require.NoError(t, err)
+
+	identity, err := authenticator.Authenticate(ctx, method, md)
+	require.NoError(t, err)
+	assert.Equal(t, "device-1", identity.Subject)
+
+	// Signed calls cannot be replayed
+	_, err = authenticator.Authenticate(ctx, method, md)
+	assert.ErrorIs(t, err, mqc.ErrUnauthenticated)
+
+	// Nor replayed with another nonce, which the signature covers
+	md[credentials.NonceKey] = "replayed"
+	_, err = authenticator.Authenticate(ctx, method, md)
+	assert.ErrorIs(t, err, mqc.ErrUnauthenticated)
+
+	// Every call is signed with its own nonce
+	md, err = credentials.HMAC("device-1", []byte("device-key")).Metadata(ctx, method)
+	require.NoError(t, err)
+	_, err = authenticator.Authenticate(ctx, method, md)
+	require.NoError(t, err)
+}
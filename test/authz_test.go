@@ -0,0 +1,274 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"github.com/google/uuid"
+	"github.com/srand/mqc"
+	"github.com/srand/mqc/authz"
+	"github.com/srand/mqc/credentials"
+	"github.com/srand/mqc/serialization"
+	"github.com/srand/mqc/transport"
+	"github.com/srand/mqc/transport/mqtt"
+	tpc "github.com/srand/mqc/transport/tcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const testPolicy = `
+default: deny
+rules:
+  - name: no streams for guests
+    effect: deny
+    types: ["server-stream", "client-stream", "bidi-stream"]
+    attributes:
+      role: guest
+  - name: operators
+    effect: allow
+    services: ["RpcTest", "*StreamTest"]
+    attributes:
+      role: operator
+  - name: guests
+    effect: allow
+    services: ["RpcTest"]
+    subjects: ["*"]
+  - effect: allow
+    services: ["Weather"]
+    subjects: ["sensor"]
+`
+
+func TestPolicyDecide(t *testing.T) {
+	policy, err := authz.ParsePolicy([]byte(testPolicy))
+	require.NoError(t, err)
+
+	operator := &mqc.Identity{Subject: "alice", Attributes: map[string]string{"role": "operator"}}
+	guest := &mqc.Identity{Subject: "bob", Attributes: map[string]string{"role": "guest"}}
+
+	unary := mqc.NewMethod("RpcTest/Rpc", mqc.MethodTypeUnary)
+	stream := mqc.NewMethod("BidiStreamTest/Stream", mqc.MethodTypeBidiStream)
+
+	assert.Equal(t, authz.Decision{Effect: authz.Allow, Rule: "operators"}, policy.Decide(stream, operator))
+	assert.Equal(t, authz.Decision{Effect: authz.Deny, Rule: "no streams for guests"}, policy.Decide(stream, guest))
+	assert.Equal(t, authz.Decision{Effect: authz.Allow, Rule: "guests"}, policy.Decide(unary, guest))
+	assert.Equal(t, authz.Decision{Effect: authz.Deny, Rule: "default"}, policy.Decide(unary, nil))
+
+	// JSON policies are accepted as well
+	policy, err = authz.ParsePolicy([]byte(`{"default": "allow", "rules": [{"effect": "deny", "methods": ["Rpc"]}]}`))
+	require.NoError(t, err)
+	assert.Equal(t, authz.Decision{Effect: authz.Deny, Rule: "#0"}, policy.Decide(unary, operator))
+	assert.Equal(t, authz.Decision{Effect: authz.Allow, Rule: "default"}, policy.Decide(stream, nil))
+
+	for _, invalid := range []string{
+		`default: maybe`,
+		`rules: [{effect: permit}]`,
+		`rules: [{effect: allow, types: [stream]}]`,
+		`rules: [{effect: allow, services: ["["]}]`,
+	} {
+		_, err := authz.ParsePolicy([]byte(invalid))
+		assert.Error(t, err, invalid)
+	}
+}
+
+// documentedPolicy is the example policy of the README.
+const documentedPolicy = `
+default: deny
+rules:
+  - name: health checks
+    effect: allow
+    services: ["Health"]
+  - name: operators
+    effect: allow
+    services: ["Greeter"]
+    types: ["unary", "server-stream"]
+    attributes:
+      role: operator
+`
+
+func TestDocumentedPolicy(t *testing.T) {
+	policy, err := authz.ParsePolicy([]byte(documentedPolicy))
+	require.NoError(t, err)
+
+	operator := &mqc.Identity{Subject: "alice", Attributes: map[string]string{"role": "operator"}}
+
+	// Health checks use the service name of the generated code
+	check := mqc.NewMethod("Health/Check", mqc.MethodTypeUnary)
+	watch := mqc.NewMethod("Health/Watch", mqc.MethodTypeServerStream)
+	assert.Equal(t, authz.Decision{Effect: authz.Allow, Rule: "health checks"}, policy.Decide(check, nil))
+	assert.Equal(t, authz.Decision{Effect: authz.Allow, Rule: "health checks"}, policy.Decide(watch, nil))
+
+	greet := mqc.NewMethod("Greeter/SayHello", mqc.MethodTypeUnary)
+	assert.Equal(t, authz.Decision{Effect: authz.Allow, Rule: "operators"}, policy.Decide(greet, operator))
+	assert.Equal(t, authz.Decision{Effect: authz.Deny, Rule: "default"}, policy.Decide(greet, nil))
+}
+
+func TestAuthorizer(t *testing.T) {
+	const address = "/tmp/mqc-authz.sock"
+	os.Remove(address)
+
+	policyFile := filepath.Join(t.TempDir(), "policy.yaml")
+	require.NoError(t, os.WriteFile(policyFile, []byte(testPolicy), 0644))
+
+	policy, err := authz.LoadPolicy(policyFile)
+	require.NoError(t, err)
+
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+
+	server, err := tpc.NewTransport(
+		transport.WithProtocol("unix"),
+		transport.WithAddress(address),
+		transport.WithAuthenticator(credentials.StaticTokens(map[string]*mqc.Identity{
+			"operator": {Subject: "alice", Attributes: map[string]string{"role": "operator"}},
+			"guest":    {Subject: "bob", Attributes: map[string]string{"role": "guest"}},
+		})),
+		transport.WithAuthorizer(authz.NewAuthorizer(policy, logger)),
+	)
+	require.NoError(t, err)
+	defer server.Close()
+
+	RegisterRpcTestServer(server, newPeerServer())
+	RegisterServerStreamTestServer(server, &serverStreamServer{})
+	go server.Serve()
+	time.Sleep(100 * time.Millisecond) // Give the server some time to start
+
+	newClient := func(token string) mqc.Transport {
+		client, err := tpc.NewTransport(
+			transport.WithProtocol("unix"),
+			transport.WithAddress(address),
+			transport.WithCredentials(credentials.BearerToken(token)),
+		)
+		require.NoError(t, err)
+		t.Cleanup(func() { client.Close() })
+		return client
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	operator := newClient("operator")
+	guest := newClient("guest")
+
+	_, err = NewRpcTestClient(operator).Rpc(ctx, &TestRequest{})
+	assert.NoError(t, err)
+	_, err = NewRpcTestClient(guest).Rpc(ctx, &TestRequest{})
+	assert.NoError(t, err)
+
+	stream, err := NewServerStreamTestClient(operator).Stream(ctx, &TestRequest{Value: 1})
+	require.NoError(t, err)
+	_, err = stream.Recv(ctx)
+	assert.NoError(t, err)
+
+	// The denial is received with the first reply of the stream
+	stream, err = NewServerStreamTestClient(guest).Stream(ctx, &TestRequest{Value: 1})
+	require.NoError(t, err)
+	_, err = stream.Recv(ctx)
+	assert.EqualError(t, err, mqc.ErrPermissionDenied.Error())
+
+	assert.Contains(t, logs.String(), `level=INFO msg="authorization decision" decision=allow rule=operators method=RpcTest/Rpc subject=alice`)
+	assert.Contains(t, logs.String(), `level=WARN msg="authorization decision" decision=deny rule="no streams for guests" method=ServerStreamTest/Stream subject=bob`)
+}
+
+// serverStreamServer streams the requested number of replies.
+type serverStreamServer struct{}
+
+func (s *serverStreamServer) Stream(ctx context.Context, req *TestRequest, stream mqc.ServerStreamServer[TestReply]) error {
+	for i := int32(0); i < req.Value; i++ {
+		if err := stream.Send(ctx, &TestReply{Value: i}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestMosquittoACL(t *testing.T) {
+	policy, err := authz.ParsePolicy([]byte(testPolicy))
+	require.NoError(t, err)
+
+	services := []*mqc.ServiceDesc{
+		{Name: "RpcTest", Methods: []mqc.MethodDesc{{Name: "Rpc", Type: mqc.MethodTypeUnary}}},
+		{Name: "Weather", Methods: []mqc.MethodDesc{{Name: "Update", Type: mqc.MethodTypePublisher}}},
+	}
+
+	assert.Equal(t, `# Generated from an mqc authorization policy
+
+# All users
+# RpcTest/Rpc
+pattern write MQC/RpcTest/Rpc/Control/+
+pattern write MQC/RpcTest/Rpc/Client/+/+
+pattern read MQC/RpcTest/Rpc/Server/+/+
+
+user sensor
+# Weather/Update
+topic write MQC/Weather/Update
+# Weather/Update
+topic read MQC/Weather/Update
+
+user server
+# RpcTest/Rpc
+topic write MQC/RpcTest/Rpc/Server/+/+
+topic read MQC/RpcTest/Rpc/Control/+
+topic read MQC/RpcTest/Rpc/Client/+/+
+`, policy.MosquittoACL(services, "server"))
+}
+
+func TestMismatchedInvokeOverMqtt(t *testing.T) {
+	server, err := mqtt.NewTransport(transport.WithAddress("localhost:1883"))
+	require.NoError(t, err)
+	defer server.Close()
+
+	serverMock := &RpcTestServerMock{}
+	streamMock := &ServerStreamTestServerMock{}
+	RegisterRpcTestServer(server, serverMock)
+	RegisterServerStreamTestServer(server, streamMock)
+	go server.Serve()
+	time.Sleep(100 * time.Millisecond) // Give the server some time to subscribe
+
+	client := paho.NewClient(paho.NewClientOptions().AddBroker("tcp://localhost:1883"))
+	token := client.Connect()
+	token.Wait()
+	require.NoError(t, token.Error())
+	defer client.Disconnect(0)
+
+	// The ACL of the client only grants the topics of RpcTest/Rpc
+	id := uuid.New().String()
+	serializer := serialization.NewJSONSerializer()
+
+	replies := make(chan *mqc.Message, 1)
+	token = client.Subscribe("MQC/RpcTest/Rpc/Server/"+id+"/Control", 0, func(_ paho.Client, msg paho.Message) {
+		var reply mqc.Message
+		if serializer.Unmarshal(msg.Payload(), &reply) == nil {
+			replies <- &reply
+		}
+	})
+	token.Wait()
+	require.NoError(t, token.Error())
+
+	// Invoke another method on the control topic of RpcTest/Rpc
+	call, err := mqc.NewCallMessage(mqc.NewMethod("ServerStreamTest/Stream", mqc.MethodTypeServerStream), nil)
+	require.NoError(t, err)
+	payload, err := serializer.Marshal(call)
+	require.NoError(t, err)
+
+	token = client.Publish("MQC/RpcTest/Rpc/Control/"+id, 2, false, payload)
+	token.Wait()
+	require.NoError(t, token.Error())
+
+	// The call is rejected instead of acknowledged
+	select {
+	case reply := <-replies:
+		assert.True(t, reply.IsError())
+		assert.EqualError(t, reply.Error(), mqc.ErrPermissionDenied.Error())
+	case <-time.After(time.Second):
+		t.Fatal("call was not rejected")
+	}
+	streamMock.AssertNotCalled(t, "Stream", mock.Anything, mock.Anything)
+	serverMock.AssertNotCalled(t, "Rpc", mock.Anything)
+}
@@ -0,0 +1,169 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/srand/mqc"
+	"github.com/srand/mqc/channelz"
+	"github.com/srand/mqc/transport"
+	tpc "github.com/srand/mqc/transport/tcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// hangingServer echoes one message and then hangs until released.
+type hangingServer struct {
+	release chan struct{}
+}
+
+func (s *hangingServer) Stream(ctx context.Context, stream mqc.BidiStreamServer[TestRequest, TestReply]) error {
+	req, err := stream.Recv(ctx)
+	if err != nil {
+		return err
+	}
+	if err := stream.Send(ctx, &TestReply{Value: req.Value}); err != nil {
+		return err
+	}
+	<-s.release
+	return nil
+}
+
+func TestChannelz(t *testing.T) {
+	const address = "/tmp/mqc-channelz.sock"
+	os.Remove(address)
+
+	registry := channelz.NewRegistry()
+	hanging := &hangingServer{release: make(chan struct{})}
+
+	server, err := tpc.NewTransport(
+		transport.WithProtocol("unix"),
+		transport.WithAddress(address),
+		transport.WithChannelz(registry),
+	)
+	require.NoError(t, err)
+	defer server.Close()
+
+	RegisterBidiStreamTestServer(server, hanging)
+	go server.Serve()
+	time.Sleep(100 * time.Millisecond) // Give the server some time to start
+
+	client, err := tpc.NewTransport(
+		transport.WithProtocol("unix"),
+		transport.WithAddress(address),
+		transport.WithChannelz(registry),
+	)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	stream, err := NewBidiStreamTestClient(client).Stream(ctx)
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(ctx, &TestRequest{Value: 1}))
+	_, err = stream.Recv(ctx)
+	require.NoError(t, err)
+
+	// The hanging stream is visible on both ends
+	snapshot := registry.Snapshot()
+	require.Len(t, snapshot, 2)
+	for _, info := range snapshot {
+		assert.Equal(t, "unix", info.Kind)
+		assert.Equal(t, []string{address}, info.Addrs)
+		require.Len(t, info.Sessions, 1)
+		assert.Equal(t, 1, info.Sessions[0].Streams)
+		require.Len(t, info.Sessions[0].Calls, 1)
+
+		call := info.Sessions[0].Calls[0]
+		assert.Equal(t, "BidiStreamTest/Stream", call.Method)
+		assert.Equal(t, "bidi-stream", call.Type)
+		assert.Equal(t, int64(1), call.MessagesSent)
+		assert.Equal(t, int64(1), call.MessagesReceived)
+		assert.Greater(t, call.BytesSent, int64(0))
+		assert.Greater(t, call.BytesReceived, int64(0))
+		assert.False(t, call.LastMessage.IsZero())
+		assert.Greater(t, call.Duration, time.Duration(0))
+	}
+	assert.Equal(t, channelz.RoleServer, snapshot[0].Sessions[0].Calls[0].Role)
+	assert.Equal(t, channelz.RoleClient, snapshot[1].Sessions[0].Calls[0].Role)
+
+	recorder := httptest.NewRecorder()
+	registry.ServeHTTP(recorder, httptest.NewRequest("GET", "/debug/channelz", nil))
+	assert.Contains(t, recorder.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, recorder.Body.String(), "<td>BidiStreamTest/Stream</td>")
+
+	recorder = httptest.NewRecorder()
+	registry.ServeHTTP(recorder, httptest.NewRequest("GET", "/debug/channelz?format=json", nil))
+	var decoded []channelz.TransportInfo
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &decoded))
+	assert.Len(t, decoded, 2)
+
+	// Completed calls, closed sessions and closed transports are removed
+	close(hanging.release)
+	require.NoError(t, stream.CloseSend())
+	_, err = stream.Recv(ctx)
+	assert.ErrorIs(t, err, io.EOF)
+	assert.Eventually(t, func() bool {
+		snapshot := registry.Snapshot()
+		return len(snapshot[0].Sessions[0].Calls) == 0 && len(snapshot[1].Sessions[0].Calls) == 0
+	}, time.Second, 10*time.Millisecond)
+
+	client.Close()
+	assert.Eventually(t, func() bool {
+		snapshot := registry.Snapshot()
+		return len(snapshot) == 1 && len(snapshot[0].Sessions) == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+// watchServer replies to watches with the request value.
+type watchServer struct {
+	UnimplementedOptionsTestServer
+}
+
+func (s *watchServer) Watch(ctx context.Context, req *TestRequest, stream mqc.ServerStreamServer[TestReply]) error {
+	return stream.Send(ctx, &TestReply{Value: req.Value})
+}
+
+func TestChannelzMethodSerializer(t *testing.T) {
+	const address = "/tmp/mqc-channelz-serializer.sock"
+	os.Remove(address)
+
+	registry := channelz.NewRegistry()
+
+	server, err := tpc.NewTransport(
+		transport.WithProtocol("unix"),
+		transport.WithAddress(address),
+		transport.WithChannelz(registry),
+	)
+	require.NoError(t, err)
+	defer server.Close()
+
+	RegisterOptionsTestServer(server, &watchServer{})
+	go server.Serve()
+	time.Sleep(100 * time.Millisecond) // Give the server some time to start
+
+	client, err := tpc.NewTransport(
+		transport.WithProtocol("unix"),
+		transport.WithAddress(address),
+		transport.WithChannelz(registry),
+	)
+	require.NoError(t, err)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Tracked calls are handled with the serializer of their method
+	stream, err := NewOptionsTestClient(client).Watch(ctx, &TestRequest{Value: 5})
+	require.NoError(t, err)
+	reply, err := stream.Recv(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int32(5), reply.Value)
+	_, err = stream.Recv(ctx)
+	assert.ErrorIs(t, err, io.EOF)
+}
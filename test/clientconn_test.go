@@ -0,0 +1,169 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/srand/mqc"
+	"github.com/srand/mqc/balancer"
+	"github.com/srand/mqc/mqctest"
+	"github.com/srand/mqc/transport"
+	tpc "github.com/srand/mqc/transport/tcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFakeClientConn(t *testing.T) {
+	conn := mqctest.NewFakeClientConn()
+	defer conn.Close()
+
+	serverMock := &RpcTestServerMock{}
+	serverMock.On("Rpc", mock.Anything).Return(&TestReply{Value: 1}, nil).Once()
+	serverMock.On("Rpc", mock.Anything).Return(nil, mqc.ErrPermissionDenied).Once()
+	RegisterRpcTestServer(conn, serverMock)
+	RegisterServerStreamTestServer(conn, &serverStreamServer{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Calls reach the registered servers through generated clients
+	rpcClient := NewRpcTestClient(conn)
+	res, err := rpcClient.Rpc(ctx, &TestRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), res.Value)
+
+	// Handler errors are received like remote errors
+	_, err = rpcClient.Rpc(ctx, &TestRequest{})
+	assert.EqualError(t, err, mqc.ErrPermissionDenied.Error())
+
+	stream, err := NewServerStreamTestClient(conn).Stream(ctx, &TestRequest{Value: 3})
+	require.NoError(t, err)
+	for i := int32(0); i < 3; i++ {
+		reply, err := stream.Recv(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, i, reply.Value)
+	}
+	_, err = stream.Recv(ctx)
+	assert.ErrorIs(t, err, io.EOF)
+
+	// Methods without a server are unimplemented
+	_, err = NewClientStreamTestClient(conn).Stream(ctx)
+	assert.ErrorIs(t, err, mqc.ErrUnimplemented)
+
+	assert.Len(t, conn.Invoked(), 4)
+	assert.Equal(t, mqc.ConnStateReady, conn.State())
+
+	conn.Close()
+	assert.Equal(t, mqc.ConnStateShutdown, conn.State())
+	_, err = rpcClient.Rpc(ctx, &TestRequest{})
+	assert.ErrorIs(t, err, mqc.ErrUnavailable)
+}
+
+func TestBalancer(t *testing.T) {
+	_, err := balancer.New()
+	assert.Error(t, err)
+
+	replicas := []*mqctest.FakeClientConn{mqctest.NewFakeClientConn(), mqctest.NewFakeClientConn()}
+	for _, replica := range replicas {
+		RegisterRpcTestServer(replica, &concurrencyServer{})
+	}
+
+	conn, err := balancer.New(replicas[0], replicas[1])
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	rpcClient := NewRpcTestClient(conn)
+
+	// Calls are distributed round-robin
+	for i := int32(0); i < 4; i++ {
+		res, err := rpcClient.Rpc(ctx, &TestRequest{Value: i})
+		require.NoError(t, err)
+		assert.Equal(t, i, res.Value)
+	}
+	assert.Len(t, replicas[0].Invoked(), 2)
+	assert.Len(t, replicas[1].Invoked(), 2)
+
+	// Failing connections are tried last
+	replicas[0].SetState(mqc.ConnStateTransientFailure)
+	for i := 0; i < 2; i++ {
+		_, err := rpcClient.Rpc(ctx, &TestRequest{})
+		require.NoError(t, err)
+	}
+	assert.Len(t, replicas[0].Invoked(), 2)
+	assert.Len(t, replicas[1].Invoked(), 4)
+	assert.Equal(t, mqc.ConnStateReady, conn.State())
+
+	// Calls that cannot be invoked fail over to the next connection
+	replicas[1].Err = errors.New("connection refused")
+	_, err = rpcClient.Rpc(ctx, &TestRequest{})
+	require.NoError(t, err)
+	assert.Len(t, replicas[0].Invoked(), 3)
+
+	// The error of the last connection is returned if all fail
+	replicas[0].Err = mqc.ErrUnavailable
+	_, err = rpcClient.Rpc(ctx, &TestRequest{})
+	assert.Error(t, err)
+
+	replicas[1].SetState(mqc.ConnStateTransientFailure)
+	assert.Equal(t, mqc.ConnStateTransientFailure, conn.State())
+
+	// Closing the balancer closes its connections
+	require.NoError(t, conn.Close())
+	assert.Equal(t, mqc.ConnStateShutdown, conn.State())
+	assert.Equal(t, mqc.ConnStateShutdown, replicas[0].State())
+	_, err = rpcClient.Rpc(ctx, &TestRequest{})
+	assert.ErrorIs(t, err, mqc.ErrUnavailable)
+}
+
+func TestTransportState(t *testing.T) {
+	const address = "/tmp/mqc-state.sock"
+	os.Remove(address)
+
+	options := []transport.TransportOption{
+		transport.WithProtocol("unix"),
+		transport.WithAddress(address),
+		transport.WithIdleTimeout(100 * time.Millisecond),
+	}
+
+	client, err := tpc.NewTransport(options...)
+	require.NoError(t, err)
+	defer client.Close()
+	assert.Equal(t, mqc.ConnStateIdle, client.State())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// The server is not running yet
+	rpcClient := NewRpcTestClient(client)
+	_, err = rpcClient.Rpc(ctx, &TestRequest{})
+	require.Error(t, err)
+	assert.Equal(t, mqc.ConnStateTransientFailure, client.State())
+
+	server, err := tpc.NewTransport(options...)
+	require.NoError(t, err)
+	defer server.Close()
+
+	RegisterRpcTestServer(server, &concurrencyServer{})
+	go server.Serve()
+	time.Sleep(100 * time.Millisecond) // Give the server some time to start
+
+	_, err = rpcClient.Rpc(ctx, &TestRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, mqc.ConnStateReady, client.State())
+
+	// The connection is idle once the session is closed for inactivity
+	assert.Eventually(t, func() bool {
+		return client.State() == mqc.ConnStateIdle
+	}, time.Second, 10*time.Millisecond)
+
+	client.Close()
+	assert.Equal(t, mqc.ConnStateShutdown, client.State())
+}
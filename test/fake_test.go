@@ -0,0 +1,96 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/srand/mqc"
+	"github.com/stretchr/testify/assert"
+)
+
+// Increments every request value by one
+type incrementServer struct{}
+
+func (s *incrementServer) Stream(stream mqc.BidiStreamServer[TestRequest, TestReply]) error {
+	ctx := context.Background()
+
+	for {
+		req, err := stream.Recv(ctx)
+		if errors.Is(err, io.EOF) {
+			return stream.CloseSend()
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := stream.Send(ctx, &TestReply{Value: req.Value + 1}); err != nil {
+			return err
+		}
+	}
+}
+
+func TestFakeServerStream(t *testing.T) {
+	stream := &FakeBidiStreamTestStreamServer{
+		Requests: []*TestRequest{{Value: 1}, {Value: 2}},
+	}
+
+	err := (&incrementServer{}).Stream(stream)
+	assert.NoError(t, err)
+	assert.True(t, stream.Closed)
+	assert.Len(t, stream.Sent, 2)
+	assert.Equal(t, int32(2), stream.Sent[0].Value)
+	assert.Equal(t, int32(3), stream.Sent[1].Value)
+}
+
+func TestFakeServerStreamError(t *testing.T) {
+	expected := errors.New("recv error")
+
+	stream := &FakeBidiStreamTestStreamServer{
+		Requests: []*TestRequest{{Value: 1}},
+		Err:      expected,
+	}
+
+	err := (&incrementServer{}).Stream(stream)
+	assert.Equal(t, expected, err)
+	assert.False(t, stream.Closed)
+	assert.Len(t, stream.Sent, 1)
+}
+
+func TestMockClient(t *testing.T) {
+	ctx := context.Background()
+
+	stream := &FakeServerStreamTestStreamClient{
+		Responses: []*TestReply{{Value: 1}, {Value: 2}},
+	}
+
+	var client ServerStreamTestClient = &MockServerStreamTestClient{
+		StreamFunc: func(ctx context.Context, req *TestRequest) (mqc.ServerStreamClient[TestReply], error) {
+			assert.Equal(t, int32(42), req.Value)
+			return stream, nil
+		},
+	}
+
+	replies, err := client.Stream(ctx, &TestRequest{Value: 42})
+	assert.NoError(t, err)
+
+	var values []int32
+	for {
+		reply, err := replies.Recv(ctx)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		assert.NoError(t, err)
+		values = append(values, reply.Value)
+	}
+	assert.Equal(t, []int32{1, 2}, values)
+}
+
+func TestMockClientNotMocked(t *testing.T) {
+	client := &MockRpcTestClient{}
+
+	reply, err := client.Rpc(context.Background(), &TestRequest{})
+	assert.Error(t, err)
+	assert.Nil(t, reply)
+}
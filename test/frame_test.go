@@ -0,0 +1,139 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/srand/mqc"
+	"github.com/srand/mqc/serialization"
+	"github.com/srand/mqc/transport/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFrameRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+
+	writer := serialization.NewFrameWriter(&buf)
+	reader := serialization.NewFrameReader(&buf)
+
+	frames := []*serialization.Frame{
+		{Type: 4, Payload: []byte("hello")},
+		{Type: 2, Flags: serialization.FlagEndStream},
+		{Type: 4, Metadata: []byte("traceparent"), Payload: []byte("world")},
+		{Type: 4, Flags: serialization.FlagCompressed, Payload: bytes.Repeat([]byte("x"), 1<<16)},
+	}
+
+	for _, frame := range frames {
+		assert.NoError(t, writer.WriteFrame(frame))
+	}
+
+	for _, expected := range frames {
+		frame, err := reader.ReadFrame()
+		assert.NoError(t, err)
+		assert.Equal(t, byte(serialization.FrameVersion), frame.Version)
+		assert.Equal(t, expected.Type, frame.Type)
+		assert.Equal(t, expected.Flags.Has(serialization.FlagEndStream), frame.Flags.Has(serialization.FlagEndStream))
+		assert.Equal(t, len(expected.Metadata) > 0, frame.Flags.Has(serialization.FlagMetadata))
+		assert.Equal(t, string(expected.Metadata), string(frame.Metadata))
+		assert.Equal(t, string(expected.Payload), string(frame.Payload))
+	}
+}
+
+func TestFrameRejectsUnknownVersion(t *testing.T) {
+	reader := serialization.NewFrameReader(bytes.NewReader([]byte{0xA0 | 0x0F, 0, 0, 0}))
+
+	_, err := reader.ReadFrame()
+	assert.Equal(t, serialization.ErrUnsupportedVersion, err)
+
+	reader = serialization.NewFrameReader(bytes.NewReader([]byte{0x00, 0, 0, 0}))
+
+	_, err = reader.ReadFrame()
+	assert.Equal(t, serialization.ErrInvalidFrame, err)
+}
+
+func TestFrameHandshake(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	done := make(chan error)
+	go func() {
+		_, err := common.ServerHandshake(server)
+		done <- err
+	}()
+
+	version, err := common.ClientHandshake(client)
+	assert.NoError(t, err)
+	assert.Equal(t, byte(serialization.FrameVersion), version)
+	assert.NoError(t, <-done)
+}
+
+func TestFrameHandshakeVersionMismatch(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	done := make(chan error)
+	go func() {
+		_, err := common.ServerHandshake(server)
+		done <- err
+	}()
+
+	// Announce a version older than any supported one
+	go serialization.WritePreface(client, serialization.MinFrameVersion-1)
+
+	version, err := serialization.ReadPreface(client)
+	assert.NoError(t, err)
+	assert.Equal(t, byte(serialization.FrameVersion), version)
+	assert.Equal(t, serialization.ErrUnsupportedVersion, <-done)
+}
+
+// Calls exchange frames of the version negotiated in the handshake. Frames
+// of other supported versions are rejected, which is tested in serialization.
+func TestFrameNegotiatedVersion(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	done := make(chan byte)
+	go func() {
+		version, err := common.ServerHandshake(server)
+		assert.NoError(t, err)
+		done <- version
+	}()
+
+	// The peer announces the oldest supported version
+	go serialization.WritePreface(client, serialization.MinFrameVersion)
+
+	version, err := serialization.ReadPreface(client)
+	assert.NoError(t, err)
+	assert.Equal(t, byte(serialization.MinFrameVersion), version)
+	assert.Equal(t, version, <-done)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	call := common.NewConn(server, serialization.NewProtoSerializer(), version)
+
+	// Frames are sent with the negotiated version
+	go call.Send(ctx, []byte("pong"))
+	frame, err := serialization.NewFrameReader(client).ReadFrame()
+	assert.NoError(t, err)
+	assert.Equal(t, version, frame.Version)
+	assert.Equal(t, "pong", string(frame.Payload))
+
+	writer := serialization.NewVersionedFrameWriter(client, version)
+	go writer.WriteFrame(&serialization.Frame{Type: byte(mqc.Message_DATA), Payload: []byte("ping")})
+	data, err := call.Recv(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "ping", string(data))
+
+	// Frames of unsupported versions are rejected
+	writer = serialization.NewVersionedFrameWriter(client, serialization.FrameVersion+1)
+	go writer.WriteFrame(&serialization.Frame{Type: byte(mqc.Message_DATA), Payload: []byte("ping")})
+	_, err = call.Recv(ctx)
+	assert.EqualError(t, err, serialization.ErrUnsupportedVersion.Error())
+}
@@ -0,0 +1,81 @@
+package test
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/srand/mqc"
+	"github.com/srand/mqc/transport"
+	tpc "github.com/srand/mqc/transport/tcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDynamicHandlers(t *testing.T) {
+	const address = "/tmp/mqc-handlers.sock"
+	os.Remove(address)
+
+	server, err := tpc.NewTransport(
+		transport.WithProtocol("unix"),
+		transport.WithAddress(address),
+	)
+	require.NoError(t, err)
+	defer server.Close()
+
+	go server.Serve()
+	time.Sleep(100 * time.Millisecond) // Give the server some time to start
+
+	client, err := tpc.NewTransport(
+		transport.WithProtocol("unix"),
+		transport.WithAddress(address),
+	)
+	require.NoError(t, err)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	method := mqc.NewMethod("RpcTest/Rpc", mqc.MethodTypeUnary)
+
+	_, err = NewRpcTestClient(client).Rpc(ctx, &TestRequest{Value: 1})
+	assert.EqualError(t, err, mqc.ErrUnimplemented.Error())
+
+	// Handlers registered while serving take effect immediately
+	RegisterRpcTestServer(server, &tracedServer{})
+	assert.Len(t, server.Methods(), 1)
+
+	reply, err := NewRpcTestClient(client).Rpc(ctx, &TestRequest{Value: 1})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), reply.Value)
+
+	require.NoError(t, server.UnregisterHandler(method))
+	assert.Empty(t, server.Methods())
+	require.NoError(t, server.UnregisterHandler(method))
+
+	_, err = NewRpcTestClient(client).Rpc(ctx, &TestRequest{Value: 1})
+	assert.EqualError(t, err, mqc.ErrUnimplemented.Error())
+
+	assert.Error(t, server.RegisterHandler(method, nil))
+
+	// Registering while calls are handled is safe
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			RegisterRpcTestServer(server, &tracedServer{})
+			server.UnregisterHandler(method)
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := NewRpcTestClient(client).Rpc(ctx, &TestRequest{Value: int32(i)})
+			if err != nil {
+				assert.EqualError(t, err, mqc.ErrUnimplemented.Error())
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,184 @@
+package test
+
+import (
+	"context"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/srand/mqc"
+	"github.com/srand/mqc/health"
+	"github.com/srand/mqc/transport"
+	tpc "github.com/srand/mqc/transport/tcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealth(t *testing.T) {
+	const address = "/tmp/mqc-health.sock"
+	os.Remove(address)
+
+	server, err := tpc.NewTransport(transport.WithProtocol("unix"), transport.WithAddress(address))
+	require.NoError(t, err)
+	defer server.Close()
+
+	client, err := tpc.NewTransport(transport.WithProtocol("unix"), transport.WithAddress(address))
+	require.NoError(t, err)
+	defer client.Close()
+
+	RegisterRpcTestServer(server, &RpcTestServerMock{})
+	healthServer, err := health.Register(server)
+	require.NoError(t, err)
+	go server.Serve()
+	time.Sleep(100 * time.Millisecond) // Give the server some time to start
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	healthClient := health.NewHealthClient(client)
+
+	res, err := healthClient.Check(ctx, &health.HealthCheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, health.HealthCheckResponse_SERVING, res.Status)
+
+	res, err = healthClient.Check(ctx, &health.HealthCheckRequest{Service: "RpcTest"})
+	require.NoError(t, err)
+	assert.Equal(t, health.HealthCheckResponse_SERVING, res.Status)
+
+	_, err = healthClient.Check(ctx, &health.HealthCheckRequest{Service: "Unknown"})
+	assert.EqualError(t, err, health.ErrServiceUnknown.Error())
+
+	stream, err := healthClient.Watch(ctx, &health.HealthCheckRequest{Service: "RpcTest"})
+	require.NoError(t, err)
+
+	res, err = stream.Recv(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, health.HealthCheckResponse_SERVING, res.Status)
+
+	healthServer.SetServingStatus("RpcTest", health.HealthCheckResponse_NOT_SERVING)
+	res, err = stream.Recv(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, health.HealthCheckResponse_NOT_SERVING, res.Status)
+
+	healthServer.SetServingStatus("RpcTest", health.HealthCheckResponse_SERVING)
+	res, err = stream.Recv(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, health.HealthCheckResponse_SERVING, res.Status)
+
+	// Shutdown reports not serving and ends the watch
+	healthServer.Shutdown()
+	res, err = stream.Recv(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, health.HealthCheckResponse_NOT_SERVING, res.Status)
+
+	_, err = stream.Recv(ctx)
+	assert.ErrorIs(t, err, io.EOF)
+
+	// Status changes are ignored until the server resumes
+	healthServer.SetServingStatus("RpcTest", health.HealthCheckResponse_SERVING)
+	res, err = healthClient.Check(ctx, &health.HealthCheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, health.HealthCheckResponse_NOT_SERVING, res.Status)
+
+	healthServer.Resume()
+	res, err = healthClient.Check(ctx, &health.HealthCheckRequest{Service: "RpcTest"})
+	require.NoError(t, err)
+	assert.Equal(t, health.HealthCheckResponse_SERVING, res.Status)
+}
+
+// healthStream is a server stream delivering responses on a channel.
+type healthStream chan *health.HealthCheckResponse
+
+func (s healthStream) Send(ctx context.Context, res *health.HealthCheckResponse) error {
+	s <- res
+	return nil
+}
+
+func TestHealthWatchUnknownService(t *testing.T) {
+	server := health.NewServer()
+	stream := make(healthStream)
+
+	done := make(chan error)
+	go func() {
+		done <- server.Watch(context.Background(), &health.HealthCheckRequest{Service: "Plugin"}, stream)
+	}()
+
+	// Unknown services are watched until their status is set
+	assert.Equal(t, health.HealthCheckResponse_SERVICE_UNKNOWN, (<-stream).Status)
+
+	server.SetServingStatus("Plugin", health.HealthCheckResponse_SERVING)
+	assert.Equal(t, health.HealthCheckResponse_SERVING, (<-stream).Status)
+
+	server.ClearServingStatus("Plugin")
+	assert.Equal(t, health.HealthCheckResponse_SERVICE_UNKNOWN, (<-stream).Status)
+
+	server.Shutdown()
+	require.NoError(t, <-done)
+}
+
+// watchedHealthServer reports when its watches end.
+type watchedHealthServer struct {
+	*health.Server
+	ended chan error
+}
+
+func (s *watchedHealthServer) Watch(ctx context.Context, req *health.HealthCheckRequest, stream mqc.ServerStreamServer[health.HealthCheckResponse]) error {
+	err := s.Server.Watch(ctx, req, stream)
+	s.ended <- err
+	return err
+}
+
+func TestHealthWatchDisconnect(t *testing.T) {
+	const address = "/tmp/mqc-health-watch.sock"
+	os.Remove(address)
+
+	server, err := tpc.NewTransport(transport.WithProtocol("unix"), transport.WithAddress(address))
+	require.NoError(t, err)
+	defer server.Close()
+
+	client, err := tpc.NewTransport(transport.WithProtocol("unix"), transport.WithAddress(address))
+	require.NoError(t, err)
+	defer client.Close()
+
+	healthServer := &watchedHealthServer{Server: health.NewServer(), ended: make(chan error, 1)}
+	health.RegisterHealthServer(server, healthServer)
+	go server.Serve()
+	time.Sleep(100 * time.Millisecond) // Give the server some time to start
+
+	healthClient := health.NewHealthClient(client)
+
+	watch := func(ctx context.Context) mqc.ServerStreamClient[health.HealthCheckResponse] {
+		stream, err := healthClient.Watch(ctx, &health.HealthCheckRequest{})
+		require.NoError(t, err)
+		res, err := stream.Recv(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, health.HealthCheckResponse_SERVING, res.Status)
+		return stream
+	}
+
+	ended := func() error {
+		select {
+		case err := <-healthServer.ended:
+			return err
+		case <-time.After(time.Second):
+			t.Fatal("watch did not end")
+			return nil
+		}
+	}
+
+	// Watches end when the client cancels the call
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	stream := watch(ctx)
+	cancel()
+	_, err = stream.Recv(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.ErrorIs(t, ended(), context.Canceled)
+
+	// Or disconnects
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	watch(ctx)
+	client.Close()
+	assert.ErrorIs(t, ended(), context.Canceled)
+}
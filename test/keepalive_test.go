@@ -0,0 +1,134 @@
+package test
+
+import (
+	"context"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/srand/mqc/transport"
+	tpc "github.com/srand/mqc/transport/tcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdleTimeoutReconnects(t *testing.T) {
+	const address = "/tmp/mqc-idle.sock"
+	os.Remove(address)
+
+	options := []transport.TransportOption{
+		transport.WithProtocol("unix"),
+		transport.WithAddress(address),
+		transport.WithIdleTimeout(100 * time.Millisecond),
+		transport.WithKeepAlive(50*time.Millisecond, time.Second),
+	}
+
+	server, err := tpc.NewTransport(options...)
+	require.NoError(t, err)
+	defer server.Close()
+
+	client, err := tpc.NewTransport(options...)
+	require.NoError(t, err)
+	defer client.Close()
+
+	serverMock := &RpcTestServerMock{}
+	serverMock.On("Rpc", mock.Anything).Return(&TestReply{Value: 1}, nil)
+
+	RegisterRpcTestServer(server, serverMock)
+	go server.Serve()
+	time.Sleep(100 * time.Millisecond) // Give the server some time to start
+
+	rpcClient := NewRpcTestClient(client)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	_, err = rpcClient.Rpc(ctx, &TestRequest{})
+	require.NoError(t, err)
+
+	// Keepalive pings do not keep the idle connection open
+	time.Sleep(300 * time.Millisecond)
+
+	_, err = rpcClient.Rpc(ctx, &TestRequest{})
+	require.NoError(t, err)
+	serverMock.AssertNumberOfCalls(t, "Rpc", 2)
+}
+
+// concurrencyServer counts the calls running concurrently.
+type concurrencyServer struct {
+	running atomic.Int32
+	max     atomic.Int32
+}
+
+func (s *concurrencyServer) Rpc(ctx context.Context, req *TestRequest) (*TestReply, error) {
+	n := s.running.Add(1)
+	defer s.running.Add(-1)
+
+	for {
+		m := s.max.Load()
+		if n <= m || s.max.CompareAndSwap(m, n) {
+			break
+		}
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	return &TestReply{Value: req.Value}, nil
+}
+
+func TestMaxConcurrentStreams(t *testing.T) {
+	const address = "/tmp/mqc-streams.sock"
+	os.Remove(address)
+
+	server, err := tpc.NewTransport(
+		transport.WithProtocol("unix"),
+		transport.WithAddress(address),
+		transport.WithMaxConcurrentStreams(2),
+		transport.WithStreamWindowSize(1024*1024),
+	)
+	require.NoError(t, err)
+	defer server.Close()
+
+	client, err := tpc.NewTransport(transport.WithProtocol("unix"), transport.WithAddress(address))
+	require.NoError(t, err)
+	defer client.Close()
+
+	impl := &concurrencyServer{}
+	RegisterRpcTestServer(server, impl)
+	go server.Serve()
+	time.Sleep(100 * time.Millisecond) // Give the server some time to start
+
+	rpcClient := NewRpcTestClient(client)
+	require.NoError(t, client.Dial())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	errs := make(chan error)
+	for i := 0; i < 4; i++ {
+		go func() {
+			_, err := rpcClient.Rpc(ctx, &TestRequest{Value: int32(i)})
+			errs <- err
+		}()
+	}
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, <-errs)
+	}
+
+	assert.Equal(t, int32(2), impl.max.Load())
+}
+
+func TestTransportOptionValidation(t *testing.T) {
+	_, err := tpc.NewTransport(transport.WithAddress("localhost:0"), transport.WithKeepAlive(-time.Second, 0))
+	assert.Error(t, err)
+
+	_, err = tpc.NewTransport(transport.WithAddress("localhost:0"), transport.WithIdleTimeout(-time.Second))
+	assert.Error(t, err)
+
+	_, err = tpc.NewTransport(transport.WithAddress("localhost:0"), transport.WithMaxConcurrentStreams(-1))
+	assert.Error(t, err)
+
+	_, err = tpc.NewTransport(transport.WithAddress("localhost:0"), transport.WithStreamWindowSize(1024))
+	assert.Error(t, err)
+}
@@ -0,0 +1,118 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/srand/mqc"
+	"github.com/srand/mqc/credentials"
+	"github.com/srand/mqc/transport"
+	tpc "github.com/srand/mqc/transport/tcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// logBuffer collects the output of a logger written from server goroutines.
+type logBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *logBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// panicServer panics in every call.
+type panicServer struct{}
+
+func (s *panicServer) Stream(ctx context.Context, req *TestRequest, stream mqc.ServerStreamServer[TestReply]) error {
+	panic("boom")
+}
+
+func TestLogging(t *testing.T) {
+	const address = "/tmp/mqc-logging.sock"
+	os.Remove(address)
+
+	var logs logBuffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	server, err := tpc.NewTransport(
+		transport.WithProtocol("unix"),
+		transport.WithAddress(address),
+		transport.WithLogger(logger),
+		transport.WithAuthenticator(credentials.StaticTokens(map[string]*mqc.Identity{
+			"token": {Subject: "alice"},
+		})),
+	)
+	require.NoError(t, err)
+	defer server.Close()
+
+	RegisterRpcTestServer(server, &tracedServer{})
+	RegisterClientStreamTestServer(server, &UnimplementedClientStreamTestServer{})
+	RegisterServerStreamTestServer(server, &panicServer{})
+	go server.Serve()
+	time.Sleep(100 * time.Millisecond) // Give the server some time to start
+
+	newClient := func(options ...transport.TransportOption) mqc.Transport {
+		client, err := tpc.NewTransport(append([]transport.TransportOption{
+			transport.WithProtocol("unix"),
+			transport.WithAddress(address),
+		}, options...)...)
+		require.NoError(t, err)
+		t.Cleanup(func() { client.Close() })
+		return client
+	}
+
+	client := newClient(transport.WithCredentials(credentials.BearerToken("token")))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	_, err = NewRpcTestClient(client).Rpc(ctx, &TestRequest{Value: 1})
+	require.NoError(t, err)
+	_, err = NewRpcTestClient(client).Rpc(ctx, &TestRequest{Value: -1})
+	require.Error(t, err)
+
+	// Unknown methods and unimplemented stubs fail explicitly
+	_, err = mqc.Rpc[TestRequest, TestReply](ctx, client, mqc.NewMethod("BidiStreamTest/Stream", mqc.MethodTypeBidiStream), &TestRequest{})
+	assert.EqualError(t, err, mqc.ErrUnimplemented.Error())
+
+	stream, err := NewClientStreamTestClient(client).Stream(ctx)
+	require.NoError(t, err)
+	_, err = stream.CloseAndRecv(ctx)
+	assert.EqualError(t, err, mqc.ErrUnimplemented.Error())
+
+	serverStream, err := NewServerStreamTestClient(client).Stream(ctx, &TestRequest{Value: 1})
+	require.NoError(t, err)
+	_, err = serverStream.Recv(ctx)
+	assert.EqualError(t, err, mqc.ErrInternal.Error())
+
+	_, err = NewRpcTestClient(newClient()).Rpc(ctx, &TestRequest{Value: 1})
+	assert.EqualError(t, err, mqc.ErrUnauthenticated.Error())
+
+	assert.Eventually(t, func() bool {
+		return strings.Contains(logs.String(), `msg="panic in handler"`) && strings.Contains(logs.String(), `msg="call rejected"`)
+	}, time.Second, 10*time.Millisecond)
+
+	output := logs.String()
+	assert.Regexp(t, `level=DEBUG msg="call completed" peer.addr=\S+ .*call_id=\d+ method=RpcTest/Rpc\n`, output)
+	assert.Regexp(t, `level=DEBUG msg="call failed" .*call_id=\d+ method=RpcTest/Rpc error="server error"`, output)
+	assert.Regexp(t, `level=WARN msg="unimplemented method" .*call_id=\d+ method=BidiStreamTest/Stream`, output)
+	assert.Regexp(t, `level=DEBUG msg="call failed" .* method=ClientStreamTest/Stream error="method not implemented"`, output)
+	assert.Regexp(t, `level=ERROR msg="panic in handler" .* method=ServerStreamTest/Stream panic=boom stack=`, output)
+	assert.Regexp(t, `level=WARN msg="call rejected" .* method=RpcTest/Rpc error=unauthenticated`, output)
+}
@@ -0,0 +1,89 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/srand/mqc"
+	"github.com/srand/mqc/serialization"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+)
+
+var methodTypes = []int{
+	mqc.MethodTypeUnary,
+	mqc.MethodTypeServerStream,
+	mqc.MethodTypeClientStream,
+	mqc.MethodTypeBidiStream,
+	mqc.MethodTypePublisher,
+	mqc.MethodTypeConsumer,
+}
+
+func TestNewMethod(t *testing.T) {
+	method := mqc.NewMethod("helloworld.Greeter/SayHello", mqc.MethodTypeServerStream)
+	assert.Equal(t, "helloworld.Greeter", method.Service)
+	assert.Equal(t, "SayHello", method.Name)
+	assert.Equal(t, mqc.MethodTypeServerStream, method.Type)
+	assert.Equal(t, "helloworld.Greeter/SayHello", method.FullName())
+}
+
+func TestMethodInvokeRoundTrip(t *testing.T) {
+	serializers := map[string]serialization.Serializer{
+		"proto": serialization.NewProtoSerializer(),
+		"json":  serialization.NewJSONSerializer(),
+	}
+
+	for name, serializer := range serializers {
+		for _, methodType := range methodTypes {
+			method := mqc.NewMethod("RpcTest/Rpc", methodType)
+
+			md := mqc.Metadata{"authorization": "Bearer token"}
+
+			msg, err := mqc.NewCallMessage(method, md)
+			assert.NoError(t, err)
+
+			// Control messages are serialized with the transport serializer
+			data, err := serializer.Marshal(msg)
+			assert.NoError(t, err, name)
+
+			var decoded mqc.Message
+			assert.NoError(t, serializer.Unmarshal(data, &decoded), name)
+
+			result, resultMd, err := decoded.Invoke()
+			assert.NoError(t, err, name)
+			assert.Equal(t, *method, *result, name)
+			assert.Equal(t, md, resultMd, name)
+		}
+	}
+}
+
+func TestMethodInvokeDistinguishesTypes(t *testing.T) {
+	handlers := map[mqc.Method]int{}
+	for _, methodType := range methodTypes {
+		handlers[*mqc.NewMethod("Weather/Update", methodType)] = methodType
+	}
+
+	for _, methodType := range methodTypes {
+		msg, err := mqc.NewCallMessage(mqc.NewMethod("Weather/Update", methodType), nil)
+		assert.NoError(t, err)
+
+		method, err := msg.Method()
+		assert.NoError(t, err)
+		assert.Equal(t, methodType, handlers[*method])
+	}
+}
+
+func TestMethodInvokeRejectsUnknownProtocol(t *testing.T) {
+	invoke := mqc.NewMethod("RpcTest/Rpc", mqc.MethodTypeUnary).Invoke()
+	invoke.ProtocolVersion = mqc.ProtocolVersion + 1
+
+	data, err := proto.Marshal(invoke)
+	assert.NoError(t, err)
+
+	msg := &mqc.Message{Type: mqc.Message_INVOKE, Data: data}
+	_, err = msg.Method()
+	assert.Equal(t, mqc.ErrUnsupportedProtocol, err)
+
+	msg = &mqc.Message{Type: mqc.Message_DATA, Data: data}
+	_, err = msg.Method()
+	assert.Equal(t, mqc.ErrProtocolViolation, err)
+}
@@ -0,0 +1,131 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/srand/mqc"
+	"github.com/srand/mqc/metrics"
+	"github.com/srand/mqc/transport"
+	tpc "github.com/srand/mqc/transport/tcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics(t *testing.T) {
+	const address = "/tmp/mqc-metrics.sock"
+	os.Remove(address)
+
+	registry := metrics.NewRegistry()
+	m := metrics.New(registry)
+
+	server, err := tpc.NewTransport(
+		transport.WithProtocol("unix"),
+		transport.WithAddress(address),
+		transport.WithMetrics(m),
+	)
+	require.NoError(t, err)
+	defer server.Close()
+
+	RegisterRpcTestServer(server, &tracedServer{})
+	RegisterServerStreamTestServer(server, &serverStreamServer{})
+	go server.Serve()
+	time.Sleep(100 * time.Millisecond) // Give the server some time to start
+
+	client, err := tpc.NewTransport(
+		transport.WithProtocol("unix"),
+		transport.WithAddress(address),
+		transport.WithMetrics(m),
+	)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	_, err = NewRpcTestClient(client).Rpc(ctx, &TestRequest{Value: 1})
+	require.NoError(t, err)
+	_, err = NewRpcTestClient(client).Rpc(ctx, &TestRequest{Value: -1})
+	require.Error(t, err)
+
+	stream, err := NewServerStreamTestClient(client).Stream(ctx, &TestRequest{Value: 3})
+	require.NoError(t, err)
+	for {
+		_, err := stream.Recv(ctx)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+	}
+
+	// Both sessions of the connection are open
+	assert.Equal(t, 2.0, registry.Value("mqc_sessions_active", "unix"))
+
+	for _, role := range []string{metrics.RoleClient, metrics.RoleServer} {
+		assert.Equal(t, 2.0, registry.Value("mqc_calls_started_total", role, "unix", "RpcTest", "Rpc", "unary"), role)
+		assert.Eventually(t, func() bool {
+			return registry.Value("mqc_calls_completed_total", role, "unix", "RpcTest", "Rpc", "unary", "ok") == 1 &&
+				registry.Value("mqc_calls_completed_total", role, "unix", "RpcTest", "Rpc", "unary", "unknown") == 1 &&
+				registry.Value("mqc_calls_completed_total", role, "unix", "ServerStreamTest", "Stream", "server-stream", "ok") == 1
+		}, time.Second, 10*time.Millisecond, role)
+		assert.Equal(t, 2.0, registry.Value("mqc_call_duration_seconds", role, "unix", "RpcTest", "Rpc"), role)
+	}
+
+	assert.Contains(t, scrape(t, registry), `mqc_call_messages_received_bucket{role="client",transport="unix",service="ServerStreamTest",method="Stream",le="2"} 0`)
+	assert.Contains(t, scrape(t, registry), `mqc_call_messages_received_bucket{role="client",transport="unix",service="ServerStreamTest",method="Stream",le="5"} 1`)
+	assert.Contains(t, scrape(t, registry), `mqc_call_messages_sent_count{role="server",transport="unix",service="ServerStreamTest",method="Stream"} 1`)
+
+	assert.Greater(t, registry.Value("mqc_bytes_sent_total", "unix"), 0.0)
+	assert.Greater(t, registry.Value("mqc_bytes_received_total", "unix"), 0.0)
+
+	// Streams and sessions are closed with the connection
+	client.Close()
+	assert.Eventually(t, func() bool {
+		return registry.Value("mqc_sessions_active", "unix") == 0 && registry.Value("mqc_streams_active", "unix") == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestMetricsRegistry(t *testing.T) {
+	registry := metrics.NewRegistry()
+
+	counter := registry.Counter("test_total", "A counter.", "name")
+	counter.Add(1, `a "quoted" name`)
+	counter.Add(2, `a "quoted" name`)
+	assert.Same(t, counter, registry.Counter("test_total", "A counter.", "name"))
+	assert.Panics(t, func() { registry.Gauge("test_total", "A gauge.") })
+
+	histogram := registry.Histogram("test_seconds", "A histogram.", []float64{1, 0.5})
+	histogram.Observe(0.25)
+	histogram.Observe(0.75)
+	histogram.Observe(2)
+
+	assert.Equal(t, `# HELP test_seconds A histogram.
+# TYPE test_seconds histogram
+test_seconds_bucket{le="0.5"} 1
+test_seconds_bucket{le="1"} 2
+test_seconds_bucket{le="+Inf"} 3
+test_seconds_sum 3
+test_seconds_count 3
+# HELP test_total A counter.
+# TYPE test_total counter
+test_total{name="a \"quoted\" name"} 3
+`, scrape(t, registry))
+
+	assert.Equal(t, "ok", metrics.Code(nil))
+	assert.Equal(t, "deadline_exceeded", metrics.Code(context.DeadlineExceeded))
+	assert.Equal(t, "permission_denied", metrics.Code(errors.New(mqc.ErrPermissionDenied.Error())))
+	assert.Equal(t, "unknown", metrics.Code(errors.New("server error")))
+}
+
+// scrape returns the metrics served by the registry.
+func scrape(t *testing.T, registry *metrics.MemoryRegistry) string {
+	recorder := httptest.NewRecorder()
+	registry.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	assert.True(t, strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/plain"))
+	return recorder.Body.String()
+}
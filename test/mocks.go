@@ -1,6 +1,8 @@
 package test
 
 import (
+	"context"
+
 	"github.com/srand/mqc"
 	"github.com/stretchr/testify/mock"
 )
@@ -9,7 +11,7 @@ type RpcTestServerMock struct {
 	mock.Mock
 }
 
-func (s *RpcTestServerMock) Rpc(req *TestRequest) (*TestReply, error) {
+func (s *RpcTestServerMock) Rpc(ctx context.Context, req *TestRequest) (*TestReply, error) {
 	args := s.Called(req)
 	reply := args.Get(0)
 	if reply == nil {
@@ -22,7 +24,7 @@ type ServerStreamTestServerMock struct {
 	mock.Mock
 }
 
-func (s *ServerStreamTestServerMock) Stream(req *TestRequest, stream mqc.ServerStreamServer[TestReply]) error {
+func (s *ServerStreamTestServerMock) Stream(ctx context.Context, req *TestRequest, stream mqc.ServerStreamServer[TestReply]) error {
 	args := s.Called(req, stream)
 	return args.Error(0)
 }
@@ -31,7 +33,7 @@ type ClientStreamTestServerMock struct {
 	mock.Mock
 }
 
-func (s *ClientStreamTestServerMock) Stream(stream mqc.ClientStreamServer[TestRequest, TestReply]) error {
+func (s *ClientStreamTestServerMock) Stream(ctx context.Context, stream mqc.ClientStreamServer[TestRequest, TestReply]) error {
 	args := s.Called(stream)
 	return args.Error(0)
 }
@@ -40,7 +42,7 @@ type BidiStreamTestServerMock struct {
 	mock.Mock
 }
 
-func (s *BidiStreamTestServerMock) Stream(stream mqc.BidiStreamServer[TestRequest, TestReply]) error {
+func (s *BidiStreamTestServerMock) Stream(ctx context.Context, stream mqc.BidiStreamServer[TestRequest, TestReply]) error {
 	args := s.Called(stream)
 	return args.Error(0)
 }
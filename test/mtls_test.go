@@ -0,0 +1,227 @@
+package test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/srand/mqc"
+	"github.com/srand/mqc/transport"
+	mqc_http "github.com/srand/mqc/transport/http"
+	tpc "github.com/srand/mqc/transport/tcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCA issues certificates for tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	return &testCA{cert: cert, key: key, pool: pool}
+}
+
+func (ca *testCA) issue(t *testing.T, commonName string, uris ...string) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName, Organization: []string{"mqc"}},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	for _, uri := range uris {
+		parsed, err := url.Parse(uri)
+		require.NoError(t, err)
+		template.URIs = append(template.URIs, parsed)
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// peerServer echoes requests and records the peer and identity of the
+// first caller not yet received by the test.
+type peerServer struct {
+	peer     chan *mqc.Peer
+	identity chan *mqc.Identity
+}
+
+func newPeerServer() *peerServer {
+	return &peerServer{
+		peer:     make(chan *mqc.Peer, 1),
+		identity: make(chan *mqc.Identity, 1),
+	}
+}
+
+func (s *peerServer) Rpc(ctx context.Context, req *TestRequest) (*TestReply, error) {
+	select {
+	case s.peer <- mqc.PeerFromContext(ctx):
+		s.identity <- mqc.IdentityFromContext(ctx)
+	default:
+	}
+	return &TestReply{Value: req.Value}, nil
+}
+
+func startPeerServer(t *testing.T, server mqc.Transport) *peerServer {
+	t.Cleanup(func() { server.Close() })
+
+	impl := newPeerServer()
+	RegisterRpcTestServer(server, impl)
+
+	go server.Serve()
+	time.Sleep(100 * time.Millisecond) // Give the server some time to start
+
+	return impl
+}
+
+func callWithOptions(t *testing.T, options ...transport.TransportOption) error {
+	client, err := tpc.NewTransport(options...)
+	require.NoError(t, err)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	_, err = NewRpcTestClient(client).Rpc(ctx, &TestRequest{Value: 1})
+	return err
+}
+
+func TestMutualTLS(t *testing.T) {
+	const address = "localhost:8093"
+
+	ca := newTestCA(t)
+
+	server, err := tpc.NewTransport(
+		transport.WithAddress(address),
+		transport.WithTLSConfig(&tls.Config{Certificates: []tls.Certificate{ca.issue(t, "server")}}),
+		transport.WithClientCAs(ca.pool),
+		transport.WithAllowedSANs("spiffe://mqc/client"),
+	)
+	require.NoError(t, err)
+	impl := startPeerServer(t, server)
+
+	clientConfig := func(certificates ...tls.Certificate) transport.TransportOption {
+		return transport.WithTLSConfig(&tls.Config{RootCAs: ca.pool, Certificates: certificates})
+	}
+
+	// Clients without an allowed certificate are rejected in the TLS handshake
+	assert.Error(t, callWithOptions(t, transport.WithAddress(address), clientConfig()))
+	assert.Error(t, callWithOptions(t, transport.WithAddress(address), clientConfig(ca.issue(t, "other", "spiffe://mqc/other"))))
+	assert.Error(t, callWithOptions(t, transport.WithAddress(address), clientConfig(newTestCA(t).issue(t, "client", "spiffe://mqc/client"))))
+
+	err = callWithOptions(t, transport.WithAddress(address), clientConfig(ca.issue(t, "client", "spiffe://mqc/client")))
+	require.NoError(t, err)
+
+	peer := <-impl.peer
+	require.NotNil(t, peer)
+	assert.NotNil(t, peer.Addr)
+	require.NotNil(t, peer.TLS)
+	assert.Len(t, peer.TLS.PeerCertificates, 1)
+
+	// The certificate identity is the caller identity without an authenticator
+	identity := <-impl.identity
+	require.NotNil(t, identity)
+	assert.Equal(t, "client", identity.Subject)
+	assert.Equal(t, "mqc", identity.Attributes["organization"])
+	assert.Equal(t, "spiffe://mqc/client,localhost", identity.Attributes["san"])
+	assert.Equal(t, identity, peer.Identity)
+}
+
+func TestUnixPeerCredentials(t *testing.T) {
+	const address = "/tmp/mqc-peer.sock"
+	os.Remove(address)
+
+	options := []transport.TransportOption{
+		transport.WithProtocol("unix"),
+		transport.WithAddress(address),
+	}
+
+	server, err := tpc.NewTransport(options...)
+	require.NoError(t, err)
+	impl := startPeerServer(t, server)
+
+	require.NoError(t, callWithOptions(t, options...))
+
+	peer := <-impl.peer
+	require.NotNil(t, peer)
+	assert.Nil(t, peer.TLS)
+	assert.Nil(t, <-impl.identity)
+
+	if runtime.GOOS != "linux" {
+		assert.Nil(t, peer.Unix)
+		return
+	}
+
+	require.NotNil(t, peer.Unix)
+	assert.Equal(t, os.Getpid(), peer.Unix.Pid)
+	assert.Equal(t, os.Getuid(), peer.Unix.Uid)
+	assert.Equal(t, os.Getgid(), peer.Unix.Gid)
+}
+
+func TestWebSocketPeer(t *testing.T) {
+	const address = "ws://localhost:8094/mqc"
+
+	server, err := mqc_http.NewWebSocketTransport(transport.WithAddress(address))
+	require.NoError(t, err)
+	impl := startPeerServer(t, server)
+
+	client, err := mqc_http.NewWebSocketTransport(transport.WithAddress(address), transport.WithOrigin("http://localhost/"))
+	require.NoError(t, err)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	_, err = NewRpcTestClient(client).Rpc(ctx, &TestRequest{Value: 1})
+	require.NoError(t, err)
+
+	peer := <-impl.peer
+	require.NotNil(t, peer)
+	require.NotNil(t, peer.Addr)
+	require.IsType(t, &net.TCPAddr{}, peer.Addr)
+	assert.True(t, peer.Addr.(*net.TCPAddr).IP.IsLoopback())
+	assert.Nil(t, peer.TLS)
+}
@@ -0,0 +1,60 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/srand/mqc"
+	"github.com/srand/mqc/serialization"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMethodOptions(t *testing.T) {
+	get := mqc.GetMethodOptions(mqc.NewMethod("OptionsTest/Get", mqc.MethodTypeUnary))
+	assert.NotNil(t, get)
+	assert.Equal(t, 2*time.Second, get.Timeout)
+	assert.True(t, get.Idempotent)
+	assert.Nil(t, get.Serializer)
+
+	watch := mqc.GetMethodOptions(mqc.NewMethod("OptionsTest/Watch", mqc.MethodTypeServerStream))
+	assert.NotNil(t, watch)
+	assert.False(t, watch.Idempotent)
+	assert.Equal(t, serialization.NewJSONSerializer(), watch.Serializer)
+
+	assert.Nil(t, mqc.GetMethodOptions(mqc.NewMethod("RpcTest/Rpc", mqc.MethodTypeUnary)))
+}
+
+func TestMethodOptionsPubSub(t *testing.T) {
+	for _, methodType := range []int{mqc.MethodTypePublisher, mqc.MethodTypeConsumer} {
+		method := mqc.NewMethod("OptionsTest/Events", methodType)
+
+		options := mqc.GetMethodOptions(method)
+		assert.NotNil(t, options)
+		assert.Equal(t, mqc.QoSAtLeastOnce, options.QoS)
+		assert.Equal(t, "events/OptionsTest/Events", method.Topic())
+	}
+
+	assert.Equal(t, "", mqc.NewMethod("OptionsTest/Get", mqc.MethodTypeUnary).Topic())
+}
+
+func TestWithDefaultTimeout(t *testing.T) {
+	ctx, cancel := mqc.WithDefaultTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	deadline, ok := ctx.Deadline()
+	assert.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(time.Second), deadline, 100*time.Millisecond)
+
+	parent, cancelParent := context.WithTimeout(context.Background(), time.Minute)
+	defer cancelParent()
+
+	ctx, cancel = mqc.WithDefaultTimeout(parent, time.Second)
+	defer cancel()
+	assert.Equal(t, parent, ctx)
+
+	ctx, cancel = mqc.WithDefaultTimeout(context.Background(), 0)
+	defer cancel()
+	_, ok = ctx.Deadline()
+	assert.False(t, ok)
+}
@@ -0,0 +1,88 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/srand/mqc"
+	"github.com/srand/mqc/metrics"
+	"github.com/srand/mqc/transport"
+	tpc "github.com/srand/mqc/transport/tcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPanicRecovery(t *testing.T) {
+	const address = "/tmp/mqc-panic.sock"
+	os.Remove(address)
+
+	panics := make(chan *mqc.PanicError, 1)
+	registry := metrics.NewRegistry()
+
+	server, err := tpc.NewTransport(
+		transport.WithProtocol("unix"),
+		transport.WithAddress(address),
+		transport.WithMetrics(metrics.New(registry)),
+		transport.WithPanicHandler(func(ctx context.Context, p *mqc.PanicError) {
+			panics <- p
+		}),
+	)
+	require.NoError(t, err)
+	defer server.Close()
+
+	RegisterServerStreamTestServer(server, &panicServer{})
+	RegisterRpcTestServer(server, &tracedServer{})
+	go server.Serve()
+	time.Sleep(100 * time.Millisecond) // Give the server some time to start
+
+	client, err := tpc.NewTransport(
+		transport.WithProtocol("unix"),
+		transport.WithAddress(address),
+	)
+	require.NoError(t, err)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// The caller receives an internal error instead of waiting for the timeout
+	stream, err := NewServerStreamTestClient(client).Stream(ctx, &TestRequest{Value: 1})
+	require.NoError(t, err)
+	_, err = stream.Recv(ctx)
+	assert.EqualError(t, err, mqc.ErrInternal.Error())
+	assert.NoError(t, ctx.Err())
+
+	select {
+	case p := <-panics:
+		assert.Equal(t, "ServerStreamTest/Stream", p.Method.FullName())
+		assert.Equal(t, "boom", p.Value)
+		assert.True(t, errors.Is(p, mqc.ErrInternal))
+		assert.True(t, strings.Contains(string(p.Stack), "panicServer"), "stack contains the panicking handler")
+	case <-time.After(time.Second):
+		t.Fatal("panic handler was not called")
+	}
+
+	assert.Eventually(t, func() bool {
+		return registry.Value("mqc_calls_completed_total", metrics.RoleServer, "unix", "ServerStreamTest", "Stream", "server-stream", "internal") == 1
+	}, time.Second, 10*time.Millisecond)
+
+	// The server keeps serving other calls
+	reply, err := NewRpcTestClient(client).Rpc(ctx, &TestRequest{Value: 1})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), reply.Value)
+}
+
+func TestPanicOptions(t *testing.T) {
+	_, err := tpc.NewTransport(transport.WithPanicPolicy(mqc.PanicPolicy(42)))
+	assert.Error(t, err)
+
+	_, err = tpc.NewTransport(transport.WithPanicHandler(nil))
+	assert.Error(t, err)
+
+	_, err = tpc.NewTransport(transport.WithAddress("localhost:0"), transport.WithPanicPolicy(mqc.PanicRepanic))
+	assert.NoError(t, err)
+}
@@ -0,0 +1,117 @@
+package test
+
+import (
+	"crypto/tls"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/srand/mqc"
+	"github.com/srand/mqc/transport"
+	tpc "github.com/srand/mqc/transport/tcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrustOnFirstUse(t *testing.T) {
+	const address = "localhost:8095"
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "server.pem")
+	keyFile := filepath.Join(dir, "server.key")
+	knownFile := filepath.Join(dir, "known_keys")
+
+	serve := func(certFile, keyFile string) func() {
+		server, err := tpc.NewTransport(
+			transport.WithAddress(address),
+			transport.WithPersistentSelfSignedCert(certFile, keyFile, "localhost"),
+		)
+		require.NoError(t, err)
+
+		RegisterRpcTestServer(server, newPeerServer())
+		go server.Serve()
+		time.Sleep(100 * time.Millisecond) // Give the server some time to start
+
+		return func() { server.Close() }
+	}
+
+	pinned := []transport.TransportOption{
+		transport.WithAddress(address),
+		transport.WithPublicKeyPin(mqc.TrustOnFirstUse(knownFile)),
+	}
+
+	stop := serve(certFile, keyFile)
+
+	// The generated certificate is not trusted without a pin
+	assert.Error(t, callWithOptions(t, transport.WithAddress(address), transport.WithTLSConfig(&tls.Config{})))
+
+	// The key is trusted on first use and saved
+	require.NoError(t, callWithOptions(t, pinned...))
+
+	known, err := os.ReadFile(knownFile)
+	require.NoError(t, err)
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	require.NoError(t, err)
+	assert.Equal(t, address+" "+mqc.SPKIFingerprint(cert.Leaf)+"\n", string(known))
+
+	// The key pair is kept across restarts
+	stop()
+	stop = serve(certFile, keyFile)
+	require.NoError(t, callWithOptions(t, pinned...))
+	require.NoError(t, callWithOptions(t, transport.WithAddress(address), transport.WithPublicKeyPin(mqc.PinFingerprints(mqc.SPKIFingerprint(cert.Leaf)))))
+
+	// A server with another key is rejected
+	stop()
+	stop = serve(filepath.Join(dir, "other.pem"), filepath.Join(dir, "other.key"))
+	defer stop()
+
+	assert.ErrorIs(t, callWithOptions(t, pinned...), mqc.ErrPinMismatch)
+	assert.ErrorIs(t, callWithOptions(t, transport.WithAddress(address), transport.WithPublicKeyPin(mqc.PinFingerprints(mqc.SPKIFingerprint(cert.Leaf)))), mqc.ErrPinMismatch)
+}
+
+func TestCertificateAuthority(t *testing.T) {
+	const address = "localhost:8096"
+
+	dir := t.TempDir()
+	caCertFile := filepath.Join(dir, "ca.pem")
+	caKeyFile := filepath.Join(dir, "ca.key")
+
+	ca, err := mqc.LoadOrCreateCertificateAuthority(caCertFile, caKeyFile, "Test CA", time.Hour)
+	require.NoError(t, err)
+
+	// The authority is loaded from the files once created
+	loaded, err := mqc.LoadOrCreateCertificateAuthority(caCertFile, caKeyFile, "Test CA", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, ca.Certificate.Raw, loaded.Certificate.Raw)
+
+	info, err := os.Stat(caKeyFile)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	cert, err := loaded.Issue(time.Hour, "localhost", "127.0.0.1", "spiffe://mqc/server", "admin@example.org")
+	require.NoError(t, err)
+	assert.Equal(t, "localhost", cert.Leaf.Subject.CommonName)
+	assert.Equal(t, []string{"localhost"}, cert.Leaf.DNSNames)
+	assert.Equal(t, "127.0.0.1", cert.Leaf.IPAddresses[0].String())
+	assert.Equal(t, "spiffe://mqc/server", cert.Leaf.URIs[0].String())
+	assert.Equal(t, []string{"admin@example.org"}, cert.Leaf.EmailAddresses)
+
+	server, err := tpc.NewTransport(
+		transport.WithAddress(address),
+		transport.WithTLSConfig(&tls.Config{Certificates: []tls.Certificate{cert}}),
+	)
+	require.NoError(t, err)
+	startPeerServer(t, server)
+
+	require.NoError(t, callWithOptions(t, transport.WithAddress(address), transport.WithRootCAFile(caCertFile)))
+
+	other, err := mqc.NewCertificateAuthority("Other CA", time.Hour)
+	require.NoError(t, err)
+
+	err = callWithOptions(t, transport.WithAddress(address), transport.WithRootCAs(other.CertPool()))
+	require.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "certificate"), err.Error())
+}
@@ -0,0 +1,71 @@
+package test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/srand/mqc/reflection"
+	"github.com/srand/mqc/transport"
+	tpc "github.com/srand/mqc/transport/tcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestReflection(t *testing.T) {
+	const address = "/tmp/mqc-reflection.sock"
+	os.Remove(address)
+
+	server, err := tpc.NewTransport(transport.WithProtocol("unix"), transport.WithAddress(address))
+	require.NoError(t, err)
+	defer server.Close()
+
+	client, err := tpc.NewTransport(transport.WithProtocol("unix"), transport.WithAddress(address))
+	require.NoError(t, err)
+	defer client.Close()
+
+	RegisterRpcTestServer(server, &RpcTestServerMock{})
+	reflection.Register(server)
+	go server.Serve()
+	time.Sleep(100 * time.Millisecond) // Give the server some time to start
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	reflectionClient := reflection.NewServerReflectionClient(client)
+
+	res, err := reflectionClient.ListServices(ctx, &reflection.ListServicesRequest{})
+	require.NoError(t, err)
+	require.Len(t, res.Services, 2)
+
+	rpcTest := res.Services[0]
+	assert.Equal(t, "RpcTest", rpcTest.Name)
+	assert.Equal(t, "test.RpcTest", rpcTest.FullName)
+	assert.Equal(t, "test.proto", rpcTest.File)
+	require.Len(t, rpcTest.Methods, 1)
+	assert.Equal(t, "Rpc", rpcTest.Methods[0].Name)
+	assert.Equal(t, "test.TestRequest", rpcTest.Methods[0].InputType)
+	assert.Equal(t, "test.TestReply", rpcTest.Methods[0].OutputType)
+
+	assert.Equal(t, "ServerReflection", res.Services[1].Name)
+	assert.Len(t, res.Services[1].Methods, 3)
+
+	files, err := reflectionClient.FileContainingSymbol(ctx, &reflection.FileContainingSymbolRequest{Symbol: "RpcTest"})
+	require.NoError(t, err)
+	require.NotEmpty(t, files.FileDescriptorProto)
+
+	file := &descriptorpb.FileDescriptorProto{}
+	require.NoError(t, proto.Unmarshal(files.FileDescriptorProto[0], file))
+	assert.Equal(t, "test.proto", file.GetName())
+
+	files, err = reflectionClient.FileContainingSymbol(ctx, &reflection.FileContainingSymbolRequest{Symbol: "test.TestReply"})
+	require.NoError(t, err)
+	require.NoError(t, proto.Unmarshal(files.FileDescriptorProto[0], file))
+	assert.Equal(t, "test.proto", file.GetName())
+
+	_, err = reflectionClient.FileByFilename(ctx, &reflection.FileByFilenameRequest{Filename: "missing.proto"})
+	assert.EqualError(t, err, reflection.ErrNotFound.Error())
+}
@@ -0,0 +1,182 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/srand/mqc"
+	"github.com/srand/mqc/mqctest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyServer fails its calls with err while failures remain.
+type flakyServer struct {
+	err      error
+	failures atomic.Int32
+	calls    atomic.Int32
+}
+
+func (s *flakyServer) Get(ctx context.Context, req *TestRequest) (*TestReply, error) {
+	s.calls.Add(1)
+	if s.failures.Add(-1) >= 0 {
+		return nil, s.err
+	}
+	return &TestReply{Value: req.Value}, nil
+}
+
+func (s *flakyServer) Watch(ctx context.Context, req *TestRequest, stream mqc.ServerStreamServer[TestReply]) error {
+	return mqc.ErrUnimplemented
+}
+
+func (s *flakyServer) Chat(ctx context.Context, stream mqc.BidiStreamServer[TestRequest, TestRequest]) error {
+	return mqc.ErrUnimplemented
+}
+
+// flakyClientConn fails to invoke calls with err while failures remain,
+// like a transport losing its connection before the call is acknowledged.
+type flakyClientConn struct {
+	mqc.ClientConn
+	err      error
+	failures atomic.Int32
+}
+
+func (c *flakyClientConn) Invoke(ctx context.Context, method *mqc.Method) (mqc.Conn, error) {
+	if c.failures.Add(-1) >= 0 {
+		return nil, c.err
+	}
+	return c.ClientConn.Invoke(ctx, method)
+}
+
+var testRetryPolicy = mqc.RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: 10 * time.Millisecond,
+	MaxBackoff:     50 * time.Millisecond,
+}
+
+func TestRetryIdempotent(t *testing.T) {
+	fake := mqctest.NewFakeClientConn()
+	server := &flakyServer{err: mqc.ErrUnavailable}
+	RegisterOptionsTestServer(fake, server)
+
+	client := NewOptionsTestClient(mqc.WithRetry(fake, testRetryPolicy))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Idempotent calls are retried on retryable errors
+	server.failures.Store(2)
+	res, err := client.Get(ctx, &TestRequest{Value: 7})
+	require.NoError(t, err)
+	assert.Equal(t, int32(7), res.Value)
+	assert.Equal(t, int32(3), server.calls.Load())
+
+	// Until the attempts are exhausted
+	server.calls.Store(0)
+	server.failures.Store(3)
+	_, err = client.Get(ctx, &TestRequest{})
+	assert.EqualError(t, err, mqc.ErrUnavailable.Error())
+	assert.Equal(t, int32(3), server.calls.Load())
+
+	// Other errors are not retried
+	server.calls.Store(0)
+	server.failures.Store(1)
+	server.err = mqc.ErrPermissionDenied
+	_, err = client.Get(ctx, &TestRequest{})
+	assert.EqualError(t, err, mqc.ErrPermissionDenied.Error())
+	assert.Equal(t, int32(1), server.calls.Load())
+
+	// Unless they are retryable by the policy
+	policy := testRetryPolicy
+	policy.RetryableErrors = []error{mqc.ErrPermissionDenied}
+	client = NewOptionsTestClient(mqc.WithRetry(fake, policy))
+
+	server.calls.Store(0)
+	server.failures.Store(1)
+	_, err = client.Get(ctx, &TestRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), server.calls.Load())
+}
+
+func TestRetryNotIdempotent(t *testing.T) {
+	fake := mqctest.NewFakeClientConn()
+	serverMock := &RpcTestServerMock{}
+	serverMock.On("Rpc", mock.Anything).Return(nil, mqc.ErrUnavailable).Once()
+	serverMock.On("Rpc", mock.Anything).Return(&TestReply{Value: 1}, nil)
+	RegisterRpcTestServer(fake, serverMock)
+
+	conn := &flakyClientConn{ClientConn: fake, err: errors.New("mqtt client is not connected")}
+	client := NewRpcTestClient(mqc.WithRetry(conn, testRetryPolicy))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Calls accepted by the server are not retried
+	_, err := client.Rpc(ctx, &TestRequest{})
+	assert.EqualError(t, err, mqc.ErrUnavailable.Error())
+	serverMock.AssertNumberOfCalls(t, "Rpc", 1)
+
+	// Calls failing before they were accepted are retried
+	conn.failures.Store(2)
+	res, err := client.Rpc(ctx, &TestRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), res.Value)
+	serverMock.AssertNumberOfCalls(t, "Rpc", 2)
+
+	// Unless the server rejected them
+	conn.failures.Store(1)
+	conn.err = mqc.ErrUnauthenticated
+	_, err = client.Rpc(ctx, &TestRequest{})
+	assert.ErrorIs(t, err, mqc.ErrUnauthenticated)
+	assert.Equal(t, int32(0), conn.failures.Load())
+
+	// Or the call is canceled
+	conn.failures.Store(3)
+	conn.err = context.Canceled
+	_, err = client.Rpc(ctx, &TestRequest{})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, int32(2), conn.failures.Load())
+}
+
+func TestRetryServerStream(t *testing.T) {
+	fake := mqctest.NewFakeClientConn()
+
+	method := mqc.NewMethod("RetryTest/Stream", mqc.MethodTypeServerStream)
+	mqc.RegisterMethodOptions(method, &mqc.MethodOptions{Idempotent: true})
+
+	// The first attempt fails before replying, the second one after
+	var attempts atomic.Int32
+	fake.RegisterHandler(method, func(ctx context.Context, conn mqc.Conn) error {
+		stream, req, err := mqc.NewServerStreamServer[TestRequest, TestReply](ctx, fake, conn)
+		if err != nil {
+			return err
+		}
+		if attempts.Add(1) == 1 {
+			return mqc.ErrUnavailable
+		}
+		if err := stream.Send(ctx, &TestReply{Value: req.Value}); err != nil {
+			return err
+		}
+		return mqc.ErrUnavailable
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	stream, err := mqc.NewServerStreamClient[TestRequest, TestReply](ctx, mqc.WithRetry(fake, testRetryPolicy), method, &TestRequest{Value: 3})
+	require.NoError(t, err)
+
+	// The request is replayed to the second attempt
+	reply, err := stream.Recv(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), reply.Value)
+
+	// Calls are not retried once a response was received
+	_, err = stream.Recv(ctx)
+	assert.EqualError(t, err, mqc.ErrUnavailable.Error())
+	assert.Equal(t, int32(2), attempts.Load())
+}
@@ -0,0 +1,204 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/srand/mqc"
+	"github.com/srand/mqc/health"
+	"github.com/srand/mqc/mqctest"
+	"github.com/srand/mqc/transport"
+	"github.com/srand/mqc/transport/http"
+	tpc "github.com/srand/mqc/transport/tcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer(t *testing.T) {
+	const address = "/tmp/mqc-server.sock"
+	os.Remove(address)
+
+	var mu sync.Mutex
+	var intercepted []string
+	server := mqc.NewServer(mqc.WithInterceptors(func(ctx context.Context, method *mqc.Method, conn mqc.Conn, handler mqc.MethodHandler) error {
+		mu.Lock()
+		intercepted = append(intercepted, method.FullName())
+		mu.Unlock()
+		return handler(ctx, conn)
+	}))
+
+	unixServer, err := tpc.NewTransport(
+		transport.WithProtocol("unix"),
+		transport.WithAddress(address),
+	)
+	require.NoError(t, err)
+	require.NoError(t, server.AddTransport(unixServer))
+
+	hanging := &hangingServer{release: make(chan struct{})}
+	require.NoError(t, RegisterRpcTestService(server, &tracedServer{}))
+	require.NoError(t, RegisterServerStreamTestService(server, &serverStreamServer{}))
+	require.NoError(t, RegisterBidiStreamTestService(server, hanging))
+	assert.Error(t, RegisterRpcTestService(server, &tracedServer{}))
+
+	healthServer, err := health.RegisterServer(server)
+	require.NoError(t, err)
+
+	// Services are registered on transports added later
+	wsServer, err := http.NewWebSocketTransport(transport.WithAddress("ws://localhost:8098"))
+	require.NoError(t, err)
+	require.NoError(t, server.AddTransport(wsServer))
+
+	served := make(chan error, 1)
+	go func() { served <- server.Serve() }()
+	time.Sleep(100 * time.Millisecond) // Give the server some time to start
+
+	unixClient, err := tpc.NewTransport(
+		transport.WithProtocol("unix"),
+		transport.WithAddress(address),
+	)
+	require.NoError(t, err)
+	defer unixClient.Close()
+
+	wsClient, err := http.NewWebSocketTransport(
+		transport.WithAddress("ws://localhost:8098"),
+		transport.WithOrigin("http://localhost/"),
+	)
+	require.NoError(t, err)
+	defer wsClient.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, client := range []mqc.Transport{unixClient, wsClient} {
+		reply, err := NewRpcTestClient(client).Rpc(ctx, &TestRequest{Value: 3})
+		require.NoError(t, err)
+		assert.Equal(t, int32(3), reply.Value)
+	}
+
+	mu.Lock()
+	assert.Equal(t, []string{"RpcTest/Rpc", "RpcTest/Rpc"}, intercepted)
+	mu.Unlock()
+
+	var names []string
+	for _, desc := range server.Services() {
+		names = append(names, desc.Name)
+	}
+	assert.Equal(t, []string{"BidiStreamTest", "Health", "RpcTest", "ServerStreamTest"}, names)
+
+	status, err := health.NewHealthClient(wsClient).Check(ctx, &health.HealthCheckRequest{Service: "RpcTest"})
+	require.NoError(t, err)
+	assert.Equal(t, health.HealthCheckResponse_SERVING, status.Status)
+
+	// Unregistered services are removed from all transports
+	require.NoError(t, server.UnregisterService("ServerStreamTest"))
+	for _, client := range []mqc.Transport{unixClient, wsClient} {
+		stream, err := NewServerStreamTestClient(client).Stream(ctx, &TestRequest{Value: 1})
+		require.NoError(t, err)
+		_, err = stream.Recv(ctx)
+		assert.EqualError(t, err, mqc.ErrUnimplemented.Error())
+	}
+
+	// Shutdown waits for ongoing calls and rejects new ones
+	stream, err := NewBidiStreamTestClient(unixClient).Stream(ctx)
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(ctx, &TestRequest{Value: 1}))
+	_, err = stream.Recv(ctx)
+	require.NoError(t, err)
+
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- server.Shutdown(ctx) }()
+
+	assert.Eventually(t, func() bool {
+		_, err := NewRpcTestClient(wsClient).Rpc(ctx, &TestRequest{Value: 1})
+		return err != nil && err.Error() == mqc.ErrUnavailable.Error()
+	}, time.Second, 10*time.Millisecond)
+
+	select {
+	case <-shutdown:
+		t.Fatal("shutdown did not wait for the ongoing call")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(hanging.release)
+	require.NoError(t, <-shutdown)
+	require.NoError(t, <-served)
+
+	status, err = healthServer.Check(ctx, &health.HealthCheckRequest{Service: "RpcTest"})
+	require.NoError(t, err)
+	assert.Equal(t, health.HealthCheckResponse_NOT_SERVING, status.Status)
+
+	assert.ErrorIs(t, server.Serve(), mqc.ErrUnavailable)
+}
+
+func TestServerTransportFailure(t *testing.T) {
+	const address = "/tmp/mqc-server-failure.sock"
+	os.Remove(address)
+
+	first, err := tpc.NewTransport(transport.WithProtocol("unix"), transport.WithAddress(address))
+	require.NoError(t, err)
+	defer first.Close()
+	go first.Serve()
+	time.Sleep(100 * time.Millisecond) // Give the server some time to start
+
+	// The address is in use, so serving fails
+	second, err := tpc.NewTransport(transport.WithProtocol("unix"), transport.WithAddress(address))
+	require.NoError(t, err)
+
+	server := mqc.NewServer()
+	require.NoError(t, server.AddTransport(second))
+
+	served := make(chan error, 1)
+	go func() { served <- server.Serve() }()
+
+	select {
+	case err := <-served:
+		assert.Error(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("server did not fail")
+	}
+}
+
+// failingTransport fails to register the handlers of a method,
+// like a transport failing to subscribe to its calls.
+type failingTransport struct {
+	*mqctest.FakeClientConn
+	method string
+}
+
+func (t *failingTransport) RegisterHandler(method *mqc.Method, handler mqc.MethodHandler) error {
+	if method.Name == t.method {
+		return errors.New("subscription failed")
+	}
+	return t.FakeClientConn.RegisterHandler(method, handler)
+}
+
+func TestServerRegistrationFailure(t *testing.T) {
+	newFailingTransport := func() *failingTransport {
+		return &failingTransport{FakeClientConn: mqctest.NewFakeClientConn(), method: "Watch"}
+	}
+
+	// Generated registrations return the errors of the transport
+	assert.EqualError(t, RegisterOptionsTestServer(newFailingTransport(), &flakyServer{}), "subscription failed")
+
+	// Services failing to register on a transport are removed from all transports
+	healthy, failing := mqctest.NewFakeClientConn(), newFailingTransport()
+	server := mqc.NewServer()
+	require.NoError(t, server.AddTransport(healthy))
+	require.NoError(t, server.AddTransport(failing))
+	assert.Error(t, RegisterOptionsTestService(server, &flakyServer{}))
+	assert.Empty(t, healthy.Methods())
+	assert.Empty(t, failing.Methods())
+	assert.Empty(t, server.Services())
+
+	// Transports failing to register a service are not served
+	server = mqc.NewServer()
+	require.NoError(t, RegisterRpcTestService(server, &tracedServer{}))
+	require.NoError(t, RegisterOptionsTestService(server, &flakyServer{}))
+	failing = newFailingTransport()
+	assert.Error(t, server.AddTransport(failing))
+	assert.Empty(t, failing.Methods())
+}
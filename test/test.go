@@ -1,2 +1,3 @@
-//go:generate protoc --go_out=. --go-mqc_out=. test.proto
+//go:generate protoc --go_out=. --go-mqc_out=. --go-mqc_opt=mocks=true test.proto
+//go:generate protoc -I. -I../options --go_out=. --go-mqc_out=. --go-mqc_opt=mocks=true options.proto
 package test
@@ -5,10 +5,8 @@
 package test
 
 import (
-	"context"
-	"fmt"
-
-	"github.com/srand/mqc"
+	context "context"
+	mqc "github.com/srand/mqc"
 )
 
 type RpcTestClient interface {
@@ -16,7 +14,7 @@ type RpcTestClient interface {
 }
 
 type RpcTestServer interface {
-	Rpc(req *TestRequest) (*TestReply, error)
+	Rpc(ctx context.Context, req *TestRequest) (*TestReply, error)
 }
 
 type ServerStreamTestClient interface {
@@ -24,7 +22,7 @@ type ServerStreamTestClient interface {
 }
 
 type ServerStreamTestServer interface {
-	Stream(req *TestRequest, stream mqc.ServerStreamServer[TestReply]) error
+	Stream(ctx context.Context, req *TestRequest, stream mqc.ServerStreamServer[TestReply]) error
 }
 
 type ClientStreamTestClient interface {
@@ -32,7 +30,7 @@ type ClientStreamTestClient interface {
 }
 
 type ClientStreamTestServer interface {
-	Stream(stream mqc.ClientStreamServer[TestRequest, TestReply]) error
+	Stream(ctx context.Context, stream mqc.ClientStreamServer[TestRequest, TestReply]) error
 }
 
 type BidiStreamTestClient interface {
@@ -40,115 +38,223 @@ type BidiStreamTestClient interface {
 }
 
 type BidiStreamTestServer interface {
-	Stream(stream mqc.BidiStreamServer[TestRequest, TestReply]) error
+	Stream(ctx context.Context, stream mqc.BidiStreamServer[TestRequest, TestReply]) error
 }
 
 type rpcTestClient struct {
-	transport mqc.Transport
+	conn mqc.ClientConn
 }
 
-func NewRpcTestClient(transport mqc.Transport) *rpcTestClient {
-	return &rpcTestClient{transport: transport}
+func NewRpcTestClient(conn mqc.ClientConn) *rpcTestClient {
+	return &rpcTestClient{conn: conn}
 }
 
+var _ RpcTestClient = (*rpcTestClient)(nil)
+
 func (c *rpcTestClient) Rpc(ctx context.Context, req *TestRequest) (*TestReply, error) {
-	return mqc.Rpc[TestRequest, TestReply](ctx, c.transport, mqc.NewMethod("RpcTest/Rpc", mqc.MethodTypeUnary), req)
+	return mqc.Rpc[TestRequest, TestReply](ctx, c.conn, mqc.NewMethod("RpcTest/Rpc", mqc.MethodTypeUnary), req)
 }
 
 type serverStreamTestClient struct {
-	transport mqc.Transport
+	conn mqc.ClientConn
 }
 
-func NewServerStreamTestClient(transport mqc.Transport) *serverStreamTestClient {
-	return &serverStreamTestClient{transport: transport}
+func NewServerStreamTestClient(conn mqc.ClientConn) *serverStreamTestClient {
+	return &serverStreamTestClient{conn: conn}
 }
 
+var _ ServerStreamTestClient = (*serverStreamTestClient)(nil)
+
 func (c *serverStreamTestClient) Stream(ctx context.Context, req *TestRequest) (mqc.ServerStreamClient[TestReply], error) {
-	return mqc.NewServerStreamClient[TestRequest, TestReply](ctx, c.transport, mqc.NewMethod("ServerStreamTest/Stream", mqc.MethodTypeServerStream), req)
+	return mqc.NewServerStreamClient[TestRequest, TestReply](ctx, c.conn, mqc.NewMethod("ServerStreamTest/Stream", mqc.MethodTypeServerStream), req)
 }
 
 type clientStreamTestClient struct {
-	transport mqc.Transport
+	conn mqc.ClientConn
 }
 
-func NewClientStreamTestClient(transport mqc.Transport) *clientStreamTestClient {
-	return &clientStreamTestClient{transport: transport}
+func NewClientStreamTestClient(conn mqc.ClientConn) *clientStreamTestClient {
+	return &clientStreamTestClient{conn: conn}
 }
 
+var _ ClientStreamTestClient = (*clientStreamTestClient)(nil)
+
 func (c *clientStreamTestClient) Stream(ctx context.Context) (mqc.ClientStreamClient[TestRequest, TestReply], error) {
-	return mqc.NewClientStreamClient[TestRequest, TestReply](ctx, c.transport, mqc.NewMethod("ClientStreamTest/Stream", mqc.MethodTypeClientStream))
+	return mqc.NewClientStreamClient[TestRequest, TestReply](ctx, c.conn, mqc.NewMethod("ClientStreamTest/Stream", mqc.MethodTypeClientStream))
 }
 
 type bidiStreamTestClient struct {
-	transport mqc.Transport
+	conn mqc.ClientConn
 }
 
-func NewBidiStreamTestClient(transport mqc.Transport) *bidiStreamTestClient {
-	return &bidiStreamTestClient{transport: transport}
+func NewBidiStreamTestClient(conn mqc.ClientConn) *bidiStreamTestClient {
+	return &bidiStreamTestClient{conn: conn}
 }
 
+var _ BidiStreamTestClient = (*bidiStreamTestClient)(nil)
+
 func (c *bidiStreamTestClient) Stream(ctx context.Context) (mqc.BidiStreamClient[TestRequest, TestReply], error) {
-	return mqc.NewBidiStreamClient[TestRequest, TestReply](ctx, c.transport, mqc.NewMethod("BidiStreamTest/Stream", mqc.MethodTypeBidiStream))
+	return mqc.NewBidiStreamClient[TestRequest, TestReply](ctx, c.conn, mqc.NewMethod("BidiStreamTest/Stream", mqc.MethodTypeBidiStream))
 }
 
 type UnimplementedRpcTestServer struct{}
 
-func (s *UnimplementedRpcTestServer) Rpc(req *TestRequest) (*TestReply, error) {
-	return nil, fmt.Errorf("method Rpc not implemented")
+func (s *UnimplementedRpcTestServer) Rpc(ctx context.Context, req *TestRequest) (*TestReply, error) {
+	return nil, mqc.ErrUnimplemented
 }
 
-func RegisterRpcTestServer(transport mqc.Transport, server RpcTestServer) {
-	transport.RegisterHandler(mqc.NewMethod("RpcTest/Rpc", mqc.MethodTypeUnary), func(conn mqc.Conn) error {
-		return mqc.RpcServer(conn, transport.Serializer(), func(req *TestRequest) (*TestReply, error) {
-			return server.Rpc(req)
+func RegisterRpcTestServer(transport mqc.Transport, server RpcTestServer) error {
+	if err := transport.RegisterHandler(mqc.NewMethod("RpcTest/Rpc", mqc.MethodTypeUnary), func(ctx context.Context, conn mqc.Conn) error {
+		return mqc.RpcServer(ctx, conn, transport.Serializer(), func(ctx context.Context, req *TestRequest) (*TestReply, error) {
+			return server.Rpc(ctx, req)
 		})
+	}); err != nil {
+		return err
+	}
+	return nil
+}
+
+func RegisterRpcTestService(s *mqc.Server, server RpcTestServer) error {
+	return s.RegisterService("RpcTest", func(transport mqc.Transport) error {
+		return RegisterRpcTestServer(transport, server)
 	})
 }
 
 type UnimplementedServerStreamTestServer struct{}
 
-func (s *UnimplementedServerStreamTestServer) Stream(req *TestRequest, stream mqc.ServerStreamServer[TestReply]) error {
-	return fmt.Errorf("method Stream not implemented")
+func (s *UnimplementedServerStreamTestServer) Stream(ctx context.Context, req *TestRequest, stream mqc.ServerStreamServer[TestReply]) error {
+	return mqc.ErrUnimplemented
 }
 
-func RegisterServerStreamTestServer(transport mqc.Transport, server ServerStreamTestServer) {
-	transport.RegisterHandler(mqc.NewMethod("ServerStreamTest/Stream", mqc.MethodTypeServerStream), func(conn mqc.Conn) error {
-		stream, req, err := mqc.NewServerStreamServer[TestRequest, TestReply](transport, conn)
+func RegisterServerStreamTestServer(transport mqc.Transport, server ServerStreamTestServer) error {
+	if err := transport.RegisterHandler(mqc.NewMethod("ServerStreamTest/Stream", mqc.MethodTypeServerStream), func(ctx context.Context, conn mqc.Conn) error {
+		stream, req, err := mqc.NewServerStreamServer[TestRequest, TestReply](ctx, transport, conn)
 		if err != nil {
 			return err
 		}
-		return server.Stream(req, stream)
+		return server.Stream(ctx, req, stream)
+	}); err != nil {
+		return err
+	}
+	return nil
+}
+
+func RegisterServerStreamTestService(s *mqc.Server, server ServerStreamTestServer) error {
+	return s.RegisterService("ServerStreamTest", func(transport mqc.Transport) error {
+		return RegisterServerStreamTestServer(transport, server)
 	})
 }
 
 type UnimplementedClientStreamTestServer struct{}
 
-func (s *UnimplementedClientStreamTestServer) Stream(stream mqc.ClientStreamServer[TestRequest, TestReply]) error {
-	return fmt.Errorf("method Stream not implemented")
+func (s *UnimplementedClientStreamTestServer) Stream(ctx context.Context, stream mqc.ClientStreamServer[TestRequest, TestReply]) error {
+	return mqc.ErrUnimplemented
 }
 
-func RegisterClientStreamTestServer(transport mqc.Transport, server ClientStreamTestServer) {
-	transport.RegisterHandler(mqc.NewMethod("ClientStreamTest/Stream", mqc.MethodTypeClientStream), func(conn mqc.Conn) error {
+func RegisterClientStreamTestServer(transport mqc.Transport, server ClientStreamTestServer) error {
+	if err := transport.RegisterHandler(mqc.NewMethod("ClientStreamTest/Stream", mqc.MethodTypeClientStream), func(ctx context.Context, conn mqc.Conn) error {
 		stream, err := mqc.NewClientStreamServer[TestRequest, TestReply](transport, conn)
 		if err != nil {
 			return err
 		}
-		return server.Stream(stream)
+		return server.Stream(ctx, stream)
+	}); err != nil {
+		return err
+	}
+	return nil
+}
+
+func RegisterClientStreamTestService(s *mqc.Server, server ClientStreamTestServer) error {
+	return s.RegisterService("ClientStreamTest", func(transport mqc.Transport) error {
+		return RegisterClientStreamTestServer(transport, server)
 	})
 }
 
 type UnimplementedBidiStreamTestServer struct{}
 
-func (s *UnimplementedBidiStreamTestServer) Stream(stream mqc.BidiStreamServer[TestRequest, TestReply]) error {
-	return fmt.Errorf("method Stream not implemented")
+func (s *UnimplementedBidiStreamTestServer) Stream(ctx context.Context, stream mqc.BidiStreamServer[TestRequest, TestReply]) error {
+	return mqc.ErrUnimplemented
 }
 
-func RegisterBidiStreamTestServer(transport mqc.Transport, server BidiStreamTestServer) {
-	transport.RegisterHandler(mqc.NewMethod("BidiStreamTest/Stream", mqc.MethodTypeBidiStream), func(conn mqc.Conn) error {
+func RegisterBidiStreamTestServer(transport mqc.Transport, server BidiStreamTestServer) error {
+	if err := transport.RegisterHandler(mqc.NewMethod("BidiStreamTest/Stream", mqc.MethodTypeBidiStream), func(ctx context.Context, conn mqc.Conn) error {
 		stream, err := mqc.NewBidiStreamServer[TestRequest, TestReply](transport, conn)
 		if err != nil {
 			return err
 		}
-		return server.Stream(stream)
+		return server.Stream(ctx, stream)
+	}); err != nil {
+		return err
+	}
+	return nil
+}
+
+func RegisterBidiStreamTestService(s *mqc.Server, server BidiStreamTestServer) error {
+	return s.RegisterService("BidiStreamTest", func(transport mqc.Transport) error {
+		return RegisterBidiStreamTestServer(transport, server)
+	})
+}
+
+func init() {
+	mqc.RegisterService(&mqc.ServiceDesc{
+		Name:     "RpcTest",
+		FullName: "test.RpcTest",
+		File:     "test.proto",
+		Methods: []mqc.MethodDesc{
+			{
+				Name:   "Rpc",
+				Type:   mqc.MethodTypeUnary,
+				Input:  "test.TestRequest",
+				Output: "test.TestReply",
+			},
+		},
+	})
+}
+
+func init() {
+	mqc.RegisterService(&mqc.ServiceDesc{
+		Name:     "ServerStreamTest",
+		FullName: "test.ServerStreamTest",
+		File:     "test.proto",
+		Methods: []mqc.MethodDesc{
+			{
+				Name:   "Stream",
+				Type:   mqc.MethodTypeServerStream,
+				Input:  "test.TestRequest",
+				Output: "test.TestReply",
+			},
+		},
+	})
+}
+
+func init() {
+	mqc.RegisterService(&mqc.ServiceDesc{
+		Name:     "ClientStreamTest",
+		FullName: "test.ClientStreamTest",
+		File:     "test.proto",
+		Methods: []mqc.MethodDesc{
+			{
+				Name:   "Stream",
+				Type:   mqc.MethodTypeClientStream,
+				Input:  "test.TestRequest",
+				Output: "test.TestReply",
+			},
+		},
+	})
+}
+
+func init() {
+	mqc.RegisterService(&mqc.ServiceDesc{
+		Name:     "BidiStreamTest",
+		FullName: "test.BidiStreamTest",
+		File:     "test.proto",
+		Methods: []mqc.MethodDesc{
+			{
+				Name:   "Stream",
+				Type:   mqc.MethodTypeBidiStream,
+				Input:  "test.TestRequest",
+				Output: "test.TestReply",
+			},
+		},
 	})
 }
@@ -0,0 +1,159 @@
+package test
+
+import (
+	"context"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/srand/mqc"
+	"github.com/srand/mqc/transport"
+	"github.com/srand/mqc/transport/http"
+	tpc "github.com/srand/mqc/transport/tcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// silentListener accepts connections but never responds.
+func silentListener(t *testing.T) net.Listener {
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+	return listener
+}
+
+func TestConnectTimeout(t *testing.T) {
+	listener := silentListener(t)
+	defer listener.Close()
+
+	transports := map[string]func() (mqc.Transport, error){
+		"tcp": func() (mqc.Transport, error) {
+			return tpc.NewTransport(transport.WithAddress(listener.Addr().String()), transport.WithConnectTimeout(100*time.Millisecond))
+		},
+		"websocket": func() (mqc.Transport, error) {
+			return http.NewWebSocketTransport(
+				transport.WithAddress("ws://"+listener.Addr().String()+"/"),
+				transport.WithOrigin("http://localhost"),
+				transport.WithConnectTimeout(100*time.Millisecond),
+			)
+		},
+	}
+
+	for name, newTransport := range transports {
+		t.Run(name, func(t *testing.T) {
+			client, err := newTransport()
+			require.NoError(t, err)
+			defer client.Close()
+
+			start := time.Now()
+			_, err = NewRpcTestClient(client).Rpc(context.Background(), &TestRequest{})
+			assert.ErrorIs(t, err, context.DeadlineExceeded)
+			assert.Less(t, time.Since(start), time.Second)
+		})
+	}
+}
+
+func TestConnectCanceled(t *testing.T) {
+	listener := silentListener(t)
+	defer listener.Close()
+
+	client, err := tpc.NewTransport(transport.WithAddress(listener.Addr().String()), transport.WithConnectTimeout(time.Minute))
+	require.NoError(t, err)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = NewRpcTestClient(client).Rpc(ctx, &TestRequest{})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestCallTimeout(t *testing.T) {
+	const address = "/tmp/mqc-timeout.sock"
+	os.Remove(address)
+
+	server, err := tpc.NewTransport(transport.WithProtocol("unix"), transport.WithAddress(address))
+	require.NoError(t, err)
+	defer server.Close()
+
+	client, err := tpc.NewTransport(
+		transport.WithProtocol("unix"),
+		transport.WithAddress(address),
+		transport.WithCallTimeout(100*time.Millisecond),
+	)
+	require.NoError(t, err)
+	defer client.Close()
+
+	serverMock := &RpcTestServerMock{}
+	serverMock.On("Rpc", mock.Anything).After(time.Second).Return(&TestReply{}, nil)
+
+	RegisterRpcTestServer(server, serverMock)
+	go server.Serve()
+	time.Sleep(100 * time.Millisecond) // Give the server some time to start
+
+	rpcClient := NewRpcTestClient(client)
+
+	// The call timeout applies to calls without a deadline
+	start := time.Now()
+	_, err = rpcClient.Rpc(context.Background(), &TestRequest{})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+
+	// The caller's deadline takes precedence
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	_, err = rpcClient.Rpc(ctx, &TestRequest{})
+	assert.NoError(t, err)
+}
+
+func TestStreamCallTimeout(t *testing.T) {
+	const address = "/tmp/mqc-stream-timeout.sock"
+	os.Remove(address)
+
+	server, err := tpc.NewTransport(transport.WithProtocol("unix"), transport.WithAddress(address))
+	require.NoError(t, err)
+	defer server.Close()
+
+	client, err := tpc.NewTransport(
+		transport.WithProtocol("unix"),
+		transport.WithAddress(address),
+		transport.WithCallTimeout(200*time.Millisecond),
+	)
+	require.NoError(t, err)
+	defer client.Close()
+
+	hanging := &hangingServer{release: make(chan struct{})}
+	defer close(hanging.release)
+
+	RegisterBidiStreamTestServer(server, hanging)
+	go server.Serve()
+	time.Sleep(100 * time.Millisecond) // Give the server some time to start
+
+	start := time.Now()
+	stream, err := NewBidiStreamTestClient(client).Stream(context.Background())
+	require.NoError(t, err)
+
+	require.NoError(t, stream.Send(context.Background(), &TestRequest{Value: 1}))
+	reply, err := stream.Recv(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), reply.Value)
+
+	// The call timeout bounds the whole stream, not only its establishment
+	_, err = stream.Recv(context.Background())
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+}
@@ -0,0 +1,183 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/srand/mqc"
+	"github.com/srand/mqc/mqctest"
+	"github.com/srand/mqc/opentelemetry"
+	"github.com/srand/mqc/transport"
+	mqc_http "github.com/srand/mqc/transport/http"
+	tpc "github.com/srand/mqc/transport/tcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracedServer echoes requests and fails requests with negative values.
+type tracedServer struct{}
+
+func (s *tracedServer) Rpc(ctx context.Context, req *TestRequest) (*TestReply, error) {
+	if req.Value < 0 {
+		return nil, errors.New("server error")
+	}
+	return &TestReply{Value: req.Value}, nil
+}
+
+// waitForSpans waits until the tracer has recorded n spans.
+func waitForSpans(t *testing.T, tracer *mqctest.Tracer, n int) []mqctest.SpanRecord {
+	require.Eventually(t, func() bool { return len(tracer.Spans()) >= n }, time.Second, 10*time.Millisecond)
+	return tracer.Spans()
+}
+
+func TestTracing(t *testing.T) {
+	const address = "/tmp/mqc-tracing.sock"
+	os.Remove(address)
+
+	serverTracer := mqctest.NewTracer()
+	clientTracer := mqctest.NewTracer()
+
+	server, err := tpc.NewTransport(
+		transport.WithProtocol("unix"),
+		transport.WithAddress(address),
+		transport.WithTracer(serverTracer),
+	)
+	require.NoError(t, err)
+	defer server.Close()
+
+	RegisterRpcTestServer(server, &tracedServer{})
+	RegisterServerStreamTestServer(server, &serverStreamServer{})
+	go server.Serve()
+	time.Sleep(100 * time.Millisecond) // Give the server some time to start
+
+	client, err := tpc.NewTransport(
+		transport.WithProtocol("unix"),
+		transport.WithAddress(address),
+		transport.WithTracer(clientTracer),
+	)
+	require.NoError(t, err)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// The server span continues the trace of the client span
+	_, err = NewRpcTestClient(client).Rpc(ctx, &TestRequest{Value: 1})
+	require.NoError(t, err)
+
+	clientSpan := waitForSpans(t, clientTracer, 1)[0]
+	serverSpan := waitForSpans(t, serverTracer, 1)[0]
+
+	assert.Equal(t, "RpcTest/Rpc", clientSpan.Name)
+	assert.Equal(t, mqc.SpanKindClient, clientSpan.Kind)
+	assert.Equal(t, mqc.MethodTypeUnary, clientSpan.Method.Type)
+	assert.Equal(t, "unix", clientSpan.Transport)
+	assert.Empty(t, clientSpan.ParentID)
+	assert.NoError(t, clientSpan.Err)
+
+	assert.Equal(t, "RpcTest/Rpc", serverSpan.Name)
+	assert.Equal(t, mqc.SpanKindServer, serverSpan.Kind)
+	assert.Equal(t, clientSpan.TraceID, serverSpan.TraceID)
+	assert.Equal(t, clientSpan.SpanID, serverSpan.ParentID)
+	assert.NoError(t, serverSpan.Err)
+
+	// Both spans record failed calls
+	clientTracer.Reset()
+	serverTracer.Reset()
+
+	_, err = NewRpcTestClient(client).Rpc(ctx, &TestRequest{Value: -1})
+	require.Error(t, err)
+
+	clientSpan = waitForSpans(t, clientTracer, 1)[0]
+	serverSpan = waitForSpans(t, serverTracer, 1)[0]
+	assert.EqualError(t, clientSpan.Err, "server error")
+	assert.EqualError(t, serverSpan.Err, "server error")
+
+	// Calls made within a span are its children
+	clientTracer.Reset()
+	serverTracer.Reset()
+
+	parentCtx, parent := clientTracer.Start(ctx, mqc.SpanInfo{Method: mqc.NewMethod("Parent", mqc.MethodTypeUnary)})
+
+	// Stream spans end with the stream
+	stream, err := NewServerStreamTestClient(client).Stream(parentCtx, &TestRequest{Value: 2})
+	require.NoError(t, err)
+	for {
+		_, err := stream.Recv(ctx)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+	}
+	parent.End(nil)
+
+	spans := waitForSpans(t, clientTracer, 2)
+	assert.Equal(t, "ServerStreamTest/Stream", spans[0].Name)
+	assert.NoError(t, spans[0].Err)
+	assert.Equal(t, spans[1].SpanID, spans[0].ParentID)
+	assert.Equal(t, spans[1].TraceID, waitForSpans(t, serverTracer, 1)[0].TraceID)
+}
+
+func TestOpenTelemetryTracing(t *testing.T) {
+	const address = "ws://localhost:8097/mqc"
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	defer provider.Shutdown(context.Background())
+
+	tracer := opentelemetry.NewTracer(provider)
+
+	server, err := mqc_http.NewWebSocketTransport(transport.WithAddress(address), transport.WithTracer(tracer))
+	require.NoError(t, err)
+	defer server.Close()
+
+	RegisterRpcTestServer(server, &tracedServer{})
+	go server.Serve()
+	time.Sleep(100 * time.Millisecond) // Give the server some time to start
+
+	client, err := mqc_http.NewWebSocketTransport(
+		transport.WithAddress(address),
+		transport.WithOrigin("http://localhost/"),
+		transport.WithTracer(tracer),
+	)
+	require.NoError(t, err)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	_, err = NewRpcTestClient(client).Rpc(ctx, &TestRequest{Value: -1})
+	require.Error(t, err)
+
+	require.Eventually(t, func() bool { return len(exporter.GetSpans()) == 2 }, time.Second, 10*time.Millisecond)
+
+	spans := map[trace.SpanKind]tracetest.SpanStub{}
+	for _, span := range exporter.GetSpans() {
+		spans[span.SpanKind] = span
+	}
+
+	clientSpan, serverSpan := spans[trace.SpanKindClient], spans[trace.SpanKindServer]
+	assert.Equal(t, "RpcTest/Rpc", clientSpan.Name)
+	assert.Equal(t, "RpcTest/Rpc", serverSpan.Name)
+	assert.Equal(t, clientSpan.SpanContext.TraceID(), serverSpan.SpanContext.TraceID())
+	assert.Equal(t, clientSpan.SpanContext.SpanID(), serverSpan.Parent.SpanID())
+	assert.True(t, serverSpan.Parent.IsRemote())
+
+	assert.Contains(t, clientSpan.Attributes, attribute.String("rpc.system", "mqc"))
+	assert.Contains(t, clientSpan.Attributes, attribute.String("rpc.service", "RpcTest"))
+	assert.Contains(t, clientSpan.Attributes, attribute.String("rpc.method", "Rpc"))
+	assert.Contains(t, clientSpan.Attributes, attribute.String("mqc.method_type", "unary"))
+	assert.Contains(t, clientSpan.Attributes, attribute.String("mqc.transport", "websocket"))
+
+	assert.Equal(t, codes.Error, clientSpan.Status.Code)
+	assert.Equal(t, "server error", serverSpan.Status.Description)
+}
@@ -2,62 +2,250 @@ package mqc
 
 import (
 	"bytes"
+	"crypto"
 	"crypto/ed25519"
 	"crypto/rand"
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/base64"
 	"encoding/pem"
+	"errors"
 	"fmt"
+	"io/fs"
 	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"strings"
 	"time"
 )
 
+// GenerateCertificate generates an ephemeral self-signed certificate without
+// subject alternative names. Peers can only accept it without verification,
+// see LoadOrGenerateCertificate for a certificate that can be pinned.
 func GenerateCertificate(expiration time.Duration) (tls.Certificate, error) {
-	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	template, err := newTemplate(expiration)
 	if err != nil {
-		return tls.Certificate{}, fmt.Errorf("failed to generate private key: %v", err)
+		return tls.Certificate{}, err
+	}
+	template.Subject = pkix.Name{
+		Organization: []string{"MQC Library"},
 	}
+	template.KeyUsage = x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	template.BasicConstraintsValid = true
 
-	keyUsage := x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment
-	notBefore := time.Now()
-	notAfter := notBefore.Add(expiration)
+	return createCertificate(template, nil, nil)
+}
+
+// LoadOrGenerateCertificate loads a key pair from PEM files, or generates a
+// self-signed certificate for the subject alternative names and saves it to
+// the files if they do not exist. The certificate stays the same across
+// restarts, so that clients can pin its public key.
+func LoadOrGenerateCertificate(certFile, keyFile string, expiration time.Duration, sans ...string) (tls.Certificate, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err == nil {
+		return cert, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return tls.Certificate{}, fmt.Errorf("failed to load certificate: %v", err)
+	}
+
+	template, err := newTemplate(expiration)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}
+	template.BasicConstraintsValid = true
+	setSANs(template, sans)
+
+	cert, err = createCertificate(template, nil, nil)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	if err := WriteCertificateFiles(cert, certFile, keyFile); err != nil {
+		return tls.Certificate{}, err
+	}
+	return cert, nil
+}
+
+// WriteCertificateFiles saves the certificate chain and the private key of a
+// key pair to PEM files. The key file is only readable by the owner.
+func WriteCertificateFiles(cert tls.Certificate, certFile, keyFile string) error {
+	var certOut bytes.Buffer
+	for _, der := range cert.Certificate {
+		if err := pem.Encode(&certOut, &pem.Block{Type: "CERTIFICATE", Bytes: der}); err != nil {
+			return fmt.Errorf("failed to encode certificate: %v", err)
+		}
+	}
+
+	privBytes, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	if err != nil {
+		return fmt.Errorf("unable to marshal private key: %v", err)
+	}
+	keyOut := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privBytes})
+
+	if err := os.WriteFile(keyFile, keyOut, 0600); err != nil {
+		return fmt.Errorf("failed to write key file: %v", err)
+	}
+	if err := os.WriteFile(certFile, certOut.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write certificate file: %v", err)
+	}
+	return nil
+}
+
+// SPKIFingerprint returns the fingerprint of the public key of a certificate,
+// "sha256/" followed by the base64 encoded SHA-256 hash of its subject public
+// key info. The fingerprint does not change when a certificate is renewed
+// with the same key.
+func SPKIFingerprint(cert *x509.Certificate) string {
+	hash := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return "sha256/" + base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// CertificateAuthority is a local certificate authority issuing certificates
+// for development setups and tests. Peers trust the certificates it issues
+// by adding the authority to their root or client CAs.
+type CertificateAuthority struct {
+	// Certificate of the authority.
+	Certificate *x509.Certificate
+
+	key crypto.Signer
+}
+
+// NewCertificateAuthority creates a certificate authority with a new key.
+func NewCertificateAuthority(name string, expiration time.Duration) (*CertificateAuthority, error) {
+	template, err := newTemplate(expiration)
+	if err != nil {
+		return nil, err
+	}
+	template.Subject = pkix.Name{CommonName: name}
+	template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+	template.IsCA = true
+	template.BasicConstraintsValid = true
+
+	cert, err := createCertificate(template, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CertificateAuthority{Certificate: cert.Leaf, key: cert.PrivateKey.(crypto.Signer)}, nil
+}
+
+// LoadOrCreateCertificateAuthority loads a certificate authority from PEM
+// files, or creates one and saves it to the files if they do not exist.
+func LoadOrCreateCertificateAuthority(certFile, keyFile, name string, expiration time.Duration) (*CertificateAuthority, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err == nil {
+		key, ok := cert.PrivateKey.(crypto.Signer)
+		if !ok || !cert.Leaf.IsCA {
+			return nil, fmt.Errorf("%s is not a certificate authority", certFile)
+		}
+		return &CertificateAuthority{Certificate: cert.Leaf, key: key}, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to load certificate authority: %v", err)
+	}
+
+	ca, err := NewCertificateAuthority(name, expiration)
+	if err != nil {
+		return nil, err
+	}
+
+	keyPair := tls.Certificate{Certificate: [][]byte{ca.Certificate.Raw}, PrivateKey: ca.key}
+	if err := WriteCertificateFiles(keyPair, certFile, keyFile); err != nil {
+		return nil, err
+	}
+	return ca, nil
+}
+
+// Issue issues a certificate with a new key for the subject alternative names,
+// usable by both servers and clients. SANs are DNS names, IP addresses,
+// email addresses or URIs, e.g. "localhost", "127.0.0.1" or
+// "spiffe://example.org/service". The first SAN is the common name.
+func (ca *CertificateAuthority) Issue(expiration time.Duration, sans ...string) (tls.Certificate, error) {
+	template, err := newTemplate(expiration)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	if len(sans) > 0 {
+		template.Subject = pkix.Name{CommonName: sans[0]}
+	}
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}
+	setSANs(template, sans)
+
+	return createCertificate(template, ca.Certificate, ca.key)
+}
+
+// CertPool returns a pool trusting the certificates issued by the authority.
+func (ca *CertificateAuthority) CertPool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.Certificate)
+	return pool
+}
+
+// newTemplate returns a certificate template with a random serial number,
+// valid from now until the expiration.
+func newTemplate(expiration time.Duration) (*x509.Certificate, error) {
 	serialNumberLimit := new(big.Int).Lsh(big.NewInt(1), 128)
 	serialNumber, err := rand.Int(rand.Reader, serialNumberLimit)
 	if err != nil {
-		return tls.Certificate{}, fmt.Errorf("failed to generate serial number: %v", err)
+		return nil, fmt.Errorf("failed to generate serial number: %v", err)
 	}
 
-	template := x509.Certificate{
+	notBefore := time.Now()
+	return &x509.Certificate{
 		SerialNumber: serialNumber,
-		Subject: pkix.Name{
-			Organization: []string{"MQC Library"},
-		},
-		NotBefore:             notBefore,
-		NotAfter:              notAfter,
-		KeyUsage:              keyUsage,
-		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
-		BasicConstraintsValid: true,
+		NotBefore:    notBefore,
+		NotAfter:     notBefore.Add(expiration),
+	}, nil
+}
+
+// setSANs adds subject alternative names to a certificate template.
+func setSANs(template *x509.Certificate, sans []string) {
+	for _, san := range sans {
+		if ip := net.ParseIP(san); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else if uri, err := url.Parse(san); err == nil && uri.Scheme != "" {
+			template.URIs = append(template.URIs, uri)
+		} else if strings.Contains(san, "@") {
+			template.EmailAddresses = append(template.EmailAddresses, san)
+		} else {
+			template.DNSNames = append(template.DNSNames, san)
+		}
 	}
+}
 
-	derBytes, err := x509.CreateCertificate(rand.Reader, &template, &template, priv.Public(), priv)
+// createCertificate creates a certificate with a new ED25519 key, signed by
+// the parent, or self-signed if the parent is nil.
+func createCertificate(template, parent *x509.Certificate, parentKey crypto.Signer) (tls.Certificate, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
 	if err != nil {
-		return tls.Certificate{}, fmt.Errorf("failed to create certificate: %v", err)
+		return tls.Certificate{}, fmt.Errorf("failed to generate private key: %v", err)
 	}
 
-	var certOut bytes.Buffer
-	if err := pem.Encode(&certOut, &pem.Block{Type: "CERTIFICATE", Bytes: derBytes}); err != nil {
-		return tls.Certificate{}, fmt.Errorf("failed to write data to cert.pem: %v", err)
+	if parent == nil {
+		parent, parentKey = template, priv
 	}
 
-	var keyOut bytes.Buffer
-	privBytes, err := x509.MarshalPKCS8PrivateKey(priv)
+	derBytes, err := x509.CreateCertificate(rand.Reader, template, parent, pub, parentKey)
 	if err != nil {
-		return tls.Certificate{}, fmt.Errorf("unable to marshal private key: %v", err)
+		return tls.Certificate{}, fmt.Errorf("failed to create certificate: %v", err)
 	}
-	if err := pem.Encode(&keyOut, &pem.Block{Type: "PRIVATE KEY", Bytes: privBytes}); err != nil {
-		return tls.Certificate{}, fmt.Errorf("failed to write data to key.pem: %v", err)
+
+	leaf, err := x509.ParseCertificate(derBytes)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to parse certificate: %v", err)
 	}
 
-	return tls.X509KeyPair(certOut.Bytes(), keyOut.Bytes())
+	return tls.Certificate{
+		Certificate: [][]byte{derBytes},
+		PrivateKey:  priv,
+		Leaf:        leaf,
+	}, nil
 }
@@ -0,0 +1,134 @@
+package mqc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+)
+
+// Metadata keys of the W3C trace context propagated with calls.
+const (
+	TraceparentKey = "traceparent"
+	TracestateKey  = "tracestate"
+)
+
+// SpanKind is the role of a span in a call.
+type SpanKind int
+
+const (
+	// SpanKindClient is the span of an outgoing call.
+	SpanKindClient SpanKind = iota
+	// SpanKindServer is the span of an incoming call.
+	SpanKindServer
+	// SpanKindProducer is the span of a published pub-sub message.
+	SpanKindProducer
+	// SpanKindConsumer is the span of a received pub-sub message.
+	SpanKindConsumer
+)
+
+// SpanInfo describes the call of a span.
+type SpanInfo struct {
+	Kind SpanKind
+
+	// Method is the called method.
+	Method *Method
+
+	// Transport is the name of the transport, e.g. "tcp" or "mqtt".
+	Transport string
+
+	// Remote is the metadata received from the peer of server and consumer
+	// spans. Server spans continue its trace context and consumer spans
+	// link to it.
+	Remote Metadata
+}
+
+// Span is a traced call, or a published or received message.
+type Span interface {
+	// End ends the span with the status of the call, nil on success.
+	End(err error)
+}
+
+// Tracer creates spans for calls and propagates their trace context
+// in the call metadata, e.g. with the opentelemetry package.
+type Tracer interface {
+	// Start starts a span and returns a context carrying it.
+	Start(ctx context.Context, info SpanInfo) (context.Context, Span)
+
+	// Inject adds the trace context of the span in the context to the metadata.
+	Inject(ctx context.Context, md Metadata)
+}
+
+type noopSpan struct{}
+
+func (noopSpan) End(err error) {}
+
+// StartSpan starts a span with the tracer, or a span doing nothing if the
+// tracer is nil.
+func StartSpan(ctx context.Context, tracer Tracer, info SpanInfo) (context.Context, Span) {
+	if tracer == nil {
+		return ctx, noopSpan{}
+	}
+	return tracer.Start(ctx, info)
+}
+
+// InjectTraceContext returns a copy of the metadata with the trace context
+// of the span in the context added, or the metadata if the tracer is nil.
+func InjectTraceContext(ctx context.Context, tracer Tracer, md Metadata) Metadata {
+	if tracer == nil {
+		return md
+	}
+
+	result := make(Metadata, len(md)+2)
+	for key, value := range md {
+		result[key] = value
+	}
+	tracer.Inject(ctx, result)
+	return result
+}
+
+// tracedConn ends the span of an outgoing call when the call ends.
+type tracedConn struct {
+	Conn
+	method *Method
+	span   Span
+	once   sync.Once
+}
+
+// NewTracedConn returns a client connection ending the span of the call
+// when the connection is closed or receiving fails, e.g. at the end of a stream.
+func NewTracedConn(conn Conn, method *Method, span Span) Conn {
+	return &tracedConn{Conn: conn, method: method, span: span}
+}
+
+func (c *tracedConn) end(err error) {
+	c.once.Do(func() { c.span.End(err) })
+}
+
+// Method returns the method the connection was invoked for.
+func (c *tracedConn) Method() *Method {
+	return c.method
+}
+
+func (c *tracedConn) Send(ctx context.Context, data []byte) error {
+	err := c.Conn.Send(ctx, data)
+	if err != nil {
+		c.end(err)
+	}
+	return err
+}
+
+func (c *tracedConn) Recv(ctx context.Context) ([]byte, error) {
+	data, err := c.Conn.Recv(ctx)
+	if errors.Is(err, io.EOF) {
+		c.end(nil)
+	} else if err != nil {
+		c.end(err)
+	}
+	return data, err
+}
+
+func (c *tracedConn) Close() error {
+	c.end(nil)
+	return c.Conn.Close()
+}
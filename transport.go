@@ -2,31 +2,33 @@ package mqc
 
 import (
 	"context"
-
-	"github.com/srand/mqc/serialization"
 )
 
-type MethodHandler func(stream Conn) error
+// MethodHandler handles an incoming call. The context carries the metadata
+// and the authenticated identity of the caller.
+type MethodHandler func(ctx context.Context, stream Conn) error
 
 // Transport is a communication transport for RPC calls.
+// Its client side is a ClientConn.
 type Transport interface {
-	// Close closes the transport.
-	// Any blocked Read or Write operations will be unblocked and return errors.
-	Close() error
+	ClientConn
 
 	// Dial establishes a connection to the remote server.
 	// If the transport is already connected, it returns an error.
 	// It is not necessary to call Dial before Invoke, as Invoke will dial automatically.
 	Dial() error
 
-	// Invoke creates a new connection object for the given method.
-	Invoke(ctx context.Context, method *Method) (Conn, error)
-
-	// RegisterHandler registers a new handler for the given method.
+	// RegisterHandler registers a new handler for the given method,
+	// replacing any previous handler. Handlers may be registered while
+	// the transport is serving.
 	RegisterHandler(method *Method, handler MethodHandler) error
 
-	// Serializer returns the serializer used for marshaling and unmarshaling messages.
-	Serializer() serialization.Serializer
+	// UnregisterHandler removes the handler of the given method. Ongoing calls
+	// continue, while new calls of the method fail with ErrUnimplemented.
+	UnregisterHandler(method *Method) error
+
+	// Methods returns the methods with a registered handler.
+	Methods() []*Method
 
 	// Serve starts the server to accept incoming connections and handle requests.
 	Serve() error
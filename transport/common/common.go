@@ -2,88 +2,225 @@ package common
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"time"
 
 	"github.com/hashicorp/yamux"
 	"github.com/srand/mqc"
+	"github.com/srand/mqc/channelz"
+	"github.com/srand/mqc/metrics"
 	"github.com/srand/mqc/serialization"
 	"github.com/srand/mqc/transport"
 )
 
 type BaseTransport struct {
-	Handlers  map[mqc.Method]mqc.MethodHandler
+	Handlers  *transport.Handlers
 	Options   transport.TransportOptions
 	Serialize serialization.Serializer
+	Channelz  *channelz.Transport
 }
 
 func (t *BaseTransport) RegisterHandler(method *mqc.Method, handler mqc.MethodHandler) error {
-	t.Handlers[*method] = handler
+	if handler == nil {
+		return fmt.Errorf("handler cannot be nil")
+	}
+	t.Handlers.Register(method, handler)
 	return nil
 }
 
 func (t *BaseTransport) UnregisterHandler(method *mqc.Method) error {
-	delete(t.Handlers, *method)
+	t.Handlers.Unregister(method)
 	return nil
 }
 
+func (t *BaseTransport) Methods() []*mqc.Method {
+	return t.Handlers.Methods()
+}
+
 func (t *BaseTransport) Serializer() serialization.Serializer {
 	return t.Serialize
 }
 
-func (t *BaseTransport) AcceptMux(mux *yamux.Session) error {
-	ctx := context.Background()
+// TrackSession tracks a session with the remote address in channelz until it is closed.
+func (t *BaseTransport) TrackSession(mux *yamux.Session, remote string) {
+	session := t.Channelz.AddSession(mux, remote)
+	if session == nil {
+		return
+	}
+
+	go func() {
+		<-mux.CloseChan()
+		session.Remove()
+	}()
+}
+
+// AcceptMux handles the calls of a session until it is closed, exchanging
+// frames of the version negotiated during the handshake.
+// The peer of the session is available to handlers with mqc.PeerFromContext.
+func (t *BaseTransport) AcceptMux(mux *yamux.Session, peer *mqc.Peer, version byte) error {
+	ctx := mqc.NewContextWithPeer(context.Background(), peer)
+	logger := t.Options.Logger.With(slog.Any("peer", peer))
+	session := t.Channelz.Session(mux)
+
+	// Limits the number of streams handled concurrently
+	var slots chan struct{}
+	if t.Options.MaxConcurrentStreams > 0 {
+		slots = make(chan struct{}, t.Options.MaxConcurrentStreams)
+	}
 
 	for {
-		conn, err := mux.Accept()
+		if slots != nil {
+			select {
+			case slots <- struct{}{}:
+			case <-mux.CloseChan():
+				return yamux.ErrSessionShutdown
+			}
+		}
+
+		stream, err := mux.AcceptStream()
 		if err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, yamux.ErrSessionShutdown) {
+				logger.Debug("session closed")
+			} else {
+				logger.Warn("session failed", slog.Any("error", err))
+			}
 			return err
 		}
 
+		conn := t.Options.Metrics.OpenStream(stream, t.Options.Protocol)
+		logger := logger.With(slog.Uint64("call_id", uint64(stream.StreamID())))
+
 		go func() {
-			call := NewConn(conn, t.Serialize)
+			if slots != nil {
+				defer func() { <-slots }()
+			}
+
+			call := NewConn(conn, t.Serialize, version)
 
-			method, err := call.RecvMethod(ctx)
+			// Handlers stop when the client closes the call or disconnects
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			go func() {
+				select {
+				case <-call.Done():
+					cancel()
+				case <-ctx.Done():
+				}
+			}()
+
+			method, md, err := call.RecvMethod(ctx)
 			if err != nil {
+				logger.Warn("failed to receive call", slog.Any("error", err))
 				conn.Close()
 				return
 			}
 
-			handler, ok := t.Handlers[*method]
+			logger := logger.With(slog.String("method", method.FullName()))
+
+			handler, ok := t.Handlers.Get(method)
 			if !ok {
+				logger.Warn("unimplemented method")
+				call.SendError(ctx, mqc.ErrUnimplemented)
 				conn.Close()
 				return
 			}
 
-			// Ensure that any panic is recovered
-			defer func() {
-				if r := recover(); r != nil {
-					fmt.Println("Recovered in server goroutine:", r)
-				}
-			}()
+			spanCtx, span := mqc.StartSpan(ctx, t.Options.Tracer, mqc.SpanInfo{
+				Kind:      mqc.SpanKindServer,
+				Method:    method,
+				Transport: t.Options.Protocol,
+				Remote:    md,
+			})
+			callMetrics := t.Options.Metrics.StartCall(metrics.RoleServer, t.Options.Protocol, method)
+			callz := session.StartCall(channelz.RoleServer, method)
+			defer callz.End()
+
+			// Reject unauthenticated and unauthorized calls before the handler runs
+			callCtx, err := mqc.Authenticate(spanCtx, t.Options.Authenticator, method, md)
+			if err == nil {
+				err = mqc.Authorize(callCtx, t.Options.Authorizer, method)
+			}
+			if err != nil {
+				logger.Warn("call rejected", slog.Any("error", err))
+				span.End(err)
+				callMetrics.End(err)
+				call.SendError(ctx, err)
+				conn.Close()
+				return
+			}
 
 			defer conn.Close()
 
-			err = handler(call)
-			if err != nil {
+			logger.Debug("call started")
+
+			err = mqc.CallHandler(callCtx, handler, method, callMetrics.Conn(callz.Conn(call)))
+			span.End(err)
+			callMetrics.End(err)
+
+			var panicErr *mqc.PanicError
+			if errors.As(err, &panicErr) {
+				t.Options.HandlePanic(callCtx, logger, panicErr)
+				call.SendError(ctx, mqc.ErrInternal)
+				if t.Options.PanicPolicy == mqc.PanicRepanic {
+					panic(panicErr)
+				}
+			} else if err != nil {
+				logger.Debug("call failed", slog.Any("error", err))
 				call.SendError(ctx, err)
+			} else {
+				logger.Debug("call completed")
 			}
 		}()
 	}
 }
 
-func (t *BaseTransport) InvokeMux(ctx context.Context, mux *yamux.Session, method *mqc.Method) (mqc.Conn, error) {
-	conn, err := mux.Open()
+// InvokeMux invokes a method in a new stream of a session, exchanging frames
+// of the version negotiated during the handshake.
+func (t *BaseTransport) InvokeMux(ctx context.Context, mux *yamux.Session, version byte, method *mqc.Method) (mqc.Conn, error) {
+	stream, err := mux.Open()
+	if err != nil {
+		return nil, err
+	}
+	conn := t.Options.Metrics.OpenStream(stream, t.Options.Protocol)
+
+	ctx, span := mqc.StartSpan(ctx, t.Options.Tracer, mqc.SpanInfo{
+		Kind:      mqc.SpanKindClient,
+		Method:    method,
+		Transport: t.Options.Protocol,
+	})
+	callMetrics := t.Options.Metrics.StartCall(metrics.RoleClient, t.Options.Protocol, method)
+	callz := t.Channelz.Session(mux).StartCall(channelz.RoleClient, method)
+
+	md, err := mqc.OutgoingMetadata(ctx, t.Options.Credentials, method)
 	if err != nil {
+		span.End(err)
+		callMetrics.End(err)
+		callz.End()
+		conn.Close()
 		return nil, err
 	}
 
-	call := NewConn(conn, t.Serialize)
+	call := NewConn(conn, t.Serialize, version)
 
-	err = call.SendMethod(ctx, method)
+	err = call.SendMethod(ctx, method, mqc.InjectTraceContext(ctx, t.Options.Tracer, md))
 	if err != nil {
+		span.End(err)
+		callMetrics.End(err)
+		callz.End()
 		conn.Close()
 		return nil, err
 	}
 
-	return call, nil
+	if t.Options.Tracer == nil {
+		return callMetrics.Conn(callz.Conn(call)), nil
+	}
+	return callMetrics.Conn(callz.Conn(mqc.NewTracedConn(call, method, span))), nil
+}
+
+// CallTimeout returns the default deadline for client calls.
+func (t *BaseTransport) CallTimeout() time.Duration {
+	return t.Options.CallTimeout
 }
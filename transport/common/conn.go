@@ -5,30 +5,43 @@ import (
 	"errors"
 	"io"
 	"net"
+	"sync"
 
 	"github.com/srand/mqc"
 	"github.com/srand/mqc/serialization"
 )
 
-// Represents a call connection over net.Conn transport
+// Represents a call connection over net.Conn transport.
+//
+// Each message is sent as a single frame carrying the message type in
+// the frame header and the raw payload as body, so data already marshaled
+// by the caller is never wrapped and serialized a second time.
 type callConn struct {
 	conn       net.Conn
-	decoder    serialization.Decoder
-	encoder    serialization.Encoder
+	reader     *serialization.FrameReader
+	writer     *serialization.FrameWriter
 	receiver   chan *mqc.Message
 	serializer serialization.Serializer
+	method     *mqc.Method
 	err        error
+
+	// done is closed when the stream ends
+	done     chan struct{}
+	doneOnce sync.Once
 }
 
 var _ mqc.Conn = (*callConn)(nil)
 
-func NewConn(conn net.Conn, serializer serialization.Serializer) *callConn {
+// NewConn creates a call connection exchanging frames of the version
+// negotiated with the peer during the handshake.
+func NewConn(conn net.Conn, serializer serialization.Serializer, version byte) *callConn {
 	cc := &callConn{
 		conn:       conn,
-		decoder:    serializer.NewDecoder(conn),
-		encoder:    serializer.NewEncoder(conn),
+		reader:     serialization.NewVersionedFrameReader(conn, version),
+		writer:     serialization.NewVersionedFrameWriter(conn, version),
 		receiver:   make(chan *mqc.Message),
 		serializer: serializer,
+		done:       make(chan struct{}),
 	}
 	go cc.run()
 	return cc
@@ -46,7 +59,7 @@ func (s *callConn) Send(ctx context.Context, data []byte) error {
 		return s.err
 	}
 
-	return s.encoder.Encode(mqc.NewDataMessage(data))
+	return s.writeMessage(mqc.Message_DATA, data)
 }
 
 func (s *callConn) sendControl(ctx context.Context, msg *mqc.Message) error {
@@ -54,7 +67,18 @@ func (s *callConn) sendControl(ctx context.Context, msg *mqc.Message) error {
 		return s.err
 	}
 
-	return s.encoder.Encode(msg)
+	return s.writeMessage(msg.Type, msg.Data)
+}
+
+func (s *callConn) writeMessage(msgType mqc.Message_Type, data []byte) error {
+	frame := serialization.Frame{
+		Type:    byte(msgType),
+		Payload: data,
+	}
+	if msgType == mqc.Message_CLOSE || msgType == mqc.Message_ERROR {
+		frame.Flags |= serialization.FlagEndStream
+	}
+	return s.writer.WriteFrame(&frame)
 }
 
 func (s *callConn) SendAck(ctx context.Context) error {
@@ -69,8 +93,29 @@ func (s *callConn) SendError(ctx context.Context, err error) error {
 	return s.sendControl(ctx, mqc.NewErrorMessage(err))
 }
 
-func (s *callConn) SendMethod(ctx context.Context, method *mqc.Method) error {
-	return s.sendControl(ctx, mqc.NewCallMessage(method))
+func (s *callConn) SendMethod(ctx context.Context, method *mqc.Method, md mqc.Metadata) error {
+	msg, err := mqc.NewCallMessage(method, md)
+	if err != nil {
+		return err
+	}
+	s.method = method
+	return s.sendControl(ctx, msg)
+}
+
+// Done returns a channel closed when the stream ends, because the peer closed
+// it, sent an error or disconnected. Closing the sending direction of the
+// stream does not end it.
+func (s *callConn) Done() <-chan struct{} {
+	return s.done
+}
+
+func (s *callConn) end() {
+	s.doneOnce.Do(func() { close(s.done) })
+}
+
+// Method returns the method the connection was invoked for.
+func (s *callConn) Method() *mqc.Method {
+	return s.method
 }
 
 func (s *callConn) Recv(ctx context.Context) ([]byte, error) {
@@ -102,57 +147,96 @@ func (s *callConn) Recv(ctx context.Context) ([]byte, error) {
 	return msg.DataBytes(), nil
 }
 
-func (s *callConn) RecvMethod(ctx context.Context) (*mqc.Method, error) {
+// RecvMethod receives the method and the metadata of an incoming call.
+func (s *callConn) RecvMethod(ctx context.Context) (*mqc.Method, mqc.Metadata, error) {
 	var msg *mqc.Message
 
 	if s.err != nil {
-		return nil, s.err
+		return nil, nil, s.err
 	}
 
 	select {
 	case msg = <-s.receiver:
 	case <-ctx.Done():
-		return nil, ctx.Err()
+		return nil, nil, ctx.Err()
 	}
 
 	if msg == nil || msg.IsClose() {
-		return nil, io.EOF
+		return nil, nil, io.EOF
 	}
 
 	if msg.IsError() {
 		s.err = msg.Error()
-		return nil, s.err
+		return nil, nil, s.err
 	}
 
 	if !msg.IsCall() {
-		return nil, mqc.ErrProtocolViolation
+		return nil, nil, mqc.ErrProtocolViolation
 	}
 
-	return msg.Method(), nil
+	method, md, err := msg.Invoke()
+	if err != nil {
+		return nil, nil, err
+	}
+	s.method = method
+	return method, md, nil
 }
 
 func (c *callConn) run() {
-	defer close(c.receiver)
+	defer c.end()
+	if !c.receive() {
+		return
+	}
+
+	// The peer closed its direction of the stream, which remains open until
+	// the peer closes it or disconnects
 	for {
-		var msg mqc.Message
+		if _, err := c.reader.ReadFrame(); err != nil {
+			return
+		}
+	}
+}
 
-		if err := c.decoder.Decode(&msg); err != nil {
+// receive delivers the received messages until the peer closes its direction
+// of the stream, and reports whether the stream is still open.
+func (c *callConn) receive() bool {
+	defer close(c.receiver)
+	for {
+		frame, err := c.reader.ReadFrame()
+		if err != nil {
+			c.end()
 			if errors.Is(err, io.EOF) {
-				return
+				return false
 			}
 
 			// Connection closed or error occurred
 			c.receiver <- mqc.NewErrorMessage(err)
-			return
+			return false
+		}
+
+		// The message data aliases the frame payload, which is owned by the receiver
+		msg := &mqc.Message{
+			Type: mqc.Message_Type(frame.Type),
+			Data: frame.Payload,
 		}
 
 		if msg.IsError() {
 			c.err = msg.Error()
-			c.receiver <- &msg
-			return
+			c.end()
+			c.receiver <- msg
+			return false
 		}
 
-		c.receiver <- &msg
+		// The end of the stream is received as the closing of the receiver,
+		// so that it is not lost if the messages are not received anymore.
+		// Data sent together with the end of the stream implies a close.
+		if frame.Flags.Has(serialization.FlagEndStream) {
+			if msg.IsData() {
+				c.receiver <- msg
+			}
+			return true
+		}
 
+		c.receiver <- msg
 	}
 }
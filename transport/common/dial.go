@@ -0,0 +1,39 @@
+package common
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/hashicorp/yamux"
+	"github.com/srand/mqc/transport"
+)
+
+// ConnectSession performs the client handshake on a new connection and
+// creates a multiplexed session over it, returning the negotiated frame
+// version. The handshake is aborted when the context is done.
+// The connection is closed if the session can't be created.
+func ConnectSession(ctx context.Context, conn net.Conn, opts *transport.TransportOptions) (*yamux.Session, byte, error) {
+	// Unblock the handshake when the context is done
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now())
+	})
+
+	version, err := ClientHandshake(conn)
+
+	if !stop() {
+		conn.Close()
+		return nil, 0, ctx.Err()
+	}
+	if err != nil {
+		conn.Close()
+		return nil, 0, err
+	}
+
+	session, err := NewClientSession(conn, opts)
+	if err != nil {
+		conn.Close()
+		return nil, 0, err
+	}
+	return session, version, nil
+}
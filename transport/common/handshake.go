@@ -0,0 +1,51 @@
+package common
+
+import (
+	"io"
+
+	"github.com/srand/mqc/serialization"
+)
+
+// ClientHandshake announces the frame versions supported by the client
+// and waits for the server to confirm the version to use.
+// It must be called on a new connection before any frames are exchanged.
+func ClientHandshake(conn io.ReadWriter) (byte, error) {
+	if err := serialization.WritePreface(conn, serialization.FrameVersion); err != nil {
+		return 0, err
+	}
+
+	version, err := serialization.ReadPreface(conn)
+	if err != nil {
+		return 0, err
+	}
+
+	if version < serialization.MinFrameVersion || version > serialization.FrameVersion {
+		return 0, serialization.ErrUnsupportedVersion
+	}
+
+	return version, nil
+}
+
+// ServerHandshake reads the client preface and replies with the frame version
+// to use, which is the newest version supported by both peers.
+// If there is no such version, the server announces its own newest version
+// so that the client can report the mismatch, and an error is returned.
+func ServerHandshake(conn io.ReadWriter) (byte, error) {
+	version, err := serialization.ReadPreface(conn)
+	if err != nil {
+		return 0, err
+	}
+
+	version = min(version, serialization.FrameVersion)
+
+	if version < serialization.MinFrameVersion {
+		serialization.WritePreface(conn, serialization.FrameVersion)
+		return 0, serialization.ErrUnsupportedVersion
+	}
+
+	if err := serialization.WritePreface(conn, version); err != nil {
+		return 0, err
+	}
+
+	return version, nil
+}
@@ -0,0 +1,27 @@
+package common
+
+import (
+	"crypto/tls"
+	"net"
+
+	"github.com/srand/mqc"
+)
+
+// NewPeer describes the remote end of a connection. TLS connections must
+// have completed the handshake.
+func NewPeer(conn net.Conn) *mqc.Peer {
+	peer := &mqc.Peer{Addr: conn.RemoteAddr()}
+
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		state := tlsConn.ConnectionState()
+		peer.TLS = &state
+		peer.Identity = mqc.TLSIdentity(&state)
+		conn = tlsConn.NetConn()
+	}
+
+	if unixConn, ok := conn.(*net.UnixConn); ok {
+		peer.Unix = unixCredentials(unixConn)
+	}
+
+	return peer
+}
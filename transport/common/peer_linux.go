@@ -0,0 +1,31 @@
+package common
+
+import (
+	"net"
+	"syscall"
+
+	"github.com/srand/mqc"
+)
+
+// unixCredentials returns the credentials of the peer process (SO_PEERCRED).
+func unixCredentials(conn *net.UnixConn) *mqc.UnixCredentials {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return nil
+	}
+
+	var ucred *syscall.Ucred
+	var ucredErr error
+	err = raw.Control(func(fd uintptr) {
+		ucred, ucredErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	})
+	if err != nil || ucredErr != nil {
+		return nil
+	}
+
+	return &mqc.UnixCredentials{
+		Pid: int(ucred.Pid),
+		Uid: int(ucred.Uid),
+		Gid: int(ucred.Gid),
+	}
+}
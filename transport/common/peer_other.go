@@ -0,0 +1,14 @@
+//go:build !linux
+
+package common
+
+import (
+	"net"
+
+	"github.com/srand/mqc"
+)
+
+// unixCredentials is not supported on this platform.
+func unixCredentials(conn *net.UnixConn) *mqc.UnixCredentials {
+	return nil
+}
@@ -0,0 +1,93 @@
+package common
+
+import (
+	"io"
+	"time"
+
+	"github.com/hashicorp/yamux"
+	"github.com/srand/mqc/transport"
+)
+
+// yamuxConfig returns the session configuration for the transport options.
+func yamuxConfig(opts *transport.TransportOptions) *yamux.Config {
+	config := yamux.DefaultConfig()
+
+	if opts.KeepAliveInterval > 0 {
+		config.KeepAliveInterval = opts.KeepAliveInterval
+	}
+
+	// yamux waits for ping responses as long as for any write to complete
+	if opts.KeepAliveTimeout > 0 {
+		config.ConnectionWriteTimeout = opts.KeepAliveTimeout
+	}
+
+	if opts.StreamWindowSize > 0 {
+		config.MaxStreamWindowSize = opts.StreamWindowSize
+	}
+
+	return config
+}
+
+// NewClientSession creates the client side of a multiplexed session
+// over a connection, configured from the transport options.
+func NewClientSession(conn io.ReadWriteCloser, opts *transport.TransportOptions) (*yamux.Session, error) {
+	session, err := yamux.Client(opts.Metrics.CountBytes(conn, opts.Protocol), yamuxConfig(opts))
+	if err != nil {
+		return nil, err
+	}
+	startSession(session, opts)
+	return session, nil
+}
+
+// NewServerSession creates the server side of a multiplexed session
+// over a connection, configured from the transport options.
+func NewServerSession(conn io.ReadWriteCloser, opts *transport.TransportOptions) (*yamux.Session, error) {
+	session, err := yamux.Server(opts.Metrics.CountBytes(conn, opts.Protocol), yamuxConfig(opts))
+	if err != nil {
+		return nil, err
+	}
+	startSession(session, opts)
+	return session, nil
+}
+
+// startSession starts closing the session when idle and
+// records it as open until it is closed.
+func startSession(session *yamux.Session, opts *transport.TransportOptions) {
+	if opts.IdleTimeout > 0 {
+		go closeIdle(session, opts.IdleTimeout)
+	}
+
+	if opts.Metrics != nil {
+		closed := opts.Metrics.OpenSession(opts.Protocol)
+		go func() {
+			<-session.CloseChan()
+			closed()
+		}()
+	}
+}
+
+// closeIdle closes a session once it has had no open streams for the timeout.
+func closeIdle(session *yamux.Session, timeout time.Duration) {
+	interval := timeout / 4
+	if interval <= 0 {
+		interval = timeout
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	active := time.Now()
+	for {
+		select {
+		case <-session.CloseChan():
+			return
+		case now := <-ticker.C:
+			if session.NumStreams() > 0 {
+				active = now
+			} else if now.Sub(active) >= timeout {
+				session.Close()
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package common
+
+import (
+	"sync"
+
+	"github.com/hashicorp/yamux"
+	"github.com/srand/mqc"
+)
+
+// ClientState tracks the state of the client connection of a transport.
+// It has its own mutex, so that the state can be read while connecting.
+// The zero value is idle.
+type ClientState struct {
+	mu      sync.Mutex
+	state   mqc.ConnState
+	session *yamux.Session
+}
+
+// Set sets the state of the connection.
+func (s *ClientState) Set(state mqc.ConnState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state = state
+	s.session = nil
+}
+
+// Ready reports the connection as ready until the session is closed.
+func (s *ClientState) Ready(session *yamux.Session) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state = mqc.ConnStateReady
+	s.session = session
+}
+
+// Get returns the state of the connection. A connection whose session was
+// closed, e.g. by keepalive, is idle until the next call reconnects.
+func (s *ClientState) Get() mqc.ConnState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state == mqc.ConnStateReady && s.session != nil && s.session.IsClosed() {
+		return mqc.ConnStateIdle
+	}
+	return s.state
+}
@@ -0,0 +1,62 @@
+package transport
+
+import (
+	"sync"
+
+	"github.com/srand/mqc"
+)
+
+// Handlers is a registry of method handlers, safe for concurrent use
+// while calls are handled.
+type Handlers struct {
+	mu       sync.RWMutex
+	handlers map[mqc.Method]mqc.MethodHandler
+}
+
+// NewHandlers returns an empty handler registry.
+func NewHandlers() *Handlers {
+	return &Handlers{handlers: make(map[mqc.Method]mqc.MethodHandler)}
+}
+
+// Register registers the handler of a method, replacing any previous handler.
+// It returns whether the method had no handler before.
+func (h *Handlers) Register(method *mqc.Method, handler mqc.MethodHandler) (added bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	_, ok := h.handlers[*method]
+	h.handlers[*method] = handler
+	return !ok
+}
+
+// Unregister removes the handler of a method.
+// It returns whether the method had a handler.
+func (h *Handlers) Unregister(method *mqc.Method) (removed bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	_, ok := h.handlers[*method]
+	delete(h.handlers, *method)
+	return ok
+}
+
+// Get returns the handler of a method.
+func (h *Handlers) Get(method *mqc.Method) (mqc.MethodHandler, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	handler, ok := h.handlers[*method]
+	return handler, ok
+}
+
+// Methods returns the methods with a registered handler.
+func (h *Handlers) Methods() []*mqc.Method {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	methods := make([]*mqc.Method, 0, len(h.handlers))
+	for method := range h.handlers {
+		methods = append(methods, &method)
+	}
+	return methods
+}
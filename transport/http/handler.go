@@ -1,11 +1,13 @@
 package http
 
 import (
-	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
+	"time"
 
-	"github.com/hashicorp/yamux"
 	"github.com/srand/mqc"
+	"github.com/srand/mqc/transport/common"
 	"golang.org/x/net/websocket"
 )
 
@@ -23,17 +25,32 @@ func NewHandler(transport mqc.Transport) http.Handler {
 	}
 
 	handler := websocket.Handler(func(ws *websocket.Conn) {
-		mux, err := yamux.Server(ws, nil)
+		peer := requestPeer(ws.Request())
+
+		// Clients must complete the handshake within the connect timeout
+		if t.Options.ConnectTimeout > 0 {
+			ws.SetDeadline(time.Now().Add(t.Options.ConnectTimeout))
+		}
+		version, err := common.ServerHandshake(ws)
 		if err != nil {
+			t.Options.Logger.Warn("handshake failed", slog.Any("peer", peer), slog.Any("error", err))
 			ws.Close()
 			return
 		}
-		defer mux.Close()
+		ws.SetDeadline(time.Time{})
 
-		if err := t.AcceptMux(mux); err != nil {
-			fmt.Println("Error accepting mux:", err)
+		mux, err := common.NewServerSession(ws, &t.Options)
+		if err != nil {
+			t.Options.Logger.Warn("failed to create session", slog.Any("peer", peer), slog.Any("error", err))
+			ws.Close()
 			return
 		}
+		defer mux.Close()
+
+		t.TrackSession(mux, ws.Request().RemoteAddr)
+
+		// The end of the session is logged by AcceptMux
+		t.AcceptMux(mux, peer, version)
 	})
 	return &httpHandler{
 		handler:   handler,
@@ -44,3 +61,14 @@ func NewHandler(transport mqc.Transport) http.Handler {
 func (h *httpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	h.handler.ServeHTTP(w, r)
 }
+
+// requestPeer describes the client of a websocket request.
+func requestPeer(r *http.Request) *mqc.Peer {
+	peer := &mqc.Peer{TLS: r.TLS, Identity: mqc.TLSIdentity(r.TLS)}
+
+	if addr, err := net.ResolveTCPAddr("tcp", r.RemoteAddr); err == nil {
+		peer.Addr = addr
+	}
+
+	return peer
+}
@@ -2,9 +2,13 @@ package http
 
 import (
 	"context"
+	"errors"
+	"log/slog"
 	"net"
 	"net/http"
 	"net/url"
+	"sync"
+	"time"
 
 	"github.com/hashicorp/yamux"
 	"github.com/srand/mqc"
@@ -17,15 +21,28 @@ import (
 // A websocket transport
 type websocketTransport struct {
 	common.BaseTransport
-	conn net.Conn
-	mux  *yamux.Session
+
+	// mu guards the client connection and the server
+	mu      sync.Mutex
+	conn    net.Conn
+	mux     *yamux.Session
+	version byte
+	server  *http.Server
+
+	// state is the state of the client connection
+	state common.ClientState
 }
 
 var _ mqc.Transport = (*websocketTransport)(nil)
 
 // NewWebSocketTransport creates a new WebSocket transport with the given options.
 func NewWebSocketTransport(options ...transport.TransportOption) (mqc.Transport, error) {
-	transportOptions := &transport.TransportOptions{}
+	transportOptions := &transport.TransportOptions{
+		ConnectTimeout: time.Second * 5,
+		CallTimeout:    time.Second * 5,
+		Protocol:       "websocket",
+		Logger:         slog.Default(),
+	}
 
 	for _, opt := range options {
 		if err := opt(transportOptions); err != nil {
@@ -35,33 +52,80 @@ func NewWebSocketTransport(options ...transport.TransportOption) (mqc.Transport,
 
 	return &websocketTransport{
 		BaseTransport: common.BaseTransport{
-			Handlers:  make(map[mqc.Method]mqc.MethodHandler),
+			Handlers:  transport.NewHandlers(),
 			Options:   *transportOptions,
 			Serialize: serialization.NewJSONSerializer(),
+			Channelz:  transportOptions.Channelz.AddTransport(transportOptions.Protocol, transportOptions.Addrs),
 		},
 	}, nil
 }
 
-func (t *websocketTransport) ensureConnected() error {
-	ws, err := websocket.Dial(t.Options.Addrs[0], "", t.Options.Origin)
+// ensureConnected dials the server unless the transport is connected.
+// It must be called with the mutex held.
+func (t *websocketTransport) ensureConnected(ctx context.Context) error {
+	if t.mux != nil && !t.mux.IsClosed() {
+		return nil
+	}
+	t.disconnect()
+
+	config, err := websocket.NewConfig(t.Options.Addrs[0], t.Options.Origin)
 	if err != nil {
 		return err
 	}
-	t.conn = ws
+	config.TlsConfig = t.Options.TlsConfig
+
+	ctx, cancel := t.Options.ConnectContext(ctx)
+	defer cancel()
+
+	t.state.Set(mqc.ConnStateConnecting)
 
-	// Create yamux session
-	mux, err := yamux.Client(ws, nil)
+	ws, err := config.DialContext(ctx)
 	if err != nil {
-		ws.Close()
+		t.state.Set(mqc.ConnStateTransientFailure)
+
+		// DialError does not unwrap to the context error
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 		return err
 	}
+
+	mux, version, err := common.ConnectSession(ctx, ws, &t.Options)
+	if err != nil {
+		t.state.Set(mqc.ConnStateTransientFailure)
+		return err
+	}
+
+	t.conn = ws
 	t.mux = mux
+	t.version = version
+	t.state.Ready(mux)
 
+	t.TrackSession(mux, t.Options.Addrs[0])
 	return nil
 }
 
 // Close closes the transport and releases any resources.
 func (t *websocketTransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.server != nil {
+		t.server.Close()
+		t.server = nil
+	}
+	t.Channelz.Remove()
+	t.state.Set(mqc.ConnStateShutdown)
+	return t.disconnect()
+}
+
+// State returns the state of the client connection.
+func (t *websocketTransport) State() mqc.ConnState {
+	return t.state.Get()
+}
+
+// disconnect closes the client connection.
+func (t *websocketTransport) disconnect() error {
 	if t.mux != nil {
 		t.mux.Close()
 		t.mux = nil
@@ -75,7 +139,10 @@ func (t *websocketTransport) Close() error {
 }
 
 func (t *websocketTransport) Dial() error {
-	return t.ensureConnected()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.ensureConnected(context.Background())
 }
 
 // Serve starts the server to accept incoming connections and handle requests.
@@ -93,13 +160,27 @@ func (t *websocketTransport) Serve() error {
 	mux := http.NewServeMux()
 	mux.Handle(path, NewHandler(t))
 
-	server := http.Server{
+	server := &http.Server{
 		Addr:      url.Host,
 		Handler:   mux,
 		TLSConfig: t.Options.TlsConfig,
 	}
 
-	return server.ListenAndServe()
+	// Close stops the server
+	t.mu.Lock()
+	t.server = server
+	t.mu.Unlock()
+
+	// Secure websockets are served with the certificates of the TLS config
+	if url.Scheme == "wss" {
+		err = server.ListenAndServeTLS("", "")
+	} else {
+		err = server.ListenAndServe()
+	}
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
 }
 
 func (t *websocketTransport) Invoke(ctx context.Context, method *mqc.Method) (mqc.Conn, error) {
@@ -107,9 +188,14 @@ func (t *websocketTransport) Invoke(ctx context.Context, method *mqc.Method) (mq
 		return nil, mqc.ErrPubSubNotSupported
 	}
 
-	if err := t.ensureConnected(); err != nil {
+	t.mu.Lock()
+	err := t.ensureConnected(ctx)
+	mux, version := t.mux, t.version
+	t.mu.Unlock()
+
+	if err != nil {
 		return nil, err
 	}
 
-	return t.InvokeMux(ctx, t.mux, method)
+	return t.InvokeMux(ctx, mux, version, method)
 }
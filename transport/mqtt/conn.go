@@ -4,7 +4,10 @@ import (
 	"context"
 	"errors"
 	"io"
+	"log/slog"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/srand/mqc"
 	"github.com/srand/mqc/serialization"
@@ -25,25 +28,58 @@ type callConn struct {
 	controlTopic       string
 	server             bool
 	serializer         serialization.Serializer
+	logger             *slog.Logger
 	err                error
+
+	// ended is set when the peer ended the call, and done is closed when
+	// the peer sent an error
+	ended    atomic.Bool
+	done     chan struct{}
+	doneOnce sync.Once
 }
 
 var _ mqc.Conn = (*callConn)(nil)
 
 func controlTopic(method *mqc.Method, id string) string {
-	return "MQC/" + method.Name + "/Control/" + id
+	return "MQC/" + method.FullName() + "/Control/" + id
 }
 
 func sharedControlTopic(method *mqc.Method, id string) string {
-	return "$share/MQC/MQC/" + method.Name + "/Control/" + id
+	return "$share/MQC/MQC/" + method.FullName() + "/Control/" + id
 }
 
 func clientTopic(method *mqc.Method, id string, name string) string {
-	return "MQC/" + method.Name + "/Client/" + id + "/" + name
+	return "MQC/" + method.FullName() + "/Client/" + id + "/" + name
 }
 
 func serverTopic(method *mqc.Method, id string, name string) string {
-	return "MQC/" + method.Name + "/Server/" + id + "/" + name
+	return "MQC/" + method.FullName() + "/Server/" + id + "/" + name
+}
+
+// Topics returns the topic filters a client publishes and subscribes to when
+// calling a method, e.g. to grant them in the ACL of a broker.
+// Servers handling the method publish to the subscribe topics and
+// subscribe to the publish topics.
+func Topics(method *mqc.Method) (publish, subscribe []string) {
+	if method.IsPubSub() {
+		if method.IsConsumer() {
+			return nil, []string{pubsubTopic(method)}
+		}
+		return []string{pubsubTopic(method)}, nil
+	}
+
+	publish = []string{controlTopic(method, "+"), clientTopic(method, "+", "+")}
+	subscribe = []string{serverTopic(method, "+", "+")}
+	return publish, subscribe
+}
+
+// qos returns the MQTT QoS level declared for the method,
+// or the given default if the method does not declare one.
+func qos(method *mqc.Method, def byte) byte {
+	if options := mqc.GetMethodOptions(method); options != nil && options.QoS != mqc.QoSDefault {
+		return byte(options.QoS - mqc.QoSAtMostOnce)
+	}
+	return def
 }
 
 func extractTopicId(topic string) string {
@@ -54,7 +90,7 @@ func extractTopicId(topic string) string {
 	return parts[len(parts)-1]
 }
 
-func newConn(serializer serialization.Serializer, client mqtt.Client, method *mqc.Method, id string, server bool) (*callConn, error) {
+func newConn(ctx context.Context, logger *slog.Logger, serializer serialization.Serializer, client mqtt.Client, method *mqc.Method, id string, server bool) (*callConn, error) {
 	receiver := make(chan *mqc.Message, 1)
 	cc := &callConn{
 		client:             client,
@@ -68,20 +104,22 @@ func newConn(serializer serialization.Serializer, client mqtt.Client, method *mq
 		controlTopic:       controlTopic(method, id),
 		server:             server,
 		serializer:         serializer,
+		logger:             logger,
+		done:               make(chan struct{}),
 	}
 
 	if server {
-		if err := cc.subscribe(cc.clientControlTopic, false); err != nil {
+		if err := cc.subscribe(ctx, cc.clientControlTopic, false); err != nil {
 			return nil, err
 		}
-		if err := cc.subscribe(cc.clientDataTopic, true); err != nil {
+		if err := cc.subscribe(ctx, cc.clientDataTopic, true); err != nil {
 			return nil, err
 		}
 	} else {
-		if err := cc.subscribe(cc.serverControlTopic, false); err != nil {
+		if err := cc.subscribe(ctx, cc.serverControlTopic, false); err != nil {
 			return nil, err
 		}
-		if err := cc.subscribe(cc.serverDataTopic, true); err != nil {
+		if err := cc.subscribe(ctx, cc.serverDataTopic, true); err != nil {
 			return nil, err
 		}
 	}
@@ -89,8 +127,11 @@ func newConn(serializer serialization.Serializer, client mqtt.Client, method *mq
 	return cc, nil
 }
 
-func (c *callConn) Invoke(ctx context.Context) error {
-	msg := mqc.NewCallMessage(&c.method)
+func (c *callConn) Invoke(ctx context.Context, md mqc.Metadata) error {
+	msg, err := mqc.NewCallMessage(&c.method, md)
+	if err != nil {
+		return err
+	}
 
 	payload, err := c.serializer.Marshal(msg)
 	if err != nil {
@@ -98,9 +139,8 @@ func (c *callConn) Invoke(ctx context.Context) error {
 	}
 
 	// Publish the call message to the invoke topic
-	token := c.client.Publish(c.controlTopic, 2, false, payload)
-	token.Wait()
-	if err := token.Error(); err != nil {
+	token := c.client.Publish(c.controlTopic, qos(&c.method, 2), false, payload)
+	if err := waitToken(ctx, token); err != nil {
 		return err
 	}
 
@@ -108,6 +148,17 @@ func (c *callConn) Invoke(ctx context.Context) error {
 	return c.RecvAck(ctx)
 }
 
+// Done returns a channel closed when the peer sent an error, e.g. when the
+// client closed the call before it ended.
+func (c *callConn) Done() <-chan struct{} {
+	return c.done
+}
+
+// Method returns the method the connection was invoked for.
+func (c *callConn) Method() *mqc.Method {
+	return &c.method
+}
+
 func (c *callConn) Recv(ctx context.Context) ([]byte, error) {
 	if c.err != nil {
 		return nil, c.err
@@ -150,6 +201,11 @@ func (c *callConn) RecvAck(ctx context.Context) error {
 		return io.EOF
 	}
 
+	// The server rejected the call, e.g. when unauthenticated
+	if msg.IsError() {
+		return msg.Error()
+	}
+
 	if !msg.IsAck() {
 		return mqc.ErrProtocolViolation
 	}
@@ -209,40 +265,37 @@ func (c *callConn) SendError(ctx context.Context, err error) error {
 }
 
 func (c *callConn) publish(ctx context.Context, topic string, data []byte) error {
-	token := c.client.Publish(topic, 2, false, data)
-
-	done := make(chan error)
-	go func() {
-		token.Wait()
-		if err := token.Error(); err != nil {
-			done <- err
-		}
-		close(done)
-	}()
-
-	select {
-	case <-ctx.Done():
-		return ctx.Err()
-	case err := <-done:
-		return err
-	}
-
+	token := c.client.Publish(topic, qos(&c.method, 2), false, data)
+	return waitToken(ctx, token)
 }
 
-func (c *callConn) subscribe(topic string, data bool) error {
-	token := c.client.Subscribe(topic, 0, func(_ mqtt.Client, msg mqtt.Message) {
+func (c *callConn) subscribe(ctx context.Context, topic string, data bool) error {
+	token := c.client.Subscribe(topic, qos(&c.method, 2), func(_ mqtt.Client, msg mqtt.Message) {
 		var m mqc.Message
 
 		if data {
 			m = *mqc.NewDataMessage(msg.Payload())
 		} else {
 			if err := c.serializer.Unmarshal(msg.Payload(), &m); err != nil {
+				c.logger.Warn("invalid control message", slog.String("topic", msg.Topic()), slog.Any("error", err))
 				return
 			}
 		}
 
+		if m.IsClose() || m.IsError() {
+			c.ended.Store(true)
+		}
+
+		// Errors are not queued if the messages are not received anymore,
+		// as they are also returned by later calls
 		if m.IsError() {
 			c.err = m.Error()
+			c.doneOnce.Do(func() { close(c.done) })
+			select {
+			case c.receiver <- &m:
+			default:
+			}
+			return
 		}
 
 		// Handle incoming messages
@@ -251,8 +304,7 @@ func (c *callConn) subscribe(topic string, data bool) error {
 	if token == nil {
 		return errors.New("failed to create subscription token")
 	}
-	token.Wait()
-	return token.Error()
+	return waitToken(ctx, token)
 }
 
 func (c *callConn) unsubscribe(topic string) error {
@@ -269,6 +321,14 @@ func (c *callConn) Close() error {
 		}
 		return c.unsubscribe(c.clientDataTopic)
 	}
+
+	// Servers stop handling calls closed before they ended
+	if !c.ended.Load() {
+		if data, err := c.serializer.Marshal(mqc.NewErrorMessage(context.Canceled)); err == nil {
+			c.client.Publish(c.clientControlTopic, qos(&c.method, 2), false, data)
+		}
+	}
+
 	err := c.unsubscribe(c.serverControlTopic)
 	if err != nil {
 		return err
@@ -0,0 +1,29 @@
+package mqtt
+
+import (
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/srand/mqc/metrics"
+)
+
+// countingClient records the payload bytes of published and received messages.
+type countingClient struct {
+	mqtt.Client
+	metrics *metrics.Metrics
+}
+
+func (c *countingClient) Publish(topic string, qos byte, retained bool, payload interface{}) mqtt.Token {
+	switch p := payload.(type) {
+	case []byte:
+		c.metrics.AddBytes("mqtt", len(p), 0)
+	case string:
+		c.metrics.AddBytes("mqtt", len(p), 0)
+	}
+	return c.Client.Publish(topic, qos, retained, payload)
+}
+
+func (c *countingClient) Subscribe(topic string, qos byte, callback mqtt.MessageHandler) mqtt.Token {
+	return c.Client.Subscribe(topic, qos, func(client mqtt.Client, msg mqtt.Message) {
+		c.metrics.AddBytes("mqtt", 0, len(msg.Payload()))
+		callback(client, msg)
+	})
+}
@@ -4,11 +4,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	mqtt "github.com/eclipse/paho.mqtt.golang"
 	"github.com/google/uuid"
 	"github.com/srand/mqc"
+	"github.com/srand/mqc/channelz"
+	"github.com/srand/mqc/metrics"
 	"github.com/srand/mqc/serialization"
 	"github.com/srand/mqc/transport"
 )
@@ -18,7 +23,20 @@ type pahoTransport struct {
 	mqttOptions *mqtt.ClientOptions
 	mqttClient  mqtt.Client
 	serializer  serialization.Serializer
-	handlers    map[mqc.Method]mqc.MethodHandler
+	handlers    *transport.Handlers
+	channelz    *channelz.Transport
+
+	// closed ends Serve when the transport is closed
+	closed    chan struct{}
+	closeOnce sync.Once
+
+	// failed is set while the last connection attempt failed
+	failed atomic.Bool
+
+	// mu guards the subscriptions of handlers, which map to
+	// a function removing their channelz entry
+	mu            sync.Mutex
+	subscriptions map[mqc.Method]func()
 }
 
 var _ mqc.Transport = (*pahoTransport)(nil)
@@ -27,6 +45,7 @@ func NewTransport(options ...transport.TransportOption) (mqc.Transport, error) {
 	transportOptions := &transport.TransportOptions{
 		ConnectTimeout: time.Second * 5,
 		CallTimeout:    time.Second * 5,
+		Logger:         slog.Default(),
 	}
 
 	for _, opt := range options {
@@ -49,7 +68,32 @@ func NewTransport(options ...transport.TransportOption) (mqc.Transport, error) {
 		mqttOptions.SetTLSConfig(transportOptions.TlsConfig)
 	}
 
-	client := mqtt.NewClient(mqttOptions)
+	if transportOptions.ConnectTimeout > 0 {
+		mqttOptions.SetConnectTimeout(transportOptions.ConnectTimeout)
+	}
+
+	if transportOptions.KeepAliveInterval > 0 {
+		mqttOptions.SetKeepAlive(transportOptions.KeepAliveInterval)
+	}
+
+	if transportOptions.KeepAliveTimeout > 0 {
+		mqttOptions.SetPingTimeout(transportOptions.KeepAliveTimeout)
+	}
+
+	mqttOptions.SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+		transportOptions.Logger.Warn("connection to broker lost", slog.Any("error", err))
+	})
+
+	mqttOptions.SetReconnectingHandler(func(mqtt.Client, *mqtt.ClientOptions) {
+		transportOptions.Logger.Info("reconnecting to broker")
+		transportOptions.Metrics.MQTTReconnect()
+	})
+
+	var client mqtt.Client = mqtt.NewClient(mqttOptions)
+	if transportOptions.Metrics != nil {
+		client = &countingClient{Client: client, metrics: transportOptions.Metrics}
+	}
+
 	serializer := serialization.NewJSONSerializer()
 
 	return &pahoTransport{
@@ -57,21 +101,30 @@ func NewTransport(options ...transport.TransportOption) (mqc.Transport, error) {
 		mqttClient:  client,
 		mqttOptions: mqttOptions,
 		serializer:  serializer,
-		handlers:    make(map[mqc.Method]mqc.MethodHandler),
+		handlers:    transport.NewHandlers(),
+		channelz:    transportOptions.Channelz.AddTransport("mqtt", transportOptions.Addrs),
+
+		subscriptions: make(map[mqc.Method]func()),
+		closed:        make(chan struct{}),
 	}, nil
 }
 
-func (p *pahoTransport) ensureConnected() error {
+func (p *pahoTransport) ensureConnected(ctx context.Context) error {
 	if p.mqttClient.IsConnected() {
 		return nil
 	}
 
-	if token := p.mqttClient.Connect(); token.Wait() && token.Error() != nil {
-		return token.Error()
+	ctx, cancel := p.options.ConnectContext(ctx)
+	defer cancel()
+
+	if err := waitToken(ctx, p.mqttClient.Connect()); err != nil {
+		p.failed.Store(true)
+		return err
 	}
+	p.failed.Store(false)
 
-	for method := range p.handlers {
-		if err := p.subscribe(&method); err != nil {
+	for _, method := range p.handlers.Methods() {
+		if err := p.subscribe(ctx, method); err != nil {
 			return err
 		}
 	}
@@ -85,11 +138,34 @@ func (p *pahoTransport) ensureConnected() error {
 
 func (p *pahoTransport) Close() error {
 	p.mqttClient.Disconnect(0)
+	p.channelz.Remove()
+	p.closeOnce.Do(func() { close(p.closed) })
 	return nil
 }
 
+// State returns the state of the connection to the broker.
+func (p *pahoTransport) State() mqc.ConnState {
+	select {
+	case <-p.closed:
+		return mqc.ConnStateShutdown
+	default:
+	}
+
+	switch {
+	case p.mqttClient.IsConnectionOpen():
+		return mqc.ConnStateReady
+	case p.mqttClient.IsConnected():
+		// The client is reconnecting after losing the connection
+		return mqc.ConnStateConnecting
+	case p.failed.Load():
+		return mqc.ConnStateTransientFailure
+	default:
+		return mqc.ConnStateIdle
+	}
+}
+
 func (p *pahoTransport) Invoke(ctx context.Context, method *mqc.Method) (mqc.Conn, error) {
-	if err := p.ensureConnected(); err != nil {
+	if err := p.ensureConnected(ctx); err != nil {
 		return nil, err
 	}
 
@@ -98,99 +174,238 @@ func (p *pahoTransport) Invoke(ctx context.Context, method *mqc.Method) (mqc.Con
 	}
 
 	if method.IsPubSub() {
-		return newPubSubConn(p.serializer, p.mqttClient, method)
+		return newPubSubConn(ctx, p.serializer, p.mqttClient, method, p.options.Tracer, p.options.TraceEnvelopes, p.options.Metrics, p.channelz)
+	}
+
+	ctx, span := mqc.StartSpan(ctx, p.options.Tracer, mqc.SpanInfo{
+		Kind:      mqc.SpanKindClient,
+		Method:    method,
+		Transport: "mqtt",
+	})
+	callMetrics := p.options.Metrics.StartCall(metrics.RoleClient, "mqtt", method)
+	callz := p.channelz.StartCall(channelz.RoleClient, method)
+
+	id := uuid.New().String()
+	logger := p.options.Logger.With(slog.String("call_id", id), slog.String("method", method.FullName()))
+	conn, err := newConn(ctx, logger, p.serializer, p.mqttClient, method, id, false)
+	if err != nil {
+		span.End(err)
+		callMetrics.End(err)
+		callz.End()
+		return nil, err
 	}
 
-	conn, err := newConn(p.serializer, p.mqttClient, method, uuid.New().String(), false)
+	md, err := mqc.OutgoingMetadata(ctx, p.options.Credentials, method)
 	if err != nil {
+		span.End(err)
+		callMetrics.End(err)
+		callz.End()
+		conn.Close()
 		return nil, err
 	}
 
-	err = conn.Invoke(ctx)
+	err = conn.Invoke(ctx, mqc.InjectTraceContext(ctx, p.options.Tracer, md))
 	if err != nil {
+		span.End(err)
+		callMetrics.End(err)
+		callz.End()
 		conn.Close()
 		return nil, err
 	}
 
-	return conn, nil
+	if p.options.Tracer == nil {
+		return callMetrics.Conn(callz.Conn(conn)), nil
+	}
+	return callMetrics.Conn(callz.Conn(mqc.NewTracedConn(conn, method, span))), nil
 }
 
+// RegisterHandler registers the handler of a method. Live transports
+// subscribe to the calls of new methods right away, others when connecting.
 func (p *pahoTransport) RegisterHandler(method *mqc.Method, handler mqc.MethodHandler) error {
-	p.handlers[*method] = handler
+	if handler == nil {
+		return fmt.Errorf("handler cannot be nil")
+	}
+
+	if !p.handlers.Register(method, handler) || !p.mqttClient.IsConnected() {
+		return nil
+	}
+
+	ctx, cancel := p.options.ConnectContext(context.Background())
+	defer cancel()
+
+	if err := p.subscribe(ctx, method); err != nil {
+		p.handlers.Unregister(method)
+		return err
+	}
 	return nil
 }
 
+// UnregisterHandler removes the handler of a method and unsubscribes
+// from its calls, leaving them to other servers of the method.
 func (p *pahoTransport) UnregisterHandler(method *mqc.Method) error {
-	delete(p.handlers, *method)
-	return nil
+	if !p.handlers.Unregister(method) {
+		return nil
+	}
+
+	ctx, cancel := p.options.ConnectContext(context.Background())
+	defer cancel()
+	return p.unsubscribe(ctx, method)
+}
+
+func (p *pahoTransport) Methods() []*mqc.Method {
+	return p.handlers.Methods()
 }
 
 func (p *pahoTransport) Dial() error {
 	if p.mqttClient.IsConnected() {
 		return fmt.Errorf("transport is already connected")
 	}
-	return p.ensureConnected()
+	return p.ensureConnected(context.Background())
 }
 
 func (p *pahoTransport) Serve() error {
-	if err := p.ensureConnected(); err != nil {
+	if err := p.ensureConnected(context.Background()); err != nil {
 		return err
 	}
 
-	select {}
+	<-p.closed
+	return nil
 }
 
 func (p *pahoTransport) Serializer() serialization.Serializer {
 	return p.serializer
 }
 
-func (p *pahoTransport) subscribe(method *mqc.Method) error {
+// CallTimeout returns the default deadline for client calls.
+func (p *pahoTransport) CallTimeout() time.Duration {
+	return p.options.CallTimeout
+}
+
+func (p *pahoTransport) subscribe(ctx context.Context, method *mqc.Method) error {
 	topic := sharedControlTopic(method, "+")
 
-	token := p.mqttClient.Subscribe(topic, 0, func(_ mqtt.Client, msg mqtt.Message) {
+	token := p.mqttClient.Subscribe(topic, qos(method, 2), func(_ mqtt.Client, msg mqtt.Message) {
 		var m mqc.Message
 
+		id := extractTopicId(msg.Topic())
+		logger := p.options.Logger.With(slog.String("call_id", id))
+
 		if err := p.serializer.Unmarshal(msg.Payload(), &m); err != nil {
+			logger.Warn("invalid control message", slog.String("topic", msg.Topic()), slog.Any("error", err))
 			return
 		}
 
+		// Control messages of ongoing calls are handled by their connection
 		if !m.IsCall() {
 			return
 		}
 
-		handler, ok := p.handlers[*m.Method()]
-		if !ok {
+		invoked, md, err := m.Invoke()
+		if err != nil {
+			logger.Warn("invalid call", slog.String("topic", msg.Topic()), slog.Any("error", err))
 			return
 		}
 
-		conn, err := newConn(p.serializer, p.mqttClient, method, extractTopicId(msg.Topic()), true)
+		logger = logger.With(slog.String("method", invoked.FullName()))
+
+		ctx, cancel := mqc.WithDefaultTimeout(context.Background(), p.options.CallTimeout)
+		conn, err := newConn(ctx, logger, p.serializer, p.mqttClient, method, id, true)
+		cancel()
 		if err != nil {
+			logger.Warn("failed to accept call", slog.Any("error", err))
 			return
 		}
 
-		go func() {
-			defer func() {
-				if r := recover(); r != nil {
-					fmt.Println("Recovered in server goroutine:", r)
-				}
-			}()
+		// Brokers grant topics per method, so a call may only invoke the
+		// method of the topic it was published to
+		if *invoked != *method {
+			logger.Warn("call rejected", slog.String("topic", msg.Topic()), slog.Any("error", mqc.ErrPermissionDenied))
+			ctx, cancel := mqc.WithDefaultTimeout(context.Background(), p.options.CallTimeout)
+			conn.SendError(ctx, mqc.ErrPermissionDenied)
+			cancel()
+			conn.Close()
+			return
+		}
 
+		handler, ok := p.handlers.Get(invoked)
+		if !ok {
+			logger.Warn("unimplemented method")
+			ctx, cancel := mqc.WithDefaultTimeout(context.Background(), p.options.CallTimeout)
+			conn.SendError(ctx, mqc.ErrUnimplemented)
+			cancel()
+			conn.Close()
+			return
+		}
+
+		go func() {
 			defer conn.Close()
 
-			ctx, cancel := context.WithTimeout(context.Background(), p.options.CallTimeout)
+			ctx, cancel := mqc.WithDefaultTimeout(context.Background(), p.options.CallTimeout)
 			defer cancel()
 
+			// Handlers stop when the client closes the call
+			baseCtx, cancelCall := context.WithCancel(context.Background())
+			defer cancelCall()
+			go func() {
+				select {
+				case <-conn.Done():
+					cancelCall()
+				case <-baseCtx.Done():
+				}
+			}()
+
+			spanCtx, span := mqc.StartSpan(baseCtx, p.options.Tracer, mqc.SpanInfo{
+				Kind:      mqc.SpanKindServer,
+				Method:    invoked,
+				Transport: "mqtt",
+				Remote:    md,
+			})
+			callMetrics := p.options.Metrics.StartCall(metrics.RoleServer, "mqtt", invoked)
+			callz := p.channelz.StartCall(channelz.RoleServer, invoked)
+			defer callz.End()
+
+			// Reject unauthenticated and unauthorized calls instead of acking them
+			callCtx, err := mqc.Authenticate(spanCtx, p.options.Authenticator, invoked, md)
+			if err == nil {
+				err = mqc.Authorize(callCtx, p.options.Authorizer, invoked)
+			}
+			if err != nil {
+				logger.Warn("call rejected", slog.Any("error", err))
+				span.End(err)
+				callMetrics.End(err)
+				conn.SendError(ctx, err)
+				return
+			}
+
 			// Ack the received message
 			if err := conn.SendAck(ctx); err != nil {
+				logger.Warn("failed to ack call", slog.Any("error", err))
+				span.End(err)
+				callMetrics.End(err)
 				return
 			}
 
-			ctx, cancel = context.WithTimeout(context.Background(), p.options.CallTimeout)
+			ctx, cancel = mqc.WithDefaultTimeout(context.Background(), p.options.CallTimeout)
 			defer cancel()
 
-			if err := handler(conn); err != nil {
+			logger.Debug("call started")
+
+			err = mqc.CallHandler(callCtx, handler, invoked, callMetrics.Conn(callz.Conn(conn)))
+			span.End(err)
+			callMetrics.End(err)
+
+			var panicErr *mqc.PanicError
+			if errors.As(err, &panicErr) {
+				p.options.HandlePanic(callCtx, logger, panicErr)
+				conn.SendError(ctx, mqc.ErrInternal)
+				if p.options.PanicPolicy == mqc.PanicRepanic {
+					panic(panicErr)
+				}
+			} else if err != nil {
+				logger.Debug("call failed", slog.Any("error", err))
 				conn.SendError(ctx, err)
 			} else {
+				logger.Debug("call completed")
 				conn.SendClose(ctx)
 			}
 		}()
@@ -198,6 +413,42 @@ func (p *pahoTransport) subscribe(method *mqc.Method) error {
 	if token == nil {
 		return errors.New("failed to create subscription token")
 	}
-	token.Wait()
-	return token.Error()
+	if err := waitToken(ctx, token); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// Subscriptions are renewed when reconnecting
+	if remove, ok := p.subscriptions[*method]; ok {
+		remove()
+	}
+	p.subscriptions[*method] = p.channelz.Subscribe(topic, method)
+	return nil
+}
+
+// unsubscribe stops receiving the calls of a method.
+func (p *pahoTransport) unsubscribe(ctx context.Context, method *mqc.Method) error {
+	p.mu.Lock()
+	if remove, ok := p.subscriptions[*method]; ok {
+		remove()
+		delete(p.subscriptions, *method)
+	}
+	p.mu.Unlock()
+
+	if !p.mqttClient.IsConnected() {
+		return nil
+	}
+	return waitToken(ctx, p.mqttClient.Unsubscribe(sharedControlTopic(method, "+")))
+}
+
+// waitToken waits for an MQTT operation to complete or the context to be done.
+func waitToken(ctx context.Context, token mqtt.Token) error {
+	select {
+	case <-token.Done():
+		return token.Error()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
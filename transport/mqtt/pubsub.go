@@ -1,13 +1,17 @@
 package mqtt
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"io"
 
 	mqtt "github.com/eclipse/paho.mqtt.golang"
 	"github.com/srand/mqc"
+	"github.com/srand/mqc/channelz"
+	"github.com/srand/mqc/metrics"
 	"github.com/srand/mqc/serialization"
+	"google.golang.org/protobuf/proto"
 )
 
 type pubsubConn struct {
@@ -16,16 +20,53 @@ type pubsubConn struct {
 	receiver   chan *mqc.Message
 	topic      string
 	serializer serialization.Serializer
+	tracer     mqc.Tracer
+	envelopes  bool
+	metrics    *metrics.Metrics
+	consumer   *channelz.Consumer
 	err        error
 }
 
+// envelopeMagic prefixes published messages wrapped in an envelope carrying
+// metadata, see transport.WithTraceEnvelopes. Neither protobuf messages nor
+// JSON documents start with a zero byte.
+var envelopeMagic = []byte("\x00MQC")
+
+// wrapEnvelope wraps a published message in an envelope carrying the metadata.
+func wrapEnvelope(data []byte, md mqc.Metadata) ([]byte, error) {
+	envelope, err := proto.Marshal(&mqc.Message{Type: mqc.Message_DATA, Data: data, Metadata: md})
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]byte{}, envelopeMagic...), envelope...), nil
+}
+
+// unwrapEnvelope returns the message in a published payload,
+// which is either wrapped in an envelope or the plain message.
+func unwrapEnvelope(payload []byte) *mqc.Message {
+	if bytes.HasPrefix(payload, envelopeMagic) {
+		var m mqc.Message
+		if err := proto.Unmarshal(payload[len(envelopeMagic):], &m); err == nil && m.Type == mqc.Message_DATA {
+			return &m
+		}
+	}
+
+	return &mqc.Message{
+		Type: mqc.Message_DATA,
+		Data: payload,
+	}
+}
+
 var _ mqc.Conn = (*pubsubConn)(nil)
 
 func pubsubTopic(method *mqc.Method) string {
-	return "MQC/" + method.Name
+	if topic := method.Topic(); topic != "" {
+		return topic
+	}
+	return "MQC/" + method.FullName()
 }
 
-func newPubSubConn(serializer serialization.Serializer, client mqtt.Client, method *mqc.Method) (*pubsubConn, error) {
+func newPubSubConn(ctx context.Context, serializer serialization.Serializer, client mqtt.Client, method *mqc.Method, tracer mqc.Tracer, envelopes bool, m *metrics.Metrics, cz *channelz.Transport) (*pubsubConn, error) {
 	receiver := make(chan *mqc.Message, 1)
 	pc := &pubsubConn{
 		client:     client,
@@ -33,15 +74,24 @@ func newPubSubConn(serializer serialization.Serializer, client mqtt.Client, meth
 		receiver:   receiver,
 		topic:      pubsubTopic(method),
 		serializer: serializer,
+		tracer:     tracer,
+		envelopes:  envelopes,
+		metrics:    m,
 	}
 	if method.IsConsumer() {
-		if err := pc.subscribe(pc.topic, false); err != nil {
+		if err := pc.subscribe(ctx, pc.topic, false); err != nil {
 			return nil, err
 		}
+		pc.consumer = cz.AddConsumer(pc.topic, method)
 	}
 	return pc, nil
 }
 
+// Method returns the method the connection was invoked for.
+func (c *pubsubConn) Method() *mqc.Method {
+	return &c.method
+}
+
 func (c *pubsubConn) Recv(ctx context.Context) ([]byte, error) {
 	if c.err != nil {
 		return nil, c.err
@@ -64,6 +114,17 @@ func (c *pubsubConn) Recv(ctx context.Context) ([]byte, error) {
 		return nil, c.err
 	}
 
+	// Link the received message to the trace of its publisher
+	_, span := mqc.StartSpan(ctx, c.tracer, mqc.SpanInfo{
+		Kind:      mqc.SpanKindConsumer,
+		Method:    &c.method,
+		Transport: "mqtt",
+		Remote:    msg.Metadata,
+	})
+	span.End(nil)
+	c.metrics.Received(&c.method)
+	c.consumer.Received()
+
 	return msg.DataBytes(), nil
 }
 
@@ -76,7 +137,35 @@ func (c *pubsubConn) Send(ctx context.Context, data []byte) error {
 		return c.err
 	}
 
-	return c.publish(ctx, c.topic, data)
+	if c.tracer == nil {
+		err := c.publish(ctx, c.topic, data)
+		if err == nil {
+			c.metrics.Published(&c.method)
+		}
+		return err
+	}
+
+	ctx, span := c.tracer.Start(ctx, mqc.SpanInfo{
+		Kind:      mqc.SpanKindProducer,
+		Method:    &c.method,
+		Transport: "mqtt",
+	})
+
+	// Published messages only carry the trace context in an envelope if
+	// enabled, as other consumers expect the plain messages
+	payload := data
+	var err error
+	if c.envelopes {
+		payload, err = wrapEnvelope(data, mqc.InjectTraceContext(ctx, c.tracer, nil))
+	}
+	if err == nil {
+		err = c.publish(ctx, c.topic, payload)
+	}
+	span.End(err)
+	if err == nil {
+		c.metrics.Published(&c.method)
+	}
+	return err
 }
 
 func (c *pubsubConn) SendClose(ctx context.Context) error {
@@ -88,43 +177,18 @@ func (c *pubsubConn) SendMethod(ctx context.Context, method mqc.Method) error {
 }
 
 func (c *pubsubConn) publish(ctx context.Context, topic string, data []byte) error {
-	token := c.client.Publish(topic, 0, false, data)
-
-	done := make(chan error)
-	go func() {
-		token.Wait()
-		if err := token.Error(); err != nil {
-			done <- err
-		}
-		close(done)
-	}()
-
-	select {
-	case <-ctx.Done():
-		return ctx.Err()
-	case err := <-done:
-		return err
-	}
+	token := c.client.Publish(topic, qos(&c.method, 0), false, data)
+	return waitToken(ctx, token)
 }
 
-func (c *pubsubConn) subscribe(topic string, data bool) error {
-	token := c.client.Subscribe(topic, 0, func(_ mqtt.Client, msg mqtt.Message) {
-		m := mqc.Message{
-			Type: mqc.Message_DATA,
-			Data: msg.Payload(),
-		}
-
-		if m.IsError() {
-			c.err = m.Error()
-		}
-
-		c.receiver <- &m
+func (c *pubsubConn) subscribe(ctx context.Context, topic string, data bool) error {
+	token := c.client.Subscribe(topic, qos(&c.method, 0), func(_ mqtt.Client, msg mqtt.Message) {
+		c.receiver <- unwrapEnvelope(msg.Payload())
 	})
 	if token == nil {
 		return errors.New("failed to create subscription token")
 	}
-	token.Wait()
-	return token.Error()
+	return waitToken(ctx, token)
 }
 
 func (c *pubsubConn) unsubscribe(topic string) error {
@@ -134,5 +198,6 @@ func (c *pubsubConn) unsubscribe(topic string) error {
 }
 
 func (c *pubsubConn) Close() error {
+	c.consumer.Remove()
 	return c.unsubscribe(c.topic)
 }
@@ -1,21 +1,30 @@
 package transport
 
 import (
+	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"log/slog"
+	"os"
 	"time"
 
 	"github.com/srand/mqc"
+	"github.com/srand/mqc/channelz"
+	"github.com/srand/mqc/metrics"
 )
 
 type TransportOptions struct {
 	Addrs []string
 
-	// Timeout for the dial operation
-	ConnectTimeout time.Duration // in seconds
+	// Timeout for dialing and the handshake with the server.
+	// The caller's context may end the connection attempt earlier.
+	ConnectTimeout time.Duration
 
-	// Timeout for individual RPC calls
-	CallTimeout time.Duration // in seconds
+	// Default deadline for client calls whose context has none.
+	// Calls are bounded as a whole, including all messages of streaming
+	// calls. Zero for no default deadline.
+	CallTimeout time.Duration
 
 	// Underlying protocol to use (e.g. "tcp", "unix").
 	// Varies depending on the transport implementation.
@@ -29,10 +38,78 @@ type TransportOptions struct {
 
 	// Origin is the allowed origin for CORS requests (used in HTTP transport)
 	Origin string
+
+	// Interval between keepalive pings, zero for the transport default.
+	KeepAliveInterval time.Duration
+
+	// Time to wait for a keepalive response before the connection
+	// is considered dead, zero for the transport default.
+	KeepAliveTimeout time.Duration
+
+	// Idle connections without any streams are closed after this duration.
+	// Zero keeps idle connections open.
+	IdleTimeout time.Duration
+
+	// Maximum number of streams handled concurrently per session.
+	// Zero for no limit.
+	MaxConcurrentStreams int
+
+	// Maximum flow control window of a stream in bytes,
+	// zero for the transport default.
+	StreamWindowSize uint32
+
+	// Credentials attached to every call made by the transport.
+	Credentials mqc.Credentials
+
+	// Authenticator validating the credentials of incoming calls.
+	Authenticator mqc.Authenticator
+
+	// Authorizer deciding which callers may call which methods.
+	Authorizer mqc.Authorizer
+
+	// Tracer creating spans for outgoing and incoming calls.
+	Tracer mqc.Tracer
+
+	// TraceEnvelopes wraps published pub-sub messages in an envelope
+	// carrying the trace context of the publisher.
+	TraceEnvelopes bool
+
+	// Metrics recording calls, streams and connections.
+	Metrics *metrics.Metrics
+
+	// Logger receiving structured events of the transport.
+	Logger *slog.Logger
+
+	// Channelz registry tracking the sessions and calls of the transport.
+	Channelz *channelz.Registry
+
+	// What to do after a handler panicked, see mqc.PanicPolicy.
+	PanicPolicy mqc.PanicPolicy
+
+	// PanicHandler called with every handler panic, e.g. for crash reporting.
+	PanicHandler mqc.PanicHandler
 }
 
 type TransportOption func(*TransportOptions) error
 
+// ConnectContext bounds a context by the connect timeout.
+func (o *TransportOptions) ConnectContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if o.ConnectTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, o.ConnectTimeout)
+}
+
+// HandlePanic reports a handler panic to the logger and the panic handler.
+// The caller must send mqc.ErrInternal, and panic again with p if the
+// policy is mqc.PanicRepanic.
+func (o *TransportOptions) HandlePanic(ctx context.Context, logger *slog.Logger, p *mqc.PanicError) {
+	logger.Error("panic in handler", slog.Any("panic", p.Value), slog.String("stack", string(p.Stack)))
+	if o.PanicHandler != nil {
+		o.PanicHandler(ctx, p)
+	}
+}
+
 func WithAddress(addr string) TransportOption {
 	return func(opts *TransportOptions) error {
 		opts.Addrs = append(opts.Addrs, addr)
@@ -84,6 +161,133 @@ func WithCertificateFile(certFile, keyFile string) TransportOption {
 	}
 }
 
+// WithRootCAs verifies the certificate of the server with the
+// certificate authorities in the pool, e.g. of a mqc.CertificateAuthority.
+func WithRootCAs(pool *x509.CertPool) TransportOption {
+	return func(opts *TransportOptions) error {
+		if pool == nil {
+			return fmt.Errorf("root CA pool cannot be nil")
+		}
+
+		if opts.TlsConfig == nil {
+			opts.TlsConfig = &tls.Config{}
+		}
+
+		opts.TlsConfig.RootCAs = pool
+		return nil
+	}
+}
+
+// WithRootCAFile verifies the certificate of the server with the
+// PEM encoded certificate authorities in the file.
+func WithRootCAFile(caFile string) TransportOption {
+	return func(opts *TransportOptions) error {
+		pool, err := loadCertPool(caFile)
+		if err != nil {
+			return err
+		}
+		return WithRootCAs(pool)(opts)
+	}
+}
+
+// WithClientAuth sets the policy of a server for client certificates.
+// Clients present the certificate configured with WithCertificateFile.
+func WithClientAuth(clientAuth tls.ClientAuthType) TransportOption {
+	return func(opts *TransportOptions) error {
+		if opts.TlsConfig == nil {
+			opts.TlsConfig = &tls.Config{}
+		}
+
+		opts.TlsConfig.ClientAuth = clientAuth
+		return nil
+	}
+}
+
+// WithClientCAs verifies client certificates with the certificate authorities
+// in the pool. Client certificates are required unless another policy is
+// set with WithClientAuth.
+func WithClientCAs(pool *x509.CertPool) TransportOption {
+	return func(opts *TransportOptions) error {
+		if pool == nil {
+			return fmt.Errorf("client CA pool cannot be nil")
+		}
+
+		if opts.TlsConfig == nil {
+			opts.TlsConfig = &tls.Config{}
+		}
+
+		opts.TlsConfig.ClientCAs = pool
+		if opts.TlsConfig.ClientAuth == tls.NoClientCert {
+			opts.TlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+		return nil
+	}
+}
+
+// WithClientCAFile verifies client certificates with the PEM encoded
+// certificate authorities in the file, see WithClientCAs.
+func WithClientCAFile(caFile string) TransportOption {
+	return func(opts *TransportOptions) error {
+		pool, err := loadCertPool(caFile)
+		if err != nil {
+			return err
+		}
+		return WithClientCAs(pool)(opts)
+	}
+}
+
+// WithAllowedSANs only accepts peers whose certificate has one of the subject
+// alternative names: URIs, DNS names, email addresses or IP addresses.
+// It applies to client certificates on servers and to the server certificate
+// on clients, and peers without a certificate are rejected.
+func WithAllowedSANs(sans ...string) TransportOption {
+	return func(opts *TransportOptions) error {
+		if len(sans) == 0 {
+			return fmt.Errorf("at least one SAN must be allowed")
+		}
+
+		allowed := make(map[string]bool, len(sans))
+		for _, san := range sans {
+			allowed[san] = true
+		}
+
+		if opts.TlsConfig == nil {
+			opts.TlsConfig = &tls.Config{}
+		}
+
+		opts.TlsConfig.VerifyConnection = func(state tls.ConnectionState) error {
+			if len(state.PeerCertificates) == 0 {
+				return fmt.Errorf("peer did not present a certificate")
+			}
+
+			for _, san := range mqc.CertificateSANs(state.PeerCertificates[0]) {
+				if allowed[san] {
+					return nil
+				}
+			}
+			return fmt.Errorf("peer certificate has no allowed SAN")
+		}
+		return nil
+	}
+}
+
+func loadCertPool(caFile string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA file: %v", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in %s", caFile)
+	}
+	return pool, nil
+}
+
+// WithSelfSignedCert generates an ephemeral self-signed certificate and
+// disables the verification of peer certificates. Connections are encrypted
+// but not protected against impersonation; prefer WithPersistentSelfSignedCert
+// on servers and WithPublicKeyPin on clients.
 func WithSelfSignedCert() TransportOption {
 	return func(opts *TransportOptions) error {
 		if opts.TlsConfig == nil {
@@ -103,6 +307,67 @@ func WithSelfSignedCert() TransportOption {
 	}
 }
 
+// WithPersistentSelfSignedCert loads the certificate of a server from PEM files,
+// or generates a self-signed certificate for the subject alternative names and
+// saves it if the files do not exist. Unlike WithSelfSignedCert the key pair
+// survives restarts, so that clients can pin it with WithPublicKeyPin.
+func WithPersistentSelfSignedCert(certFile, keyFile string, sans ...string) TransportOption {
+	return func(opts *TransportOptions) error {
+		cert, err := mqc.LoadOrGenerateCertificate(certFile, keyFile, 10*365*24*time.Hour, sans...)
+		if err != nil {
+			return err
+		}
+
+		if opts.TlsConfig == nil {
+			opts.TlsConfig = &tls.Config{}
+		}
+
+		opts.TlsConfig.Certificates = []tls.Certificate{cert}
+		return nil
+	}
+}
+
+// WithPublicKeyPin verifies servers by the public key fingerprint of their
+// certificate instead of a certificate authority, e.g. with
+// mqc.PinFingerprints or mqc.TrustOnFirstUse. The certificate must still be
+// within its validity period.
+func WithPublicKeyPin(verify mqc.PinVerifier) TransportOption {
+	return func(opts *TransportOptions) error {
+		if verify == nil {
+			return fmt.Errorf("pin verifier cannot be nil")
+		}
+
+		if opts.TlsConfig == nil {
+			opts.TlsConfig = &tls.Config{}
+		}
+
+		// The pin replaces the verification of the certificate chain
+		opts.TlsConfig.InsecureSkipVerify = true
+		opts.TlsConfig.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			if len(rawCerts) == 0 {
+				return fmt.Errorf("server did not present a certificate")
+			}
+
+			cert, err := x509.ParseCertificate(rawCerts[0])
+			if err != nil {
+				return err
+			}
+
+			now := time.Now()
+			if now.Before(cert.NotBefore) || now.After(cert.NotAfter) {
+				return fmt.Errorf("server certificate is expired or not yet valid")
+			}
+
+			var addr string
+			if len(opts.Addrs) > 0 {
+				addr = opts.Addrs[0]
+			}
+			return verify(addr, mqc.SPKIFingerprint(cert))
+		}
+		return nil
+	}
+}
+
 func WithOnConnect(f func(mqc.Transport)) TransportOption {
 	return func(opts *TransportOptions) error {
 		if f == nil {
@@ -122,3 +387,185 @@ func WithOrigin(origin string) TransportOption {
 		return nil
 	}
 }
+
+// WithKeepAlive sets the interval between keepalive pings and the time to
+// wait for a response, after which the connection is closed.
+// Zero keeps the transport default.
+// Used by the multiplexed transports (tcp, unix, websocket) and MQTT.
+func WithKeepAlive(interval, timeout time.Duration) TransportOption {
+	return func(opts *TransportOptions) error {
+		if interval < 0 || timeout < 0 {
+			return fmt.Errorf("keepalive interval and timeout must not be negative")
+		}
+		opts.KeepAliveInterval = interval
+		opts.KeepAliveTimeout = timeout
+		return nil
+	}
+}
+
+// WithIdleTimeout closes connections that have had no open streams for the
+// given duration. Clients reconnect on the next call.
+// Used by the multiplexed transports (tcp, unix, websocket).
+func WithIdleTimeout(d time.Duration) TransportOption {
+	return func(opts *TransportOptions) error {
+		if d < 0 {
+			return fmt.Errorf("idle timeout must not be negative")
+		}
+		opts.IdleTimeout = d
+		return nil
+	}
+}
+
+// WithMaxConcurrentStreams limits the number of incoming streams handled
+// concurrently per session. Further streams wait for a running call to finish.
+// Used by the multiplexed transports (tcp, unix, websocket).
+func WithMaxConcurrentStreams(n int) TransportOption {
+	return func(opts *TransportOptions) error {
+		if n < 0 {
+			return fmt.Errorf("max concurrent streams must not be negative")
+		}
+		opts.MaxConcurrentStreams = n
+		return nil
+	}
+}
+
+// WithStreamWindowSize sets the maximum flow control window of a stream,
+// i.e. how many bytes may be in flight before the sender blocks.
+// Used by the multiplexed transports (tcp, unix, websocket).
+func WithStreamWindowSize(size uint32) TransportOption {
+	return func(opts *TransportOptions) error {
+		// The window must not be smaller than the yamux initial window
+		if size != 0 && size < 256*1024 {
+			return fmt.Errorf("stream window size must be at least 256 KiB")
+		}
+		opts.StreamWindowSize = size
+		return nil
+	}
+}
+
+// WithCredentials attaches credentials to every call made by the transport,
+// e.g. a bearer token. Credentials should only be sent over TLS.
+func WithCredentials(credentials mqc.Credentials) TransportOption {
+	return func(opts *TransportOptions) error {
+		if credentials == nil {
+			return fmt.Errorf("credentials cannot be nil")
+		}
+		opts.Credentials = credentials
+		return nil
+	}
+}
+
+// WithAuthenticator validates the credentials of incoming calls before their
+// handler runs. Rejected calls fail with mqc.ErrUnauthenticated.
+func WithAuthenticator(authenticator mqc.Authenticator) TransportOption {
+	return func(opts *TransportOptions) error {
+		if authenticator == nil {
+			return fmt.Errorf("authenticator cannot be nil")
+		}
+		opts.Authenticator = authenticator
+		return nil
+	}
+}
+
+// WithAuthorizer restricts which callers may call which methods, e.g. with an
+// authz policy. It runs after the authenticator, before the handler.
+// Denied calls fail with mqc.ErrPermissionDenied.
+func WithAuthorizer(authorizer mqc.Authorizer) TransportOption {
+	return func(opts *TransportOptions) error {
+		if authorizer == nil {
+			return fmt.Errorf("authorizer cannot be nil")
+		}
+		opts.Authorizer = authorizer
+		return nil
+	}
+}
+
+// WithTracer creates a span for every outgoing and incoming call, and
+// propagates the W3C trace context with the calls, e.g. with the
+// opentelemetry package. On MQTT, published and received pub-sub messages
+// are also traced, but their trace context is only propagated with
+// WithTraceEnvelopes.
+func WithTracer(tracer mqc.Tracer) TransportOption {
+	return func(opts *TransportOptions) error {
+		if tracer == nil {
+			return fmt.Errorf("tracer cannot be nil")
+		}
+		opts.Tracer = tracer
+		return nil
+	}
+}
+
+// WithTraceEnvelopes propagates the trace context of the publisher with the
+// messages published to pub-sub methods, so that the spans of received
+// messages link to it. Messages are wrapped in an envelope, the bytes
+// "\x00MQC" followed by an mqc.Message in protobuf, which consumers other
+// than mqc transports do not understand. Used by the MQTT transport with
+// a tracer.
+func WithTraceEnvelopes() TransportOption {
+	return func(opts *TransportOptions) error {
+		opts.TraceEnvelopes = true
+		return nil
+	}
+}
+
+// WithMetrics records metrics of the calls, streams and connections
+// of the transport, e.g. in a metrics.MemoryRegistry.
+func WithMetrics(m *metrics.Metrics) TransportOption {
+	return func(opts *TransportOptions) error {
+		if m == nil {
+			return fmt.Errorf("metrics cannot be nil")
+		}
+		opts.Metrics = m
+		return nil
+	}
+}
+
+// WithChannelz tracks the sessions, in-flight calls, MQTT subscriptions and
+// pub-sub consumers of the transport in the registry, for live introspection.
+func WithChannelz(registry *channelz.Registry) TransportOption {
+	return func(opts *TransportOptions) error {
+		if registry == nil {
+			return fmt.Errorf("channelz registry cannot be nil")
+		}
+		opts.Channelz = registry
+		return nil
+	}
+}
+
+// WithLogger sets the logger receiving structured events of the transport,
+// e.g. rejected calls, unknown methods and protocol errors. Transports log
+// to the default logger unless set.
+func WithLogger(logger *slog.Logger) TransportOption {
+	return func(opts *TransportOptions) error {
+		if logger == nil {
+			return fmt.Errorf("logger cannot be nil")
+		}
+		opts.Logger = logger
+		return nil
+	}
+}
+
+// WithPanicPolicy sets whether servers keep running after a handler panicked,
+// or panic again once the caller received mqc.ErrInternal. Panics are
+// recovered by default.
+func WithPanicPolicy(policy mqc.PanicPolicy) TransportOption {
+	return func(opts *TransportOptions) error {
+		if policy != mqc.PanicRecover && policy != mqc.PanicRepanic {
+			return fmt.Errorf("unknown panic policy %d", policy)
+		}
+		opts.PanicPolicy = policy
+		return nil
+	}
+}
+
+// WithPanicHandler calls the handler with the value and stack trace of every
+// handler panic, e.g. to report crashes, before the policy is applied.
+func WithPanicHandler(handler mqc.PanicHandler) TransportOption {
+	return func(opts *TransportOptions) error {
+		if handler == nil {
+			return fmt.Errorf("panic handler cannot be nil")
+		}
+		opts.PanicHandler = handler
+		return nil
+	}
+}
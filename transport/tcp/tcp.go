@@ -4,7 +4,9 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
+	"log/slog"
 	"net"
+	"sync"
 	"time"
 
 	"github.com/hashicorp/yamux"
@@ -17,8 +19,18 @@ import (
 type tcpTransport struct {
 	common.BaseTransport
 
-	conn net.Conn
-	mux  *yamux.Session
+	// mu guards the client connection and the listener
+	mu       sync.Mutex
+	conn     net.Conn
+	mux      *yamux.Session
+	version  byte
+	listener net.Listener
+
+	// state is the state of the client connection
+	state common.ClientState
+
+	// accepted transports share the channelz entry of their server
+	accepted bool
 }
 
 var _ mqc.Transport = (*tcpTransport)(nil)
@@ -28,6 +40,7 @@ func NewTransport(options ...transport.TransportOption) (mqc.Transport, error) {
 		ConnectTimeout: time.Second * 5,
 		CallTimeout:    time.Second * 5,
 		Protocol:       "tcp",
+		Logger:         slog.Default(),
 	}
 
 	for _, opt := range options {
@@ -43,38 +56,84 @@ func NewTransport(options ...transport.TransportOption) (mqc.Transport, error) {
 	return &tcpTransport{
 		BaseTransport: common.BaseTransport{
 			Options:   *transportOptions,
-			Handlers:  make(map[mqc.Method]mqc.MethodHandler),
+			Handlers:  transport.NewHandlers(),
 			Serialize: serialization.NewProtoSerializer(),
+			Channelz:  transportOptions.Channelz.AddTransport(transportOptions.Protocol, transportOptions.Addrs),
 		},
 	}, nil
 }
 
-func (t *tcpTransport) ensureConnected() error {
-	if t.conn == nil {
-		var err error
+// ensureConnected dials the server unless the transport is connected.
+// It must be called with the mutex held.
+func (t *tcpTransport) ensureConnected(ctx context.Context) error {
+	// Reconnect if the session was closed, e.g. when idle or by keepalive
+	if t.mux != nil && t.mux.IsClosed() {
+		t.disconnect()
+	}
 
-		if t.Options.TlsConfig != nil {
-			t.conn, err = tls.Dial(t.Options.Protocol, t.Options.Addrs[0], t.Options.TlsConfig)
-		} else {
-			t.conn, err = net.Dial(t.Options.Protocol, t.Options.Addrs[0])
-		}
-		if err != nil {
-			return err
-		}
+	if t.conn != nil {
+		return nil
+	}
 
-		t.mux, err = yamux.Client(t.conn, nil)
-		if err != nil {
-			t.conn.Close()
-			t.conn = nil
-			return err
-		}
+	ctx, cancel := t.Options.ConnectContext(ctx)
+	defer cancel()
+
+	t.state.Set(mqc.ConnStateConnecting)
 
-		go t.AcceptMux(t.mux)
+	dialer := &net.Dialer{}
+
+	var conn net.Conn
+	var err error
+
+	if t.Options.TlsConfig != nil {
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: t.Options.TlsConfig}
+		conn, err = tlsDialer.DialContext(ctx, t.Options.Protocol, t.Options.Addrs[0])
+	} else {
+		conn, err = dialer.DialContext(ctx, t.Options.Protocol, t.Options.Addrs[0])
+	}
+	if err != nil {
+		t.state.Set(mqc.ConnStateTransientFailure)
+		return err
+	}
+
+	mux, version, err := common.ConnectSession(ctx, conn, &t.Options)
+	if err != nil {
+		t.state.Set(mqc.ConnStateTransientFailure)
+		return err
 	}
+
+	t.conn = conn
+	t.mux = mux
+	t.version = version
+	t.state.Ready(mux)
+
+	t.TrackSession(mux, conn.RemoteAddr().String())
+	go t.AcceptMux(mux, common.NewPeer(conn), version)
 	return nil
 }
 
 func (t *tcpTransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.listener != nil {
+		t.listener.Close()
+		t.listener = nil
+	}
+	if !t.accepted {
+		t.Channelz.Remove()
+	}
+	t.state.Set(mqc.ConnStateShutdown)
+	return t.disconnect()
+}
+
+// State returns the state of the client connection.
+func (t *tcpTransport) State() mqc.ConnState {
+	return t.state.Get()
+}
+
+// disconnect closes the client connection.
+func (t *tcpTransport) disconnect() error {
 	if t.mux != nil {
 		t.mux.Close()
 		t.mux = nil
@@ -88,10 +147,13 @@ func (t *tcpTransport) Close() error {
 }
 
 func (t *tcpTransport) Dial() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
 	if t.conn != nil {
 		return fmt.Errorf("transport is already connected")
 	}
-	return t.ensureConnected()
+	return t.ensureConnected(context.Background())
 }
 
 func (t *tcpTransport) Invoke(ctx context.Context, method *mqc.Method) (mqc.Conn, error) {
@@ -99,14 +161,23 @@ func (t *tcpTransport) Invoke(ctx context.Context, method *mqc.Method) (mqc.Conn
 		return nil, mqc.ErrPubSubNotSupported
 	}
 
-	if err := t.ensureConnected(); err != nil {
+	t.mu.Lock()
+	err := t.ensureConnected(ctx)
+	mux, version := t.mux, t.version
+	t.mu.Unlock()
+
+	if err != nil {
 		return nil, err
 	}
-	return t.InvokeMux(ctx, t.mux, method)
+	return t.InvokeMux(ctx, mux, version, method)
 }
 
 func (t *tcpTransport) Serve() error {
-	if t.conn != nil {
+	t.mu.Lock()
+	connected := t.conn != nil
+	t.mu.Unlock()
+
+	if connected {
 		return fmt.Errorf("transport is already connected")
 	}
 
@@ -123,35 +194,69 @@ func (t *tcpTransport) Serve() error {
 	}
 	defer listener.Close()
 
+	// Close stops accepting connections
+	t.mu.Lock()
+	t.listener = listener
+	t.mu.Unlock()
+
 	for {
 		conn, err := listener.Accept()
 		if err != nil {
+			t.mu.Lock()
+			closed := t.listener != listener
+			t.mu.Unlock()
+
+			// The listener was closed by Close
+			if closed {
+				return nil
+			}
 			return err
 		}
 
 		go func() {
 			defer conn.Close()
 
+			// Agree on the frame version before any streams are opened.
+			// Clients must complete the handshake within the connect timeout.
+			if t.Options.ConnectTimeout > 0 {
+				conn.SetDeadline(time.Now().Add(t.Options.ConnectTimeout))
+			}
+			version, err := common.ServerHandshake(conn)
+			if err != nil {
+				t.Options.Logger.Warn("handshake failed", slog.String("addr", conn.RemoteAddr().String()), slog.Any("error", err))
+				return
+			}
+			conn.SetDeadline(time.Time{})
+
+			// The TLS state of the peer is known after the handshake
+			peer := common.NewPeer(conn)
+
 			// Create a new yamux session for the incoming connection
-			session, err := yamux.Server(conn, nil)
+			session, err := common.NewServerSession(conn, &t.Options)
 			if err != nil {
+				t.Options.Logger.Warn("failed to create session", slog.Any("peer", peer), slog.Any("error", err))
 				conn.Close()
 				return
 			}
 			defer session.Close()
 
+			t.TrackSession(session, conn.RemoteAddr().String())
+
 			clientTransport := &tcpTransport{
 				BaseTransport: t.BaseTransport,
 				conn:          conn,
 				mux:           session,
+				version:       version,
+				accepted:      true,
 			}
+			clientTransport.state.Ready(session)
 
 			if t.Options.OnConnect != nil {
 				t.Options.OnConnect(clientTransport)
 			}
 
 			// Handle incoming streams
-			t.AcceptMux(session)
+			t.AcceptMux(session, peer, version)
 		}()
 	}
 }